@@ -2,6 +2,7 @@ package apm
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"go.mongodb.org/mongo-driver/event"
@@ -15,4 +16,21 @@ type (
 		Shutdown(duration time.Duration)
 		CommandMonitor() *event.CommandMonitor
 	}
+
+	// TraceExtractor is implemented by the APMs able to continue a distributed trace,
+	// see StartTransactionFromHeaders
+	TraceExtractor interface {
+		// StartTransactionFromHeaders continues the distributed trace written by InsertDistributedTraceHeaders,
+		// it starts a new trace when header does not carry one
+		StartTransactionFromHeaders(transactionName string, header http.Header) Transaction
+	}
 )
+
+// StartTransactionFromHeaders continues the distributed trace of header when a implements TraceExtractor
+// and starts a new transaction otherwise.
+func StartTransactionFromHeaders(a APM, transactionName string, header http.Header) Transaction {
+	if extractor, ok := a.(TraceExtractor); ok {
+		return extractor.StartTransactionFromHeaders(transactionName, header)
+	}
+	return a.StartTransaction(transactionName)
+}
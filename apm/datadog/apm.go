@@ -2,6 +2,7 @@ package datadog
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"go.mongodb.org/mongo-driver/event"
@@ -49,6 +50,19 @@ func (dd *datadog) StartTransaction(transactionName string) apm.Transaction {
 	}
 }
 
+func (dd *datadog) StartTransactionFromHeaders(transactionName string, header http.Header) apm.Transaction {
+	parent, err := tracer.Extract(tracer.HTTPHeadersCarrier(header))
+	if err != nil {
+		return dd.StartTransaction(transactionName)
+	}
+
+	span := tracer.StartSpan(transactionName, tracer.ChildOf(parent))
+	return &transaction{
+		app:  dd,
+		span: span,
+	}
+}
+
 func (dd *datadog) Shutdown(duration time.Duration) {
 	tracer.Stop()
 }
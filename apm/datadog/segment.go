@@ -1,6 +1,8 @@
 package datadog
 
 import (
+	"net/http"
+
 	"github.com/Dert12318/Utilities/apm"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
@@ -19,3 +21,7 @@ func (s *segment) AddAttribute(key string, val interface{}) {
 func (s *segment) End() {
 	s.span.Finish()
 }
+
+func (s *segment) InsertDistributedTraceHeaders(header http.Header) {
+	_ = tracer.Inject(s.span.Context(), tracer.HTTPHeadersCarrier(header))
+}
@@ -92,7 +92,7 @@ func (t *transaction) StartExternalSegment(request *http.Request) apm.Segment {
 }
 
 func (t *transaction) InsertDistributedTraceHeaders(header http.Header) {
-
+	_ = tracer.Inject(t.span.Context(), tracer.HTTPHeadersCarrier(header))
 }
 
 func (t *transaction) NewContext(ctx context.Context) context.Context {
@@ -2,6 +2,7 @@ package disabled
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/Dert12318/Utilities/apm"
@@ -24,6 +25,10 @@ func (nr *disabled) StartTransaction(transactionName string) apm.Transaction {
 	return &transaction{}
 }
 
+func (nr *disabled) StartTransactionFromHeaders(transactionName string, header http.Header) apm.Transaction {
+	return &transaction{}
+}
+
 func (nr *disabled) RecordCustomEvent(eventType string, params map[string]interface{}) {}
 
 func (nr *disabled) Shutdown(duration time.Duration) {}
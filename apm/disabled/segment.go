@@ -1,6 +1,10 @@
 package disabled
 
-import "github.com/Dert12318/Utilities/apm"
+import (
+	"net/http"
+
+	"github.com/Dert12318/Utilities/apm"
+)
 
 type (
 	segment struct{}
@@ -10,6 +14,8 @@ func (s *segment) AddAttribute(key string, val interface{}) {}
 
 func (s *segment) End() {}
 
+func (s *segment) InsertDistributedTraceHeaders(header http.Header) {}
+
 func NewSegment() apm.Segment {
 	return &segment{}
 }
@@ -1,8 +1,25 @@
 package apm
 
+import (
+	"net/http"
+)
+
 type (
 	Segment interface {
 		AddAttribute(key string, val interface{})
 		End()
 	}
+
+	// TraceInjector is implemented by the segments able to propagate their trace, see InsertDistributedTraceHeaders
+	TraceInjector interface {
+		// InsertDistributedTraceHeaders writes the headers that make the receiver continue the trace from this segment
+		InsertDistributedTraceHeaders(header http.Header)
+	}
 )
+
+// InsertDistributedTraceHeaders writes the trace headers of s when it implements TraceInjector, nothing otherwise.
+func InsertDistributedTraceHeaders(s Segment, header http.Header) {
+	if injector, ok := s.(TraceInjector); ok {
+		injector.InsertDistributedTraceHeaders(header)
+	}
+}
@@ -5,16 +5,140 @@ import (
 	"time"
 
 	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+var (
+	ErrLockNotObtained = errors.New("lock not obtained")
+	ErrLockNotHeld     = errors.New("lock not held")
+	// ErrPipeNotExecuted is returned by the results of a Pipe that has not been executed yet
+	ErrPipeNotExecuted = errors.New("pipeline is not executed yet")
+	// ErrTxFailed is returned by Watch when a watched key changed before the transaction was executed
+	ErrTxFailed = errors.New("transaction failed, watched key changed")
+)
+
+const (
+	// NoExpiration is returned by TTL for a key that never expires
+	NoExpiration time.Duration = -1
+
+	// MessageChannel and MessagePattern are the attributes a Subscriber sets on every message,
+	// MessagePattern is only set for messages received through PSubscribe
+	MessageChannel = "channel"
+	MessagePattern = "pattern"
 )
 
 type (
+	// Z is a sorted set member, Member is encoded the same way Cache.Set encodes values
+	// and always read back as a string
+	Z struct {
+		Score  float64
+		Member interface{}
+	}
+
+	// ZRangeBy bounds a range by score, Min and Max accept the redis syntax e.g. "-inf", "(1.5" or "+inf"
+	ZRangeBy struct {
+		Min, Max      string
+		Offset, Count int64
+	}
+
+	ScanOption struct {
+		// Match is the glob style pattern keys must match, defaults to every key
+		Match string
+		// Type only returns keys holding the given type: "string", "hash", "set", "list" or "zset".
+		// Redis needs at least version 6 to filter by type.
+		Type string
+		// Count is a hint of how many keys are fetched per round trip
+		Count int64
+	}
+
+	// Iterator walks the keys of a Scan, every master is visited in cluster mode. A key may be returned
+	// more than once and keys written during the iteration may or may not be returned, like SCAN.
+	Iterator interface {
+		Next() bool
+		Key() string
+		Err() error
+	}
+
+	// PipeResult is populated once the pipeline is executed, before that Err returns ErrPipeNotExecuted
+	PipeResult interface {
+		Err() error
+	}
+
+	StatusResult interface {
+		PipeResult
+	}
+
+	StringResult interface {
+		PipeResult
+		Val() string
+		// Scan decodes the value into object the same way Cache.Get does
+		Scan(object interface{}) error
+	}
+
+	IntResult interface {
+		PipeResult
+		Val() int64
+	}
+
+	BoolResult interface {
+		PipeResult
+		Val() bool
+	}
+
+	SliceResult interface {
+		PipeResult
+		Val() []interface{}
+	}
+
+	StringSliceResult interface {
+		PipeResult
+		Val() []string
+	}
+
+	StringMapResult interface {
+		PipeResult
+		Val() map[string]string
+	}
+
+	// Pipe queues commands until Exec, values are encoded the same way Cache.Set does
 	Pipe interface {
-		Set(key string, value interface{}) error
-		SetWithExpiration(key string, value interface{}, expired time.Duration) error
-		Get(key string, object interface{}) error
+		Set(key string, value interface{}) StatusResult
+		SetWithExpiration(key string, value interface{}, expired time.Duration) StatusResult
+		Get(key string) StringResult
+		Del(keys ...string) IntResult
+		Expire(key string, expiration time.Duration) BoolResult
+		Incr(key string) IntResult
+		IncrBy(key string, value int64) IntResult
+
+		HSet(key, field string, value interface{}) BoolResult
+		HMSet(key string, value map[string]interface{}) StatusResult
+		HGet(key, field string) StringResult
+		HMGet(key string, fields ...string) SliceResult
+		HGetAll(key string) StringMapResult
+		HDel(key string, fields ...string) IntResult
+
+		SAdd(key string, members ...interface{}) IntResult
+		SRem(key string, members ...interface{}) IntResult
+		SIsMember(key string, member interface{}) BoolResult
+		SMembers(key string) StringSliceResult
+
+		// Exec runs the queued commands and returns the first error other than a missing key,
+		// the error of every command is available on its result
 		Exec() error
+		Discard() error
+	}
+
+	// Tx is handed to the Watch callback, reads see the watched keys before the transaction runs
+	Tx interface {
+		Get(key string, object interface{}) error
+		HGet(key, field string, object interface{}) error
+		// Pipelined queues commands that are executed atomically, failing with ErrTxFailed when a watched key changed
+		Pipelined(fn func(pipe Pipe) error) error
 	}
 
+	// PubSub exposes the go-redis message type, prefer Subscriber to dispatch messages to handlers
 	PubSub interface {
 		Receive() error
 		Publish(message string) error
@@ -22,14 +146,27 @@ type (
 		Close() error
 	}
 
+	// Subscriber dispatches the published messages to the dispatcher of their channel or pattern, the payload
+	// is the MsgData and the channel is also the MsgType. Subscriptions are restored after the connection
+	// is lost, messages published in the meantime are lost as with any redis pub/sub client.
+	Subscriber interface {
+		Subscribe(channel string, dispatcher messaging.Dispatcher) error
+		// PSubscribe subscribes to every channel matching the glob style pattern
+		PSubscribe(pattern string, dispatcher messaging.Dispatcher) error
+		Unsubscribe(channels ...string) error
+		PUnsubscribe(patterns ...string) error
+		// Close waits for the message being dispatched and closes the connection
+		Close() error
+	}
+
 	Cache interface {
 		Ping(ctx *context.Context) error
 
-		// SetWithExpiration value must implement encoding.BinaryMarshaler
+		// SetWithExpiration encodes value with the backend encoding unless it is a string, number, bool, []byte
+		// or encoding.BinaryMarshaler, use WithEncoding to override the encoding of a single call
 		SetWithExpiration(ctx *context.Context, key string, value interface{}, duration time.Duration) error
-		// Set value must implement encoding.BinaryMarshaler
 		Set(ctx *context.Context, key string, value interface{}) error
-		// Set data must implement encoding.BinaryUnmarshaler
+		// Get data must be a pointer, it is decoded the same way SetWithExpiration encoded it
 		Get(ctx *context.Context, key string, data interface{}) error
 
 		HMSetWithExpiration(ctx *context.Context, key string, value map[string]interface{}, ttl time.Duration) error
@@ -48,26 +185,104 @@ type (
 
 		MGet(ctx *context.Context, key []string) ([]interface{}, error)
 
+		// SetNX sets key only when it does not exist yet and reports whether it was set
+		SetNX(ctx *context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+		Incr(ctx *context.Context, key string) (int64, error)
+		IncrBy(ctx *context.Context, key string, value int64) (int64, error)
+		Expire(ctx *context.Context, key string, ttl time.Duration) (bool, error)
+		// TTL returns NoExpiration for a persistent key and a redis.Nil error for a missing one
+		TTL(ctx *context.Context, key string) (time.Duration, error)
+		Persist(ctx *context.Context, key string) (bool, error)
+
+		LPush(ctx *context.Context, key string, values ...interface{}) (int64, error)
+		RPush(ctx *context.Context, key string, values ...interface{}) (int64, error)
+		// LPop and RPop decode the popped element into object the same way Get does
+		LPop(ctx *context.Context, key string, object interface{}) error
+		RPop(ctx *context.Context, key string, object interface{}) error
+		LRange(ctx *context.Context, key string, start, stop int64) ([]string, error)
+		LLen(ctx *context.Context, key string) (int64, error)
+
+		ZAdd(ctx *context.Context, key string, members ...Z) (int64, error)
+		ZIncrBy(ctx *context.Context, key string, increment float64, member interface{}) (float64, error)
+		ZScore(ctx *context.Context, key string, member interface{}) (float64, error)
+		// ZRank and ZRevRank return a redis.Nil error when member is not in the set
+		ZRank(ctx *context.Context, key string, member interface{}) (int64, error)
+		ZRevRank(ctx *context.Context, key string, member interface{}) (int64, error)
+		ZRange(ctx *context.Context, key string, start, stop int64) ([]string, error)
+		ZRevRange(ctx *context.Context, key string, start, stop int64) ([]string, error)
+		ZRangeWithScores(ctx *context.Context, key string, start, stop int64) ([]Z, error)
+		ZRevRangeWithScores(ctx *context.Context, key string, start, stop int64) ([]Z, error)
+		ZRangeByScore(ctx *context.Context, key string, opt ZRangeBy) ([]string, error)
+		ZRem(ctx *context.Context, key string, members ...interface{}) (int64, error)
+		ZRemRangeByScore(ctx *context.Context, key string, min, max string) (int64, error)
+		ZCard(ctx *context.Context, key string) (int64, error)
+
+		// Keys collects every key matching pattern with Scan, prefer Scan for large keyspaces
 		Keys(ctx *context.Context, pattern string) ([]string, error)
+		// Scan iterates the keys without blocking the server, ctx bounds the whole iteration
+		Scan(ctx *context.Context, option ScanOption) Iterator
 
 		Remove(ctx *context.Context, key string) error
+		// Deprecated: use DeleteByPattern, which reports how many keys were removed
 		RemoveByPattern(ctx *context.Context, pattern string, countPerLoop int64) error
+		// DeleteByPattern unlinks every key returned by Scan in batches of option.Count and returns how many were removed
+		DeleteByPattern(ctx *context.Context, option ScanOption) (int64, error)
 		FlushDatabase(ctx *context.Context) error
 		FlushAll(ctx *context.Context) error
 		Close() error
 
 		Pipeline() Pipe
+		// TxPipeline is a Pipe whose commands are executed atomically with MULTI/EXEC
+		TxPipeline() Pipe
+		// Watch runs fn as an optimistic transaction over keys, see Tx
+		Watch(ctx *context.Context, fn func(tx Tx) error, keys ...string) error
 		Client() Cache
 
 		// Implemented by Redis to redis pubsub
 		Subscribe(channel string) (PubSub, error)
 	}
 
+	LockOption struct {
+		// TTL is the lease duration of the lock
+		TTL time.Duration
+		// WaitTimeout is how long Obtain keeps retrying before returning ErrLockNotObtained, zero tries once
+		WaitTimeout time.Duration
+		// RetryInterval is the delay between attempts while waiting
+		RetryInterval time.Duration
+		// AutoRenew keeps extending the lease in the background until the lock is released or lost
+		AutoRenew bool
+	}
+
+	Lock interface {
+		Key() string
+		// Token is the fencing token, it increases every time the lock is obtained. With Redlock the counters
+		// are per node and kept in step on a majority, a node that restarts without persistence can break that.
+		Token() int64
+		Refresh(ctx *context.Context, ttl time.Duration) error
+		Release(ctx *context.Context) error
+		// Done is closed once the lock is released or its lease could not be renewed
+		Done() <-chan struct{}
+	}
+
+	Locker interface {
+		Obtain(ctx *context.Context, key string, option LockOption) (Lock, error)
+		Close() error
+	}
+
 	PoolCallback func(client Cache)
 
+	// Pool manages named clients, Use and Client work on the default one
 	Pool interface {
+		// Use borrows the default client, it waits while the maximum of concurrent borrowers is reached
+		// and skips callback when the client can not be connected
 		Use(callback PoolCallback)
+		// Client returns the default client, nil when it can not be connected
 		Client() Cache
 		Close() error
+		// Get returns the client registered as name, connecting it on first use. The client is closed once it
+		// failed a health check, prefer Borrow which keeps it open until callback returned
+		Get(ctx *context.Context, name string) (Cache, error)
+		// Borrow runs callback with the client registered as name once a slot is free or fails when ctx is done first
+		Borrow(ctx *context.Context, name string, callback func(client Cache) error) error
 	}
 )
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"context"
+	stdEncoding "encoding"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/encoding"
+)
+
+type encodingKey struct{}
+
+// WithEncoding overrides the value encoding of the backend for calls made with the returned context.
+func WithEncoding(ctx context.Context, e encoding.Encoding) context.Context {
+	return context.WithValue(ctx, encodingKey{}, e)
+}
+
+// EncodingFromContext returns the encoding set by WithEncoding or fallback when there is none.
+func EncodingFromContext(ctx *context.Context, fallback encoding.Encoding) encoding.Encoding {
+	if ctx == nil || *ctx == nil {
+		return fallback
+	}
+
+	if e, ok := (*ctx).Value(encodingKey{}).(encoding.Encoding); ok && e != nil {
+		return e
+	}
+
+	return fallback
+}
+
+// Marshal returns value untouched when redis can write it natively (strings, numbers, bools, []byte and
+// encoding.BinaryMarshaler), otherwise it is encoded with e.
+func Marshal(e encoding.Encoding, value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case nil, string, []byte,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64, bool,
+		stdEncoding.BinaryMarshaler:
+		return value, nil
+	}
+
+	data, err := e.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal %T", value)
+	}
+
+	return data, nil
+}
+
+// Unmarshal is the counterpart of Marshal, it scans data into target natively when possible
+// and falls back to e for everything else.
+func Unmarshal(e encoding.Encoding, data []byte, target interface{}) error {
+	var err error
+
+	switch t := target.(type) {
+	case stdEncoding.BinaryUnmarshaler:
+		return t.UnmarshalBinary(data)
+	case *string:
+		*t = string(data)
+		return nil
+	case *[]byte:
+		*t = append((*t)[:0], data...)
+		return nil
+	case *int:
+		var v int64
+		v, err = strconv.ParseInt(string(data), 10, 0)
+		*t = int(v)
+	case *int8:
+		var v int64
+		v, err = strconv.ParseInt(string(data), 10, 8)
+		*t = int8(v)
+	case *int16:
+		var v int64
+		v, err = strconv.ParseInt(string(data), 10, 16)
+		*t = int16(v)
+	case *int32:
+		var v int64
+		v, err = strconv.ParseInt(string(data), 10, 32)
+		*t = int32(v)
+	case *int64:
+		*t, err = strconv.ParseInt(string(data), 10, 64)
+	case *uint:
+		var v uint64
+		v, err = strconv.ParseUint(string(data), 10, 0)
+		*t = uint(v)
+	case *uint8:
+		var v uint64
+		v, err = strconv.ParseUint(string(data), 10, 8)
+		*t = uint8(v)
+	case *uint16:
+		var v uint64
+		v, err = strconv.ParseUint(string(data), 10, 16)
+		*t = uint16(v)
+	case *uint32:
+		var v uint64
+		v, err = strconv.ParseUint(string(data), 10, 32)
+		*t = uint32(v)
+	case *uint64:
+		*t, err = strconv.ParseUint(string(data), 10, 64)
+	case *float32:
+		var v float64
+		v, err = strconv.ParseFloat(string(data), 32)
+		*t = float32(v)
+	case *float64:
+		*t, err = strconv.ParseFloat(string(data), 64)
+	case *bool:
+		*t, err = strconv.ParseBool(string(data))
+	default:
+		err = e.Unmarshal(data, target)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to unmarshal %T", target)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/encoding"
+	"github.com/Dert12318/Utilities/encoding/jsontier"
+	"github.com/Dert12318/Utilities/encoding/msgpack"
+)
+
+type binary string
+
+func (b binary) MarshalBinary() ([]byte, error) {
+	return []byte("bin:" + b), nil
+}
+
+func (b *binary) UnmarshalBinary(data []byte) error {
+	*b = binary(data[len("bin:"):])
+	return nil
+}
+
+type user struct {
+	Name string `json:"name" msgpack:"name"`
+}
+
+func TestMarshalNative(t *testing.T) {
+	e := jsontier.NewEncoding()
+
+	for _, value := range []interface{}{nil, "a", []byte("b"), 1, int64(2), uint8(3), 1.5, true, binary("c")} {
+		got, err := Marshal(e, value)
+		assert.NoError(t, err)
+		assert.Equal(t, value, got)
+	}
+
+	got, err := Marshal(e, user{Name: "john"})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"name":"john"}`), got)
+
+	_, err = Marshal(e, make(chan int))
+	assert.Error(t, err)
+}
+
+func TestUnmarshalNative(t *testing.T) {
+	e := jsontier.NewEncoding()
+
+	var s string
+	assert.NoError(t, Unmarshal(e, []byte("a"), &s))
+	assert.Equal(t, "a", s)
+
+	b := []byte("old value")
+	assert.NoError(t, Unmarshal(e, []byte("b"), &b))
+	assert.Equal(t, []byte("b"), b)
+
+	var i8 int8
+	assert.NoError(t, Unmarshal(e, []byte("-8"), &i8))
+	assert.Equal(t, int8(-8), i8)
+	assert.Error(t, Unmarshal(e, []byte("300"), &i8), "out of range")
+
+	var u uint
+	assert.Error(t, Unmarshal(e, []byte("-1"), &u))
+
+	var f float32
+	assert.NoError(t, Unmarshal(e, []byte("1.5"), &f))
+	assert.Equal(t, float32(1.5), f)
+
+	// go-redis writes bools as 1 and 0
+	var ok bool
+	assert.NoError(t, Unmarshal(e, []byte("1"), &ok))
+	assert.True(t, ok)
+
+	var bin binary
+	assert.NoError(t, Unmarshal(e, []byte("bin:c"), &bin))
+	assert.Equal(t, binary("c"), bin)
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, e := range []encoding.Encoding{jsontier.NewEncoding(), msgpack.NewEncoding()} {
+		data, err := Marshal(e, user{Name: "john"})
+		assert.NoError(t, err)
+
+		var got user
+		assert.NoError(t, Unmarshal(e, data.([]byte), &got))
+		assert.Equal(t, user{Name: "john"}, got)
+
+		data, _ = Marshal(e, map[string]int{"a": 1})
+		m := make(map[string]int)
+		assert.NoError(t, Unmarshal(e, data.([]byte), &m))
+		assert.Equal(t, map[string]int{"a": 1}, m)
+
+		assert.Error(t, Unmarshal(e, []byte{0xc1, '{'}, &got))
+	}
+}
+
+func TestEncodingFromContext(t *testing.T) {
+	fallback, override := jsontier.NewEncoding(), msgpack.NewEncoding()
+	assert.Equal(t, fallback, EncodingFromContext(nil, fallback))
+
+	ctx := context.Background()
+	assert.Equal(t, fallback, EncodingFromContext(&ctx, fallback))
+
+	ctx = WithEncoding(ctx, override)
+	assert.Equal(t, override, EncodingFromContext(&ctx, fallback))
+}
@@ -0,0 +1,217 @@
+package loader
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/Dert12318/Utilities/cache"
+	"github.com/Dert12318/Utilities/encoding"
+	"github.com/Dert12318/Utilities/encoding/jsontier"
+	helperTime "github.com/Dert12318/Utilities/helper/time"
+	"github.com/Dert12318/Utilities/logs"
+	"github.com/Dert12318/Utilities/logs/logrus"
+)
+
+const (
+	DefaultEarlyRefreshBeta = 1.0
+)
+
+var (
+	// ErrNotFound is returned by a LoadFunc when the value does not exist at the source.
+	// When Option.NegativeTTL is set the miss itself is cached and ErrNotFound is returned from the cache.
+	ErrNotFound = errors.New("loader: value not found")
+)
+
+type (
+	LoadFunc[T any] func(ctx *context.Context) (T, error)
+
+	Option struct {
+		// NegativeTTL caches ErrNotFound results for the given duration, zero disables negative caching
+		NegativeTTL time.Duration
+		// EarlyRefresh enables probabilistic early expiration (XFetch) so hot keys are refreshed before they expire
+		EarlyRefresh bool
+		// EarlyRefreshBeta tunes how eagerly keys are refreshed, values above 1 favour earlier refreshes
+		EarlyRefreshBeta float64
+		Log              logs.Logger
+	}
+
+	Loader[T any] struct {
+		cache    cache.Cache
+		option   Option
+		group    singleflight.Group
+		encoding encoding.Encoding
+	}
+
+	// detached keeps the values of parent without its deadline and cancellation
+	detached struct {
+		parent context.Context
+	}
+
+	item[T any] struct {
+		Value     T     `json:"v"`
+		NotFound  bool  `json:"n,omitempty"`
+		Delta     int64 `json:"d"`
+		ExpiredAt int64 `json:"e"`
+
+		encoding encoding.Encoding
+	}
+)
+
+func New[T any](c cache.Cache, option *Option) *Loader[T] {
+	o := Option{
+		EarlyRefreshBeta: DefaultEarlyRefreshBeta,
+		Log:              logrus.DefaultLog(),
+	}
+
+	if option != nil {
+		o.NegativeTTL = option.NegativeTTL
+		o.EarlyRefresh = option.EarlyRefresh
+
+		if option.EarlyRefreshBeta > 0 {
+			o.EarlyRefreshBeta = option.EarlyRefreshBeta
+		}
+
+		if option.Log != nil {
+			o.Log = option.Log
+		}
+	}
+
+	return &Loader[T]{
+		cache:    c,
+		option:   o,
+		encoding: jsontier.NewEncoding(),
+	}
+}
+
+// GetOrLoad returns the cached value for key, calling load on a miss and caching its result for ttl.
+// Concurrent misses for the same key share a single load call, which keeps the values of ctx but is not
+// cancelled with it, a caller whose ctx is done stops waiting without failing the others. When an early
+// refresh fails the cached value is returned.
+func (l *Loader[T]) GetOrLoad(ctx *context.Context, key string, ttl time.Duration, load LoadFunc[T]) (T, error) {
+	cached := &item[T]{encoding: l.encoding}
+
+	err := l.cache.Get(ctx, key, cached)
+	if err == nil && !l.shouldRefresh(cached) {
+		return cached.result()
+	}
+
+	if err != nil && errors.Cause(err) != redis.Nil {
+		l.option.Log.Error(errors.Wrapf(err, "failed to get cache with key %s, loading from source", key))
+	}
+	refresh := err == nil
+
+	parent := context.Background()
+	if ctx != nil && *ctx != nil {
+		parent = *ctx
+	}
+	detachedCtx := context.Context(detached{parent: parent})
+	result := l.group.DoChan(key, func() (interface{}, error) {
+		return l.load(&detachedCtx, key, ttl, load)
+	})
+
+	var v interface{}
+	select {
+	case res := <-result:
+		v, err = res.Val, res.Err
+	case <-doneChannel(ctx):
+		err = (*ctx).Err()
+	}
+
+	if err != nil && refresh && !errors.Is(err, ErrNotFound) {
+		l.option.Log.Error(errors.Wrapf(err, "failed to refresh cache with key %s, returning the cached value", key))
+		return cached.result()
+	}
+
+	if err != nil {
+		var empty T
+		return empty, err
+	}
+
+	return v.(T), nil
+}
+
+func (l *Loader[T]) load(ctx *context.Context, key string, ttl time.Duration, load LoadFunc[T]) (T, error) {
+	start := helperTime.Now()
+	value, err := load(ctx)
+	delta := helperTime.Now().Sub(start)
+
+	if errors.Is(err, ErrNotFound) && l.option.NegativeTTL > 0 {
+		l.set(ctx, key, &item[T]{NotFound: true}, delta, l.option.NegativeTTL)
+	}
+
+	if err != nil {
+		return value, err
+	}
+
+	l.set(ctx, key, &item[T]{Value: value}, delta, ttl)
+	return value, nil
+}
+
+func (l *Loader[T]) set(ctx *context.Context, key string, i *item[T], delta, ttl time.Duration) {
+	i.encoding = l.encoding
+	i.Delta = delta.Milliseconds()
+	if ttl > 0 {
+		i.ExpiredAt = helperTime.Now().Add(ttl).UnixMilli()
+	}
+
+	if err := l.cache.SetWithExpiration(ctx, key, i, ttl); err != nil {
+		l.option.Log.Error(errors.Wrapf(err, "failed to set cache with key %s", key))
+	}
+}
+
+// shouldRefresh implements XFetch: now - delta * beta * ln(rand) >= expiry.
+func (l *Loader[T]) shouldRefresh(i *item[T]) bool {
+	if !l.option.EarlyRefresh || i.ExpiredAt == 0 {
+		return false
+	}
+
+	gap := float64(i.Delta) * l.option.EarlyRefreshBeta * -math.Log(1-rand.Float64())
+	return float64(helperTime.Now().UnixMilli())+gap >= float64(i.ExpiredAt)
+}
+
+// result is the value or the ErrNotFound the item caches
+func (i *item[T]) result() (T, error) {
+	if i.NotFound {
+		var empty T
+		return empty, ErrNotFound
+	}
+	return i.Value, nil
+}
+
+func (i *item[T]) MarshalBinary() ([]byte, error) {
+	return i.encoding.Marshal(i)
+}
+
+func (i *item[T]) UnmarshalBinary(data []byte) error {
+	return i.encoding.Unmarshal(data, i)
+}
+
+func (d detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (d detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (d detached) Err() error {
+	return nil
+}
+
+func (d detached) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
+
+func doneChannel(ctx *context.Context) <-chan struct{} {
+	if ctx == nil || *ctx == nil {
+		return nil
+	}
+
+	return (*ctx).Done()
+}
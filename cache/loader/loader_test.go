@@ -0,0 +1,172 @@
+package loader
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/cache/memory"
+	helperTime "github.com/Dert12318/Utilities/helper/time"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	c, _ := memory.New()
+	l := New[user](c, nil)
+
+	var (
+		calls   int32
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+	load := func(ctx *context.Context) (user, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return user{ID: 1, Name: "foo"}, nil
+	}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := l.GetOrLoad(&ctx, "user:1", time.Minute, load)
+			assert.NoError(t, err)
+			assert.Equal(t, "foo", u.Name)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	u, err := l.GetOrLoad(&ctx, "user:1", time.Minute, func(ctx *context.Context) (user, error) {
+		return user{}, errors.New("should be served from cache")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, user{ID: 1, Name: "foo"}, u)
+}
+
+func TestGetOrLoadNegative(t *testing.T) {
+	helperTime.Mock(time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC))
+	defer helperTime.ResetMock()
+
+	ctx := context.Background()
+	c, _ := memory.New()
+	l := New[user](c, &Option{NegativeTTL: time.Second})
+
+	var calls int
+	load := func(ctx *context.Context) (user, error) {
+		calls++
+		return user{}, ErrNotFound
+	}
+
+	_, err := l.GetOrLoad(&ctx, "user:2", time.Minute, load)
+	assert.Equal(t, ErrNotFound, err)
+	_, err = l.GetOrLoad(&ctx, "user:2", time.Minute, load)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, 1, calls)
+
+	helperTime.Mock(time.Date(2022, 10, 10, 0, 0, 1, 0, time.UTC))
+
+	_, err = l.GetOrLoad(&ctx, "user:2", time.Minute, load)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestGetOrLoadRefreshFailure(t *testing.T) {
+	now := time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC)
+	helperTime.Mock(now)
+	defer helperTime.ResetMock()
+
+	ctx := context.Background()
+	c, _ := memory.New()
+	// a load taking 10 seconds with such a beta refreshes the key on every read
+	l := New[user](c, &Option{EarlyRefresh: true, EarlyRefreshBeta: 1e6})
+
+	_, err := l.GetOrLoad(&ctx, "user:1", time.Minute, func(ctx *context.Context) (user, error) {
+		helperTime.Mock(now.Add(10 * time.Second))
+		return user{ID: 1, Name: "foo"}, nil
+	})
+	assert.NoError(t, err)
+
+	var calls int
+	u, err := l.GetOrLoad(&ctx, "user:1", time.Minute, func(ctx *context.Context) (user, error) {
+		calls++
+		return user{}, errors.New("source unavailable")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, user{ID: 1, Name: "foo"}, u)
+
+	// the source reporting the value is gone is not a failure
+	_, err = l.GetOrLoad(&ctx, "user:1", time.Minute, func(ctx *context.Context) (user, error) {
+		return user{}, ErrNotFound
+	})
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestGetOrLoadCallerCancelled(t *testing.T) {
+	c, _ := memory.New()
+	l := New[user](c, nil)
+
+	var (
+		once    sync.Once
+		started = make(chan struct{})
+		release = make(chan struct{})
+		loadErr error
+	)
+	load := func(ctx *context.Context) (user, error) {
+		once.Do(func() { close(started) })
+		<-release
+		loadErr = (*ctx).Err()
+		return user{ID: 1, Name: "foo"}, nil
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := l.GetOrLoad(&cancelled, "user:1", time.Minute, load)
+		first <- err
+	}()
+	<-started
+
+	ctx := context.Background()
+	second := make(chan user)
+	go func() {
+		u, err := l.GetOrLoad(&ctx, "user:1", time.Minute, load)
+		assert.NoError(t, err)
+		second <- u
+	}()
+
+	// the first caller gives up, the load shared with the second one goes on
+	cancel()
+	assert.Equal(t, context.Canceled, <-first)
+
+	close(release)
+	assert.Equal(t, user{ID: 1, Name: "foo"}, <-second)
+	assert.NoError(t, loadErr)
+}
+
+func TestGetOrLoadNilContext(t *testing.T) {
+	c, _ := memory.New()
+	l := New[user](c, nil)
+
+	u, err := l.GetOrLoad(nil, "user:1", time.Minute, func(ctx *context.Context) (user, error) {
+		assert.NoError(t, (*ctx).Err())
+		return user{ID: 1, Name: "foo"}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", u.Name)
+}
@@ -0,0 +1,89 @@
+package cache
+
+// Match reports whether str matches the redis glob-style pattern, supporting
+// '*', '?', '[...]' classes with '^' negation and ranges, and '\' escapes.
+func Match(pattern, str string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(str); i++ {
+				if Match(pattern[1:], str[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(str) == 0 {
+				return false
+			}
+			str = str[1:]
+		case '[':
+			if len(str) == 0 {
+				return false
+			}
+
+			var (
+				i       = 1
+				not     = false
+				matched = false
+			)
+			if i < len(pattern) && pattern[i] == '^' {
+				not = true
+				i++
+			}
+			for ; i < len(pattern) && pattern[i] != ']'; i++ {
+				switch {
+				case pattern[i] == '\\' && i+1 < len(pattern):
+					i++
+					if pattern[i] == str[0] {
+						matched = true
+					}
+				case i+2 < len(pattern) && pattern[i+1] == '-':
+					start, end := pattern[i], pattern[i+2]
+					if start > end {
+						start, end = end, start
+					}
+					if str[0] >= start && str[0] <= end {
+						matched = true
+					}
+					i += 2
+				default:
+					if pattern[i] == str[0] {
+						matched = true
+					}
+				}
+			}
+			if not {
+				matched = !matched
+			}
+			if !matched {
+				return false
+			}
+			if i < len(pattern) {
+				i++
+			}
+			pattern = pattern[i:]
+			str = str[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(str) == 0 || pattern[0] != str[0] {
+				return false
+			}
+			str = str[1:]
+		}
+		pattern = pattern[1:]
+	}
+
+	return len(str) == 0
+}
@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		str     string
+		want    bool
+	}{
+		{"*", "anything", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"a\\*b", "a*b", true},
+		{"a\\*b", "axb", false},
+		{"user:*:name", "user:1:name", true},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, Match(tc.pattern, tc.str), tc.pattern)
+	}
+}
@@ -0,0 +1,604 @@
+package memory
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+	utilEncoding "github.com/Dert12318/Utilities/encoding"
+	"github.com/Dert12318/Utilities/encoding/jsontier"
+	helperTime "github.com/Dert12318/Utilities/helper/time"
+)
+
+const (
+	kindString kind = iota
+	kindHash
+	kindSet
+	kindList
+	kindZSet
+)
+
+var (
+	errWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+)
+
+type (
+	kind int
+
+	entry struct {
+		kind      kind
+		str       string
+		hash      map[string]string
+		set       map[string]struct{}
+		list      []string
+		zset      map[string]float64
+		expiredAt time.Time
+		// version is bumped by every in place write, Watch compares it to detect changes
+		version uint64
+	}
+
+	memoryClient struct {
+		mu       sync.RWMutex
+		data     map[string]*entry
+		closed   bool
+		channels map[string]*pubsub
+		encoding utilEncoding.Encoding
+	}
+)
+
+// New returns an in-process cache.Cache. Values are encoded the same way redis-universal
+// sends them over the wire, using jsontier unless overridden with cache.WithEncoding.
+// Expiry is evaluated against helper/time.Now, which makes TTLs controllable with helperTime.Mock.
+func New() (cache.Cache, error) {
+	return &memoryClient{
+		data:     make(map[string]*entry),
+		channels: make(map[string]*pubsub),
+		encoding: jsontier.NewEncoding(),
+	}, nil
+}
+
+func (c *memoryClient) Ping(ctx *context.Context) error {
+	return check(c)
+}
+
+func (c *memoryClient) SetWithExpiration(ctx *context.Context, key string, value interface{}, duration time.Duration) error {
+	if err := check(c); err != nil {
+		return err
+	}
+
+	val, err := c.toString(ctx, value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set cache with key %s!", key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setString(key, val, duration)
+	return nil
+}
+
+func (c *memoryClient) Set(ctx *context.Context, key string, value interface{}) error {
+	return c.SetWithExpiration(ctx, key, value, 0)
+}
+
+func (c *memoryClient) Get(ctx *context.Context, key string, data interface{}) error {
+	if err := check(c); err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	val, ok, err := c.getString(key)
+	c.mu.RUnlock()
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if !ok {
+		return errors.Wrapf(redis.Nil, "key %s does not exits", key)
+	}
+
+	if err := cache.Unmarshal(c.codec(ctx), []byte(val), data); err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *memoryClient) Keys(ctx *context.Context, pattern string) ([]string, error) {
+	if err := check(c); err != nil {
+		return []string{}, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.keys(pattern), nil
+}
+
+func (c *memoryClient) HMSetWithExpiration(ctx *context.Context, key string, value map[string]interface{}, ttl time.Duration) error {
+	return c.hmsetWithExpiration(ctx, key, value, ttl, true)
+}
+
+func (c *memoryClient) HMSet(ctx *context.Context, key string, value map[string]interface{}) error {
+	return c.hmsetWithExpiration(ctx, key, value, 0, false)
+}
+
+// hmsetWithExpiration expires the key after ttl when expire is set, a ttl that is not positive removes it like EXPIRE
+func (c *memoryClient) hmsetWithExpiration(ctx *context.Context, key string, value map[string]interface{}, ttl time.Duration, expire bool) error {
+	if err := check(c); err != nil {
+		return err
+	}
+
+	fields, err := encodeFields(c.codec(ctx), value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.hmset(key, fields); err != nil {
+		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
+	}
+
+	if expire {
+		c.expire(key, ttl)
+	}
+	return nil
+}
+
+func (c *memoryClient) HSetWithExpiration(ctx *context.Context, key, field string, value interface{}, ttl time.Duration) error {
+	return c.hsetWithExpiration(ctx, key, field, value, ttl, true)
+}
+
+func (c *memoryClient) HSet(ctx *context.Context, key, field string, value interface{}) error {
+	return c.hsetWithExpiration(ctx, key, field, value, 0, false)
+}
+
+// hsetWithExpiration expires the key after ttl when expire is set, a ttl that is not positive removes it like EXPIRE
+func (c *memoryClient) hsetWithExpiration(ctx *context.Context, key, field string, value interface{}, ttl time.Duration, expire bool) error {
+	if err := check(c); err != nil {
+		return err
+	}
+
+	val, err := c.toString(ctx, value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to HSet cache with key %s!", key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.hset(key, field, val); err != nil {
+		return errors.Wrapf(err, "failed to HSet cache with key %s!", key)
+	}
+
+	if expire {
+		c.expire(key, ttl)
+	}
+	return nil
+}
+
+func (c *memoryClient) HMGet(ctx *context.Context, key string, fields ...string) ([]interface{}, error) {
+	if err := check(c); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	val, err := c.hmget(key, fields...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return val, nil
+}
+
+func (c *memoryClient) HGetAll(ctx *context.Context, key string) (map[string]string, error) {
+	if err := check(c); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	val, err := c.hgetall(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return val, nil
+}
+
+func (c *memoryClient) HGet(ctx *context.Context, key, field string, response interface{}) error {
+	if err := check(c); err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	val, ok, err := c.hget(key, field)
+	c.mu.RUnlock()
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if !ok {
+		return errors.Wrapf(redis.Nil, "key %s does not exits", key)
+	}
+
+	if err := cache.Unmarshal(c.codec(ctx), []byte(val), response); err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *memoryClient) MGet(ctx *context.Context, key []string) ([]interface{}, error) {
+	if err := check(c); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	val := make([]interface{}, len(key))
+	for i, k := range key {
+		// MGET never fails on a wrong type, it just reports the key as missing
+		if e, err := c.lookup(k, kindString); err == nil && e != nil {
+			val[i] = e.str
+		}
+	}
+
+	return val, nil
+}
+
+func (c *memoryClient) SAdd(ctx context.Context, key string, values ...interface{}) error {
+	if err := check(c); err != nil {
+		return err
+	}
+
+	members, err := encodeAll(c.codec(&ctx), values)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set cache with key %s!", key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.sadd(key, members...); err != nil {
+		return errors.Wrapf(err, "failed to set cache with key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *memoryClient) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
+	if err := check(c); err != nil {
+		return false, err
+	}
+
+	m, err := c.toString(&ctx, member)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ok, err := c.sismember(key, m)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return ok, nil
+}
+
+func (c *memoryClient) SMembers(ctx context.Context, key string) ([]string, error) {
+	if err := check(c); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	val, err := c.smembers(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return val, nil
+}
+
+func (c *memoryClient) Remove(ctx *context.Context, key string) error {
+	if err := check(c); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.del(key)
+	return nil
+}
+
+func (c *memoryClient) RemoveByPattern(ctx *context.Context, pattern string, countPerLoop int64) error {
+	_, err := c.DeleteByPattern(ctx, cache.ScanOption{Match: pattern, Count: countPerLoop})
+	return err
+}
+
+func (c *memoryClient) FlushDatabase(ctx *context.Context) error {
+	if err := check(c); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data = make(map[string]*entry)
+	return nil
+}
+
+func (c *memoryClient) FlushAll(ctx *context.Context) error {
+	return c.FlushDatabase(ctx)
+}
+
+func (c *memoryClient) Close() error {
+	c.mu.Lock()
+	channels := c.channels
+	c.channels = make(map[string]*pubsub)
+	c.closed = true
+	c.mu.Unlock()
+
+	for _, p := range channels {
+		_ = p.Close()
+	}
+
+	return nil
+}
+
+func (c *memoryClient) Client() cache.Cache {
+	return c
+}
+
+func (c *memoryClient) Pipeline() cache.Pipe {
+	return newPipe(c, c.encoding, nil)
+}
+
+func (c *memoryClient) TxPipeline() cache.Pipe {
+	return newPipe(c, c.encoding, nil)
+}
+
+func (c *memoryClient) Watch(ctx *context.Context, fn func(tx cache.Tx) error, keys ...string) error {
+	if err := check(c); err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	w := c.snapshot(keys...)
+	c.mu.RUnlock()
+
+	err := fn(&tx{client: c, ctx: ctx, encoding: c.codec(ctx), watched: w})
+	if errors.Cause(err) == cache.ErrTxFailed {
+		return errors.Wrapf(cache.ErrTxFailed, "failed to watch keys %v", keys)
+	}
+
+	return err
+}
+
+func (c *memoryClient) Subscribe(channel string) (cache.PubSub, error) {
+	if err := check(c); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.channels[channel]; ok {
+		return p, nil
+	}
+
+	p := newPubSub(c, channel)
+	c.channels[channel] = p
+	return p, nil
+}
+
+func (c *memoryClient) publish(channel, message string) {
+	c.mu.RLock()
+	p, ok := c.channels[channel]
+	c.mu.RUnlock()
+
+	if ok {
+		p.deliver(message)
+	}
+}
+
+func (c *memoryClient) unsubscribe(p *pubsub) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.channels[p.cn] == p {
+		delete(c.channels, p.cn)
+	}
+}
+
+// lookup returns the live entry for key or nil when it is missing or expired.
+// The caller must hold at least the read lock.
+func (c *memoryClient) lookup(key string, k kind) (*entry, error) {
+	e, ok := c.data[key]
+	if !ok || e.isExpired(helperTime.Now()) {
+		return nil, nil
+	}
+
+	if e.kind != k {
+		return nil, errWrongType
+	}
+
+	return e, nil
+}
+
+// lookupOrCreate is lookup for writers, creating an empty entry of the given kind when missing.
+// The caller must hold the write lock.
+func (c *memoryClient) lookupOrCreate(key string, k kind) (*entry, error) {
+	e, err := c.lookup(key, k)
+	if err != nil || e != nil {
+		return e, err
+	}
+
+	e = &entry{kind: k}
+	switch k {
+	case kindHash:
+		e.hash = make(map[string]string)
+	case kindSet:
+		e.set = make(map[string]struct{})
+	case kindZSet:
+		e.zset = make(map[string]float64)
+	}
+	c.data[key] = e
+	return e, nil
+}
+
+// keys returns the sorted live keys matching pattern. The caller must hold at least the read lock.
+func (c *memoryClient) keys(pattern string) []string {
+	now := helperTime.Now()
+	keys := make([]string, 0)
+	for key, e := range c.data {
+		if e.isExpired(now) {
+			continue
+		}
+
+		if cache.Match(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func (e *entry) isExpired(now time.Time) bool {
+	return !e.expiredAt.IsZero() && !now.Before(e.expiredAt)
+}
+
+func expiredAt(duration time.Duration) time.Time {
+	if duration <= 0 {
+		return time.Time{}
+	}
+
+	return helperTime.Now().Add(duration)
+}
+
+func (c *memoryClient) codec(ctx *context.Context) utilEncoding.Encoding {
+	return cache.EncodingFromContext(ctx, c.encoding)
+}
+
+func (c *memoryClient) toString(ctx *context.Context, value interface{}) (string, error) {
+	return encode(c.codec(ctx), value)
+}
+
+// encode encodes value with cache.Marshal and converts it the same way go-redis writes command arguments.
+func encode(e utilEncoding.Encoding, value interface{}) (string, error) {
+	val, err := cache.Marshal(e, value)
+	if err != nil {
+		return "", err
+	}
+
+	return toString(val)
+}
+
+func encodeFields(e utilEncoding.Encoding, value map[string]interface{}) (map[string]string, error) {
+	fields := make(map[string]string, len(value))
+	for field, v := range value {
+		val, err := encode(e, v)
+		if err != nil {
+			return nil, err
+		}
+		fields[field] = val
+	}
+
+	return fields, nil
+}
+
+func encodeAll(e utilEncoding.Encoding, values []interface{}) ([]string, error) {
+	vals := make([]string, 0, len(values))
+	for _, v := range values {
+		val, err := encode(e, v)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+	}
+
+	return vals, nil
+}
+
+func check(c *memoryClient) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed {
+		return errors.New("memory cache is closed")
+	}
+
+	return nil
+}
+
+// toString converts value the same way go-redis writes command arguments.
+func toString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case int:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 64), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case encoding.BinaryMarshaler:
+		b, err := v.MarshalBinary()
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	default:
+		return "", errors.New(fmt.Sprintf("redis: can't marshal %T (implement encoding.BinaryMarshaler)", value))
+	}
+}
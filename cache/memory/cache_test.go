@@ -0,0 +1,401 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/cache"
+	"github.com/Dert12318/Utilities/encoding/gzip"
+	"github.com/Dert12318/Utilities/encoding/msgpack"
+	helperTime "github.com/Dert12318/Utilities/helper/time"
+)
+
+type value string
+
+func (v value) MarshalBinary() ([]byte, error) {
+	return []byte(v), nil
+}
+
+func (v *value) UnmarshalBinary(data []byte) error {
+	*v = value(data)
+	return nil
+}
+
+func TestSetWithExpiration(t *testing.T) {
+	helperTime.Mock(time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC))
+	defer helperTime.ResetMock()
+
+	ctx := context.Background()
+	c, _ := New()
+
+	assert.NoError(t, c.SetWithExpiration(&ctx, "a", value("foo"), time.Minute))
+
+	var got value
+	assert.NoError(t, c.Get(&ctx, "a", &got))
+	assert.Equal(t, value("foo"), got)
+
+	helperTime.Mock(time.Date(2022, 10, 10, 0, 1, 0, 0, time.UTC))
+
+	err := c.Get(&ctx, "a", &got)
+	assert.Error(t, err)
+	assert.Equal(t, redis.Nil, errors.Cause(err))
+}
+
+func TestPlainStruct(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	ctx := context.Background()
+	c, _ := New()
+
+	assert.NoError(t, c.Set(&ctx, "u", user{Name: "foo"}))
+
+	var raw string
+	assert.NoError(t, c.Get(&ctx, "u", &raw))
+	assert.Equal(t, `{"name":"foo"}`, raw)
+
+	var got user
+	assert.NoError(t, c.Get(&ctx, "u", &got))
+	assert.Equal(t, "foo", got.Name)
+
+	gz := cache.WithEncoding(ctx, gzip.NewEncoding(msgpack.NewEncoding()))
+	assert.NoError(t, c.Set(&gz, "u", user{Name: "bar"}))
+	assert.Error(t, c.Get(&ctx, "u", &got))
+	assert.NoError(t, c.Get(&gz, "u", &got))
+	assert.Equal(t, "bar", got.Name)
+
+	var n int
+	assert.NoError(t, c.Set(&gz, "n", 42))
+	assert.NoError(t, c.Get(&ctx, "n", &n))
+	assert.Equal(t, 42, n)
+}
+
+func TestWrongType(t *testing.T) {
+	ctx := context.Background()
+	c, _ := New()
+
+	assert.NoError(t, c.HSet(&ctx, "h", "f", 1))
+
+	var got value
+	assert.Equal(t, errWrongType, errors.Cause(c.Get(&ctx, "h", &got)))
+	assert.NoError(t, c.HGet(&ctx, "h", "f", &got))
+	assert.Equal(t, value("1"), got)
+}
+
+func TestHashAndSet(t *testing.T) {
+	ctx := context.Background()
+	c, _ := New()
+
+	assert.NoError(t, c.HMSet(&ctx, "h", map[string]interface{}{"a": "1", "b": true}))
+
+	all, err := c.HGetAll(&ctx, "h")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "1"}, all)
+
+	fields, err := c.HMGet(&ctx, "h", "a", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"1", nil}, fields)
+
+	assert.NoError(t, c.SAdd(ctx, "s", "x", "y", "x"))
+	members, err := c.SMembers(ctx, "s")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"x", "y"}, members)
+
+	ok, err := c.SIsMember(ctx, "s", "z")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestHashZeroExpiration(t *testing.T) {
+	ctx := context.Background()
+	c, _ := New()
+
+	// like EXPIRE key 0, a zero TTL removes the key instead of making it persistent
+	assert.NoError(t, c.HMSetWithExpiration(&ctx, "h", map[string]interface{}{"a": "1"}, 0))
+	all, err := c.HGetAll(&ctx, "h")
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+
+	assert.NoError(t, c.HSet(&ctx, "h", "a", "1"))
+	assert.NoError(t, c.HSetWithExpiration(&ctx, "h", "b", "2", 0))
+	var got string
+	assert.Equal(t, redis.Nil, errors.Cause(c.HGet(&ctx, "h", "a", &got)))
+
+	assert.NoError(t, c.HSet(&ctx, "h", "a", "1"))
+	ttl, err := c.TTL(&ctx, "h")
+	assert.NoError(t, err)
+	assert.Equal(t, cache.NoExpiration, ttl)
+
+	ok, _ := c.Expire(&ctx, "h", 0)
+	assert.True(t, ok)
+	_, err = c.TTL(&ctx, "h")
+	assert.Equal(t, redis.Nil, errors.Cause(err))
+}
+
+func TestKeysAndRemoveByPattern(t *testing.T) {
+	ctx := context.Background()
+	c, _ := New()
+
+	for _, key := range []string{"user:1", "user:2", "user:10", "order:1"} {
+		assert.NoError(t, c.Set(&ctx, key, "v"))
+	}
+
+	keys, err := c.Keys(&ctx, "user:?")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user:1", "user:2"}, keys)
+
+	vals, err := c.MGet(&ctx, []string{"user:1", "missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"v", nil}, vals)
+
+	assert.NoError(t, c.RemoveByPattern(&ctx, "user:*", 10))
+
+	keys, err = c.Keys(&ctx, "*")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"order:1"}, keys)
+}
+
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+	c, _ := New()
+
+	p := c.Pipeline()
+	set := p.Set("a", "1")
+	incr := p.Incr("counter")
+	get := p.Get("a")
+	missing := p.Get("missing")
+
+	var got value
+	assert.Error(t, c.Get(&ctx, "a", &got))
+	assert.Equal(t, cache.ErrPipeNotExecuted, get.Err())
+
+	assert.NoError(t, p.Exec())
+	assert.NoError(t, set.Err())
+	assert.Equal(t, int64(1), incr.Val())
+	assert.NoError(t, get.Scan(&got))
+	assert.Equal(t, value("1"), got)
+	assert.Equal(t, redis.Nil, errors.Cause(missing.Err()))
+
+	// the queue is reset, new commands are not executed yet
+	assert.Equal(t, cache.ErrPipeNotExecuted, p.Get("a").Err())
+}
+
+func TestPipelineDiscard(t *testing.T) {
+	ctx := context.Background()
+	c, _ := New()
+
+	p := c.TxPipeline()
+	p.Set("a", "1")
+	assert.NoError(t, p.Discard())
+	assert.NoError(t, p.Exec())
+
+	var got string
+	assert.Error(t, c.Get(&ctx, "a", &got))
+}
+
+func TestWatch(t *testing.T) {
+	ctx := context.Background()
+	c, _ := New()
+	assert.NoError(t, c.Set(&ctx, "balance", 10))
+
+	err := c.Watch(&ctx, func(tx cache.Tx) error {
+		var balance int
+		if err := tx.Get("balance", &balance); err != nil {
+			return err
+		}
+
+		return tx.Pipelined(func(pipe cache.Pipe) error {
+			pipe.Set("balance", balance-3)
+			return nil
+		})
+	}, "balance")
+	assert.NoError(t, err)
+
+	var balance int
+	assert.NoError(t, c.Get(&ctx, "balance", &balance))
+	assert.Equal(t, 7, balance)
+}
+
+func TestWatchConflict(t *testing.T) {
+	ctx := context.Background()
+	c, _ := New()
+	assert.NoError(t, c.HSet(&ctx, "h", "f", "1"))
+
+	var result cache.StatusResult
+	err := c.Watch(&ctx, func(tx cache.Tx) error {
+		// written by someone else between the read and the transaction
+		assert.NoError(t, c.HSet(&ctx, "h", "f", "2"))
+
+		return tx.Pipelined(func(pipe cache.Pipe) error {
+			result = pipe.Set("other", "1")
+			return nil
+		})
+	}, "h")
+	assert.Equal(t, cache.ErrTxFailed, errors.Cause(err))
+	assert.Equal(t, cache.ErrTxFailed, result.Err())
+
+	var got string
+	assert.Error(t, c.Get(&ctx, "other", &got))
+}
+
+func TestSubscribe(t *testing.T) {
+	c, _ := New()
+
+	ps, err := c.Subscribe("events")
+	assert.NoError(t, err)
+
+	same, _ := c.Subscribe("events")
+	assert.Equal(t, ps, same)
+
+	assert.NoError(t, ps.Publish("hello"))
+
+	msg := <-ps.Channel()
+	assert.Equal(t, "events", msg.Channel)
+	assert.Equal(t, "hello", msg.Payload)
+
+	assert.NoError(t, ps.Close())
+	_, open := <-ps.Channel()
+	assert.False(t, open)
+}
+
+func TestCounterAndTTL(t *testing.T) {
+	ctx := context.Background()
+	c, _ := New()
+
+	ok, err := c.SetNX(&ctx, "lock", "a", 0)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, _ = c.SetNX(&ctx, "lock", "b", 0)
+	assert.False(t, ok)
+
+	n, err := c.Incr(&ctx, "counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, _ = c.IncrBy(&ctx, "counter", 9)
+	assert.Equal(t, int64(10), n)
+	_, err = c.Incr(&ctx, "lock")
+	assert.Error(t, err)
+
+	ttl, err := c.TTL(&ctx, "counter")
+	assert.NoError(t, err)
+	assert.Equal(t, cache.NoExpiration, ttl)
+
+	now := time.Now()
+	helperTime.Mock(now)
+	defer helperTime.ResetMock()
+
+	ok, _ = c.Expire(&ctx, "counter", time.Minute)
+	assert.True(t, ok)
+	ttl, _ = c.TTL(&ctx, "counter")
+	assert.Equal(t, time.Minute, ttl)
+
+	ok, _ = c.Persist(&ctx, "counter")
+	assert.True(t, ok)
+	ttl, _ = c.TTL(&ctx, "counter")
+	assert.Equal(t, cache.NoExpiration, ttl)
+
+	_, err = c.TTL(&ctx, "missing")
+	assert.Equal(t, redis.Nil, errors.Cause(err))
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	c, _ := New()
+
+	n, err := c.RPush(&ctx, "queue", "b", "c")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	n, _ = c.LPush(&ctx, "queue", "a")
+	assert.Equal(t, int64(3), n)
+
+	val, err := c.LRange(&ctx, "queue", 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, val)
+
+	val, _ = c.LRange(&ctx, "queue", -2, 10)
+	assert.Equal(t, []string{"b", "c"}, val)
+
+	var got string
+	assert.NoError(t, c.LPop(&ctx, "queue", &got))
+	assert.Equal(t, "a", got)
+	assert.NoError(t, c.RPop(&ctx, "queue", &got))
+	assert.Equal(t, "c", got)
+
+	n, _ = c.LLen(&ctx, "queue")
+	assert.Equal(t, int64(1), n)
+
+	assert.NoError(t, c.RPop(&ctx, "queue", &got))
+	assert.Equal(t, redis.Nil, errors.Cause(c.RPop(&ctx, "queue", &got)))
+}
+
+func TestSortedSet(t *testing.T) {
+	ctx := context.Background()
+	c, _ := New()
+
+	n, err := c.ZAdd(&ctx, "board", cache.Z{Score: 10, Member: "alice"}, cache.Z{Score: 5, Member: "bob"}, cache.Z{Score: 7, Member: "carol"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	score, err := c.ZIncrBy(&ctx, "board", 6, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(11), score)
+
+	val, _ := c.ZRevRange(&ctx, "board", 0, 1)
+	assert.Equal(t, []string{"bob", "alice"}, val)
+
+	zs, _ := c.ZRangeWithScores(&ctx, "board", 0, 0)
+	assert.Equal(t, []cache.Z{{Score: 7, Member: "carol"}}, zs)
+
+	rank, err := c.ZRevRank(&ctx, "board", "carol")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), rank)
+	_, err = c.ZRank(&ctx, "board", "dave")
+	assert.Equal(t, redis.Nil, errors.Cause(err))
+
+	val, _ = c.ZRangeByScore(&ctx, "board", cache.ZRangeBy{Min: "(7", Max: "+inf"})
+	assert.Equal(t, []string{"alice", "bob"}, val)
+
+	n, _ = c.ZRemRangeByScore(&ctx, "board", "-inf", "10")
+	assert.Equal(t, int64(2), n)
+	n, _ = c.ZRem(&ctx, "board", "bob")
+	assert.Equal(t, int64(1), n)
+
+	n, _ = c.ZCard(&ctx, "board")
+	assert.Equal(t, int64(0), n)
+}
+
+func TestScan(t *testing.T) {
+	ctx := context.Background()
+	c, _ := New()
+
+	assert.NoError(t, c.Set(&ctx, "user:1", "a"))
+	assert.NoError(t, c.Set(&ctx, "user:2", "b"))
+	assert.NoError(t, c.HSet(&ctx, "user:3", "f", "c"))
+	assert.NoError(t, c.Set(&ctx, "order:1", "d"))
+
+	var keys []string
+	it := c.Scan(&ctx, cache.ScanOption{Match: "user:*", Type: "string"})
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"user:1", "user:2"}, keys)
+
+	n, err := c.DeleteByPattern(&ctx, cache.ScanOption{Match: "user:*", Count: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	keys, _ = c.Keys(&ctx, "*")
+	assert.Equal(t, []string{"order:1"}, keys)
+
+	assert.NoError(t, c.Close())
+	it = c.Scan(&ctx, cache.ScanOption{})
+	assert.False(t, it.Next())
+	assert.Error(t, it.Err())
+}
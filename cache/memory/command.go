@@ -0,0 +1,245 @@
+package memory
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	helperTime "github.com/Dert12318/Utilities/helper/time"
+)
+
+var (
+	errNotInteger = errors.New("ERR value is not an integer or out of range")
+)
+
+// The commands below implement the redis semantics shared by the client methods and the pipelines.
+// Readers must hold at least the read lock, writers the write lock.
+
+func (c *memoryClient) setString(key, val string, ttl time.Duration) {
+	c.data[key] = &entry{kind: kindString, str: val, expiredAt: expiredAt(ttl)}
+}
+
+func (c *memoryClient) getString(key string) (string, bool, error) {
+	e, err := c.lookup(key, kindString)
+	if err != nil || e == nil {
+		return "", false, err
+	}
+
+	return e.str, true, nil
+}
+
+func (c *memoryClient) del(keys ...string) int64 {
+	var n int64
+	for _, key := range keys {
+		if e, ok := c.data[key]; ok {
+			if !e.isExpired(helperTime.Now()) {
+				n++
+			}
+			delete(c.data, key)
+		}
+	}
+
+	return n
+}
+
+// expire removes the key when ttl is not positive, like redis does
+func (c *memoryClient) expire(key string, ttl time.Duration) bool {
+	e, ok := c.data[key]
+	if !ok || e.isExpired(helperTime.Now()) {
+		return false
+	}
+
+	if ttl <= 0 {
+		delete(c.data, key)
+		return true
+	}
+
+	e.expiredAt = expiredAt(ttl)
+	e.version++
+	return true
+}
+
+func (c *memoryClient) incrBy(key string, value int64) (int64, error) {
+	e, err := c.lookupOrCreate(key, kindString)
+	if err != nil {
+		return 0, err
+	}
+
+	var current int64
+	if e.str != "" {
+		if current, err = strconv.ParseInt(e.str, 10, 64); err != nil {
+			return 0, errNotInteger
+		}
+	}
+
+	current += value
+	e.str = strconv.FormatInt(current, 10)
+	e.version++
+	return current, nil
+}
+
+func (c *memoryClient) hset(key, field, val string) (bool, error) {
+	e, err := c.lookupOrCreate(key, kindHash)
+	if err != nil {
+		return false, err
+	}
+
+	_, exists := e.hash[field]
+	e.hash[field] = val
+	e.version++
+	return !exists, nil
+}
+
+func (c *memoryClient) hmset(key string, fields map[string]string) error {
+	e, err := c.lookupOrCreate(key, kindHash)
+	if err != nil {
+		return err
+	}
+
+	for field, val := range fields {
+		e.hash[field] = val
+	}
+	e.version++
+	return nil
+}
+
+func (c *memoryClient) hget(key, field string) (string, bool, error) {
+	e, err := c.lookup(key, kindHash)
+	if err != nil || e == nil {
+		return "", false, err
+	}
+
+	val, ok := e.hash[field]
+	return val, ok, nil
+}
+
+func (c *memoryClient) hmget(key string, fields ...string) ([]interface{}, error) {
+	e, err := c.lookup(key, kindHash)
+	if err != nil {
+		return nil, err
+	}
+
+	val := make([]interface{}, len(fields))
+	for i, field := range fields {
+		if e == nil {
+			continue
+		}
+
+		if v, ok := e.hash[field]; ok {
+			val[i] = v
+		}
+	}
+
+	return val, nil
+}
+
+func (c *memoryClient) hgetall(key string) (map[string]string, error) {
+	e, err := c.lookup(key, kindHash)
+	if err != nil {
+		return nil, err
+	}
+
+	val := make(map[string]string)
+	if e == nil {
+		return val, nil
+	}
+
+	for field, v := range e.hash {
+		val[field] = v
+	}
+	return val, nil
+}
+
+func (c *memoryClient) hdel(key string, fields ...string) (int64, error) {
+	e, err := c.lookup(key, kindHash)
+	if err != nil || e == nil {
+		return 0, err
+	}
+
+	var n int64
+	for _, field := range fields {
+		if _, ok := e.hash[field]; ok {
+			delete(e.hash, field)
+			n++
+		}
+	}
+
+	e.version++
+	c.dropEmpty(key, e)
+	return n, nil
+}
+
+func (c *memoryClient) sadd(key string, members ...string) (int64, error) {
+	e, err := c.lookupOrCreate(key, kindSet)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for _, member := range members {
+		if _, ok := e.set[member]; !ok {
+			e.set[member] = struct{}{}
+			n++
+		}
+	}
+
+	e.version++
+	return n, nil
+}
+
+func (c *memoryClient) srem(key string, members ...string) (int64, error) {
+	e, err := c.lookup(key, kindSet)
+	if err != nil || e == nil {
+		return 0, err
+	}
+
+	var n int64
+	for _, member := range members {
+		if _, ok := e.set[member]; ok {
+			delete(e.set, member)
+			n++
+		}
+	}
+
+	e.version++
+	c.dropEmpty(key, e)
+	return n, nil
+}
+
+func (c *memoryClient) sismember(key, member string) (bool, error) {
+	e, err := c.lookup(key, kindSet)
+	if err != nil || e == nil {
+		return false, err
+	}
+
+	_, ok := e.set[member]
+	return ok, nil
+}
+
+func (c *memoryClient) smembers(key string) ([]string, error) {
+	e, err := c.lookup(key, kindSet)
+	if err != nil {
+		return nil, err
+	}
+
+	val := make([]string, 0)
+	if e == nil {
+		return val, nil
+	}
+
+	for member := range e.set {
+		val = append(val, member)
+	}
+	sort.Strings(val)
+	return val, nil
+}
+
+// dropEmpty removes aggregate keys left without elements, redis never keeps empty hashes, sets, lists
+// or sorted sets.
+func (c *memoryClient) dropEmpty(key string, e *entry) {
+	if len(e.hash) == 0 && len(e.set) == 0 && len(e.list) == 0 && len(e.zset) == 0 {
+		delete(c.data, key)
+	}
+}
@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+func (c *memoryClient) LPush(ctx *context.Context, key string, values ...interface{}) (int64, error) {
+	return c.push(ctx, key, values, true)
+}
+
+func (c *memoryClient) RPush(ctx *context.Context, key string, values ...interface{}) (int64, error) {
+	return c.push(ctx, key, values, false)
+}
+
+func (c *memoryClient) push(ctx *context.Context, key string, values []interface{}, left bool) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	vals, err := encodeAll(c.codec(ctx), values)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to push to list with key %s!", key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, err := c.lookupOrCreate(key, kindList)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to push to list with key %s!", key)
+	}
+
+	for _, val := range vals {
+		if left {
+			e.list = append([]string{val}, e.list...)
+		} else {
+			e.list = append(e.list, val)
+		}
+	}
+
+	e.version++
+	c.dropEmpty(key, e)
+	return int64(len(e.list)), nil
+}
+
+func (c *memoryClient) LPop(ctx *context.Context, key string, object interface{}) error {
+	return c.pop(ctx, key, object, true)
+}
+
+func (c *memoryClient) RPop(ctx *context.Context, key string, object interface{}) error {
+	return c.pop(ctx, key, object, false)
+}
+
+func (c *memoryClient) pop(ctx *context.Context, key string, object interface{}, left bool) error {
+	if err := check(c); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	e, err := c.lookup(key, kindList)
+	var val string
+	if e != nil {
+		if left {
+			val, e.list = e.list[0], e.list[1:]
+		} else {
+			val, e.list = e.list[len(e.list)-1], e.list[:len(e.list)-1]
+		}
+		e.version++
+		c.dropEmpty(key, e)
+	}
+	c.mu.Unlock()
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to pop from list with key %s!", key)
+	}
+
+	if e == nil {
+		return errors.Wrapf(redis.Nil, "key %s does not exits", key)
+	}
+
+	if err := cache.Unmarshal(c.codec(ctx), []byte(val), object); err != nil {
+		return errors.Wrapf(err, "failed to pop from list with key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *memoryClient) LRange(ctx *context.Context, key string, start, stop int64) ([]string, error) {
+	if err := check(c); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, err := c.lookup(key, kindList)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	val := make([]string, 0)
+	if e == nil {
+		return val, nil
+	}
+
+	lo, hi := span(len(e.list), start, stop)
+	return append(val, e.list[lo:hi]...), nil
+}
+
+func (c *memoryClient) LLen(ctx *context.Context, key string) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, err := c.lookup(key, kindList)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if e == nil {
+		return 0, nil
+	}
+
+	return int64(len(e.list)), nil
+}
+
+// span converts the inclusive, possibly negative, redis range start..stop over n elements to slice bounds.
+func span(n int, start, stop int64) (int, int) {
+	if start < 0 {
+		start += int64(n)
+	}
+	if stop < 0 {
+		stop += int64(n)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= int64(n) {
+		stop = int64(n) - 1
+	}
+	if start > stop {
+		return 0, 0
+	}
+
+	return int(start), int(stop) + 1
+}
@@ -0,0 +1,276 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+	utilEncoding "github.com/Dert12318/Utilities/encoding"
+)
+
+type (
+	// pipe queues commands and runs them under a single write lock on Exec, so a memory
+	// pipeline is always atomic and Pipeline and TxPipeline behave the same
+	pipe struct {
+		client   *memoryClient
+		encoding utilEncoding.Encoding
+		cmds     []command
+		state    *pipeState
+		// watched is the snapshot taken by Watch, Exec fails with cache.ErrTxFailed when it changed
+		watched map[string]watched
+	}
+
+	// pipeState is shared by a pipe and its results so they know when the commands ran
+	pipeState struct {
+		executed bool
+	}
+
+	command struct {
+		name   string
+		result *result
+		run    func() error
+	}
+)
+
+func newPipe(client *memoryClient, e utilEncoding.Encoding, w map[string]watched) *pipe {
+	return &pipe{client: client, encoding: e, state: &pipeState{}, watched: w}
+}
+
+func (p *pipe) Set(key string, value interface{}) cache.StatusResult {
+	return p.SetWithExpiration(key, value, 0)
+}
+
+func (p *pipe) SetWithExpiration(key string, value interface{}, expired time.Duration) cache.StatusResult {
+	val, err := encode(p.encoding, value)
+	if err != nil {
+		return &statusResult{result: p.failed(key, err)}
+	}
+
+	r := &statusResult{result: p.result(key)}
+	p.queue("set", &r.result, func() error {
+		p.client.setString(key, val, expired)
+		return nil
+	})
+	return r
+}
+
+func (p *pipe) Get(key string) cache.StringResult {
+	r := &stringResult{result: p.result(key), encoding: p.encoding}
+	p.queue("get", &r.result, func() error {
+		val, ok, err := p.client.getString(key)
+		if err == nil && !ok {
+			err = redis.Nil
+		}
+		r.val = val
+		return err
+	})
+	return r
+}
+
+func (p *pipe) Del(keys ...string) cache.IntResult {
+	r := &intResult{result: p.result(keys)}
+	p.queue("del", &r.result, func() error {
+		r.val = p.client.del(keys...)
+		return nil
+	})
+	return r
+}
+
+func (p *pipe) Expire(key string, expiration time.Duration) cache.BoolResult {
+	r := &boolResult{result: p.result(key)}
+	p.queue("expire", &r.result, func() error {
+		r.val = p.client.expire(key, expiration)
+		return nil
+	})
+	return r
+}
+
+func (p *pipe) Incr(key string) cache.IntResult {
+	return p.IncrBy(key, 1)
+}
+
+func (p *pipe) IncrBy(key string, value int64) cache.IntResult {
+	r := &intResult{result: p.result(key)}
+	p.queue("incrby", &r.result, func() (err error) {
+		r.val, err = p.client.incrBy(key, value)
+		return err
+	})
+	return r
+}
+
+func (p *pipe) HSet(key, field string, value interface{}) cache.BoolResult {
+	val, err := encode(p.encoding, value)
+	if err != nil {
+		return &boolResult{result: p.failed(key, err)}
+	}
+
+	r := &boolResult{result: p.result(key)}
+	p.queue("hset", &r.result, func() (err error) {
+		r.val, err = p.client.hset(key, field, val)
+		return err
+	})
+	return r
+}
+
+func (p *pipe) HMSet(key string, value map[string]interface{}) cache.StatusResult {
+	fields, err := encodeFields(p.encoding, value)
+	if err != nil {
+		return &statusResult{result: p.failed(key, err)}
+	}
+
+	r := &statusResult{result: p.result(key)}
+	p.queue("hmset", &r.result, func() error {
+		return p.client.hmset(key, fields)
+	})
+	return r
+}
+
+func (p *pipe) HGet(key, field string) cache.StringResult {
+	r := &stringResult{result: p.result(key), encoding: p.encoding}
+	p.queue("hget", &r.result, func() error {
+		val, ok, err := p.client.hget(key, field)
+		if err == nil && !ok {
+			err = redis.Nil
+		}
+		r.val = val
+		return err
+	})
+	return r
+}
+
+func (p *pipe) HMGet(key string, fields ...string) cache.SliceResult {
+	r := &sliceResult{result: p.result(key)}
+	p.queue("hmget", &r.result, func() (err error) {
+		r.val, err = p.client.hmget(key, fields...)
+		return err
+	})
+	return r
+}
+
+func (p *pipe) HGetAll(key string) cache.StringMapResult {
+	r := &stringMapResult{result: p.result(key)}
+	p.queue("hgetall", &r.result, func() (err error) {
+		r.val, err = p.client.hgetall(key)
+		return err
+	})
+	return r
+}
+
+func (p *pipe) HDel(key string, fields ...string) cache.IntResult {
+	r := &intResult{result: p.result(key)}
+	p.queue("hdel", &r.result, func() (err error) {
+		r.val, err = p.client.hdel(key, fields...)
+		return err
+	})
+	return r
+}
+
+func (p *pipe) SAdd(key string, members ...interface{}) cache.IntResult {
+	vals, err := encodeAll(p.encoding, members)
+	if err != nil {
+		return &intResult{result: p.failed(key, err)}
+	}
+
+	r := &intResult{result: p.result(key)}
+	p.queue("sadd", &r.result, func() (err error) {
+		r.val, err = p.client.sadd(key, vals...)
+		return err
+	})
+	return r
+}
+
+func (p *pipe) SRem(key string, members ...interface{}) cache.IntResult {
+	vals, err := encodeAll(p.encoding, members)
+	if err != nil {
+		return &intResult{result: p.failed(key, err)}
+	}
+
+	r := &intResult{result: p.result(key)}
+	p.queue("srem", &r.result, func() (err error) {
+		r.val, err = p.client.srem(key, vals...)
+		return err
+	})
+	return r
+}
+
+func (p *pipe) SIsMember(key string, member interface{}) cache.BoolResult {
+	val, err := encode(p.encoding, member)
+	if err != nil {
+		return &boolResult{result: p.failed(key, err)}
+	}
+
+	r := &boolResult{result: p.result(key)}
+	p.queue("sismember", &r.result, func() (err error) {
+		r.val, err = p.client.sismember(key, val)
+		return err
+	})
+	return r
+}
+
+func (p *pipe) SMembers(key string) cache.StringSliceResult {
+	r := &stringSliceResult{result: p.result(key)}
+	p.queue("smembers", &r.result, func() (err error) {
+		r.val, err = p.client.smembers(key)
+		return err
+	})
+	return r
+}
+
+func (p *pipe) Exec() error {
+	if err := check(p.client); err != nil {
+		return err
+	}
+
+	cmds, state := p.cmds, p.state
+	p.cmds, p.state = nil, &pipeState{}
+
+	p.client.mu.Lock()
+	defer p.client.mu.Unlock()
+
+	state.executed = true
+	if p.client.changed(p.watched) {
+		for _, cmd := range cmds {
+			cmd.result.err = cache.ErrTxFailed
+		}
+		return errors.Wrap(cache.ErrTxFailed, "failed to memory pipeline")
+	}
+
+	var first error
+	for _, cmd := range cmds {
+		err := cmd.run()
+		if err == nil {
+			continue
+		}
+
+		cmd.result.fail(cmd.name, err)
+		if first == nil && err != redis.Nil {
+			first = err
+		}
+	}
+
+	if first != nil {
+		return errors.Wrapf(first, "failed to memory pipeline")
+	}
+
+	return nil
+}
+
+func (p *pipe) Discard() error {
+	p.cmds = nil
+	return nil
+}
+
+func (p *pipe) queue(name string, r *result, run func() error) {
+	p.cmds = append(p.cmds, command{name: name, result: r, run: run})
+}
+
+func (p *pipe) result(key interface{}) result {
+	return result{key: key, state: p.state}
+}
+
+// failed is the result of a command that could not be queued
+func (p *pipe) failed(key interface{}, err error) result {
+	return result{key: key, err: errors.Wrapf(err, "failed to queue command for key %v", key), state: p.state}
+}
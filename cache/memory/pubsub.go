@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+// channelSize mirrors the buffer go-redis allocates for PubSub.Channel.
+const channelSize = 100
+
+type (
+	pubsub struct {
+		c      *memoryClient
+		cn     string
+		mu     sync.RWMutex
+		ch     chan *redis.Message
+		closed bool
+	}
+)
+
+func newPubSub(c *memoryClient, channel string) *pubsub {
+	return &pubsub{
+		c:  c,
+		cn: channel,
+		ch: make(chan *redis.Message, channelSize),
+	}
+}
+
+func (p *pubsub) Receive() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return errors.Wrap(errors.New("pubsub is closed"), "failed to receive")
+	}
+
+	return nil
+}
+
+func (p *pubsub) Publish(message string) error {
+	if err := check(p.c); err != nil {
+		return errors.Wrapf(err, "failed to publish message to cn %s", p.cn)
+	}
+
+	p.c.publish(p.cn, message)
+	return nil
+}
+
+func (p *pubsub) Channel() <-chan *redis.Message {
+	return p.ch
+}
+
+func (p *pubsub) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.ch)
+	p.mu.Unlock()
+
+	p.c.unsubscribe(p)
+	return nil
+}
+
+// deliver drops the message when the subscriber is not keeping up, like a slow redis client would.
+func (p *pubsub) deliver(message string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return
+	}
+
+	select {
+	case p.ch <- &redis.Message{Channel: p.cn, Payload: message}:
+	default:
+	}
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+	utilEncoding "github.com/Dert12318/Utilities/encoding"
+)
+
+type (
+	result struct {
+		key   interface{}
+		err   error
+		state *pipeState
+	}
+
+	statusResult struct {
+		result
+	}
+
+	stringResult struct {
+		result
+		val      string
+		encoding utilEncoding.Encoding
+	}
+
+	intResult struct {
+		result
+		val int64
+	}
+
+	boolResult struct {
+		result
+		val bool
+	}
+
+	sliceResult struct {
+		result
+		val []interface{}
+	}
+
+	stringSliceResult struct {
+		result
+		val []string
+	}
+
+	stringMapResult struct {
+		result
+		val map[string]string
+	}
+)
+
+func (r *result) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if !r.state.executed {
+		return cache.ErrPipeNotExecuted
+	}
+
+	return nil
+}
+
+// fail records the error of the command the same way redis-universal reports it
+func (r *result) fail(name string, err error) {
+	if err == redis.Nil {
+		r.err = errors.Wrapf(err, "key %v does not exits", r.key)
+		return
+	}
+
+	r.err = errors.Wrapf(err, "failed to %s key %v", name, r.key)
+}
+
+func (r *stringResult) Val() string {
+	return r.val
+}
+
+func (r *stringResult) Scan(object interface{}) error {
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	if err := cache.Unmarshal(r.encoding, []byte(r.val), object); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal object")
+	}
+
+	return nil
+}
+
+func (r *intResult) Val() int64 {
+	return r.val
+}
+
+func (r *boolResult) Val() bool {
+	return r.val
+}
+
+func (r *sliceResult) Val() []interface{} {
+	return r.val
+}
+
+func (r *stringSliceResult) Val() []string {
+	return r.val
+}
+
+func (r *stringMapResult) Val() map[string]string {
+	return r.val
+}
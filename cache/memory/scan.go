@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+type (
+	// scanIterator walks a snapshot of the keys taken when the iteration starts
+	scanIterator struct {
+		keys []string
+		pos  int
+		key  string
+		err  error
+	}
+)
+
+var kindNames = map[kind]string{
+	kindString: "string",
+	kindHash:   "hash",
+	kindSet:    "set",
+	kindList:   "list",
+	kindZSet:   "zset",
+}
+
+func (c *memoryClient) Scan(ctx *context.Context, option cache.ScanOption) cache.Iterator {
+	if err := check(c); err != nil {
+		return &scanIterator{err: err}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return &scanIterator{keys: c.scan(option)}
+}
+
+func (it *scanIterator) Next() bool {
+	if it.err != nil || it.pos >= len(it.keys) {
+		return false
+	}
+
+	it.key = it.keys[it.pos]
+	it.pos++
+	return true
+}
+
+func (it *scanIterator) Key() string {
+	return it.key
+}
+
+func (it *scanIterator) Err() error {
+	return it.err
+}
+
+func (c *memoryClient) DeleteByPattern(ctx *context.Context, option cache.ScanOption) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.del(c.scan(option)...), nil
+}
+
+// scan returns the sorted live keys selected by option. The caller must hold at least the read lock.
+func (c *memoryClient) scan(option cache.ScanOption) []string {
+	pattern := option.Match
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	keys := c.keys(pattern)
+	if option.Type == "" {
+		return keys
+	}
+
+	filtered := keys[:0]
+	for _, key := range keys {
+		if kindNames[c.data[key].kind] == option.Type {
+			filtered = append(filtered, key)
+		}
+	}
+
+	return filtered
+}
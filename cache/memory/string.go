@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+	helperTime "github.com/Dert12318/Utilities/helper/time"
+)
+
+func (c *memoryClient) SetNX(ctx *context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if err := check(c); err != nil {
+		return false, err
+	}
+
+	val, err := c.toString(ctx, value)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to set cache with key %s!", key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.data[key]; ok && !e.isExpired(helperTime.Now()) {
+		return false, nil
+	}
+
+	c.setString(key, val, ttl)
+	return true, nil
+}
+
+func (c *memoryClient) Incr(ctx *context.Context, key string) (int64, error) {
+	return c.IncrBy(ctx, key, 1)
+}
+
+func (c *memoryClient) IncrBy(ctx *context.Context, key string, value int64) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	val, err := c.incrBy(key, value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to increment key %s!", key)
+	}
+
+	return val, nil
+}
+
+func (c *memoryClient) Expire(ctx *context.Context, key string, ttl time.Duration) (bool, error) {
+	if err := check(c); err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.expire(key, ttl), nil
+}
+
+func (c *memoryClient) TTL(ctx *context.Context, key string) (time.Duration, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := helperTime.Now()
+	e, ok := c.data[key]
+	if !ok || e.isExpired(now) {
+		return 0, errors.Wrapf(redis.Nil, "key %s does not exits", key)
+	}
+
+	if e.expiredAt.IsZero() {
+		return cache.NoExpiration, nil
+	}
+
+	return e.expiredAt.Sub(now), nil
+}
+
+func (c *memoryClient) Persist(ctx *context.Context, key string) (bool, error) {
+	if err := check(c); err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.data[key]
+	if !ok || e.isExpired(helperTime.Now()) || e.expiredAt.IsZero() {
+		return false, nil
+	}
+
+	e.expiredAt = time.Time{}
+	e.version++
+	return true, nil
+}
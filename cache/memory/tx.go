@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/Dert12318/Utilities/cache"
+	utilEncoding "github.com/Dert12318/Utilities/encoding"
+	helperTime "github.com/Dert12318/Utilities/helper/time"
+)
+
+type (
+	tx struct {
+		client   *memoryClient
+		ctx      *context.Context
+		encoding utilEncoding.Encoding
+		watched  map[string]watched
+	}
+
+	// watched is the state of a key when Watch started, the entry pointer changes when the key is
+	// replaced or deleted and the version when it is written in place
+	watched struct {
+		entry   *entry
+		version uint64
+	}
+)
+
+func (t *tx) Get(key string, object interface{}) error {
+	return t.client.Get(t.ctx, key, object)
+}
+
+func (t *tx) HGet(key, field string, object interface{}) error {
+	return t.client.HGet(t.ctx, key, field, object)
+}
+
+func (t *tx) Pipelined(fn func(pipe cache.Pipe) error) error {
+	p := newPipe(t.client, t.encoding, t.watched)
+	if err := fn(p); err != nil {
+		return err
+	}
+
+	return p.Exec()
+}
+
+// snapshot records the current state of keys. The caller must hold at least the read lock.
+func (c *memoryClient) snapshot(keys ...string) map[string]watched {
+	now := helperTime.Now()
+	w := make(map[string]watched, len(keys))
+	for _, key := range keys {
+		e, ok := c.data[key]
+		if !ok || e.isExpired(now) {
+			w[key] = watched{}
+			continue
+		}
+		w[key] = watched{entry: e, version: e.version}
+	}
+
+	return w
+}
+
+// changed reports whether any key differs from the snapshot. The caller must hold at least the read lock.
+func (c *memoryClient) changed(w map[string]watched) bool {
+	if len(w) == 0 {
+		return false
+	}
+
+	current := c.snapshot(keysOf(w)...)
+	for key, before := range w {
+		if current[key] != before {
+			return true
+		}
+	}
+
+	return false
+}
+
+func keysOf(w map[string]watched) []string {
+	keys := make([]string, 0, len(w))
+	for key := range w {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,396 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+type (
+	// scoreBound is a parsed ZRangeBy limit
+	scoreBound struct {
+		score     float64
+		exclusive bool
+	}
+)
+
+func (c *memoryClient) ZAdd(ctx *context.Context, key string, members ...cache.Z) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	vals := make([]string, 0, len(members))
+	for _, member := range members {
+		val, err := c.toString(ctx, member.Member)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to add to sorted set with key %s!", key)
+		}
+		vals = append(vals, val)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, err := c.lookupOrCreate(key, kindZSet)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to add to sorted set with key %s!", key)
+	}
+
+	var n int64
+	for i, val := range vals {
+		if _, ok := e.zset[val]; !ok {
+			n++
+		}
+		e.zset[val] = members[i].Score
+	}
+
+	e.version++
+	c.dropEmpty(key, e)
+	return n, nil
+}
+
+func (c *memoryClient) ZIncrBy(ctx *context.Context, key string, increment float64, member interface{}) (float64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	m, err := c.toString(ctx, member)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to increment sorted set with key %s!", key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, err := c.lookupOrCreate(key, kindZSet)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to increment sorted set with key %s!", key)
+	}
+
+	e.zset[m] += increment
+	e.version++
+	return e.zset[m], nil
+}
+
+func (c *memoryClient) ZScore(ctx *context.Context, key string, member interface{}) (float64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	m, err := c.toString(ctx, member)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, err := c.lookup(key, kindZSet)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if e == nil {
+		return 0, errors.Wrapf(redis.Nil, "key %s does not exits", key)
+	}
+
+	score, ok := e.zset[m]
+	if !ok {
+		return 0, errors.Wrapf(redis.Nil, "key %s does not exits", key)
+	}
+
+	return score, nil
+}
+
+func (c *memoryClient) ZRank(ctx *context.Context, key string, member interface{}) (int64, error) {
+	return c.rank(ctx, key, member, false)
+}
+
+func (c *memoryClient) ZRevRank(ctx *context.Context, key string, member interface{}) (int64, error) {
+	return c.rank(ctx, key, member, true)
+}
+
+func (c *memoryClient) rank(ctx *context.Context, key string, member interface{}, reverse bool) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	m, err := c.toString(ctx, member)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, err := c.lookup(key, kindZSet)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if e != nil {
+		for i, z := range e.sorted(reverse) {
+			if z.Member == m {
+				return int64(i), nil
+			}
+		}
+	}
+
+	return 0, errors.Wrapf(redis.Nil, "key %s does not exits", key)
+}
+
+func (c *memoryClient) ZRange(ctx *context.Context, key string, start, stop int64) ([]string, error) {
+	return members(c.ZRangeWithScores(ctx, key, start, stop))
+}
+
+func (c *memoryClient) ZRevRange(ctx *context.Context, key string, start, stop int64) ([]string, error) {
+	return members(c.ZRevRangeWithScores(ctx, key, start, stop))
+}
+
+func (c *memoryClient) ZRangeWithScores(ctx *context.Context, key string, start, stop int64) ([]cache.Z, error) {
+	return c.zrange(key, start, stop, false)
+}
+
+func (c *memoryClient) ZRevRangeWithScores(ctx *context.Context, key string, start, stop int64) ([]cache.Z, error) {
+	return c.zrange(key, start, stop, true)
+}
+
+func (c *memoryClient) zrange(key string, start, stop int64, reverse bool) ([]cache.Z, error) {
+	if err := check(c); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, err := c.lookup(key, kindZSet)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if e == nil {
+		return []cache.Z{}, nil
+	}
+
+	zs := e.sorted(reverse)
+	lo, hi := span(len(zs), start, stop)
+	return zs[lo:hi], nil
+}
+
+func (c *memoryClient) ZRangeByScore(ctx *context.Context, key string, opt cache.ZRangeBy) ([]string, error) {
+	if err := check(c); err != nil {
+		return nil, err
+	}
+
+	min, err := parseScoreBound(opt.Min)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	max, err := parseScoreBound(opt.Max)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, err := c.lookup(key, kindZSet)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	val := make([]string, 0)
+	if e == nil {
+		return val, nil
+	}
+
+	var skipped int64
+	for _, z := range e.sorted(false) {
+		if !inRange(z.Score, min, max) {
+			continue
+		}
+
+		if skipped < opt.Offset {
+			skipped++
+			continue
+		}
+
+		if opt.Count > 0 && int64(len(val)) >= opt.Count {
+			break
+		}
+		val = append(val, z.Member.(string))
+	}
+
+	return val, nil
+}
+
+func (c *memoryClient) ZRem(ctx *context.Context, key string, members ...interface{}) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	vals, err := encodeAll(c.codec(ctx), members)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to remove from sorted set with key %s!", key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, err := c.lookup(key, kindZSet)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to remove from sorted set with key %s!", key)
+	}
+
+	if e == nil {
+		return 0, nil
+	}
+
+	var n int64
+	for _, val := range vals {
+		if _, ok := e.zset[val]; ok {
+			delete(e.zset, val)
+			n++
+		}
+	}
+
+	e.version++
+	c.dropEmpty(key, e)
+	return n, nil
+}
+
+func (c *memoryClient) ZRemRangeByScore(ctx *context.Context, key string, min, max string) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	lo, err := parseScoreBound(min)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to remove from sorted set with key %s!", key)
+	}
+
+	hi, err := parseScoreBound(max)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to remove from sorted set with key %s!", key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, err := c.lookup(key, kindZSet)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to remove from sorted set with key %s!", key)
+	}
+
+	if e == nil {
+		return 0, nil
+	}
+
+	var n int64
+	for member, score := range e.zset {
+		if inRange(score, lo, hi) {
+			delete(e.zset, member)
+			n++
+		}
+	}
+
+	e.version++
+	c.dropEmpty(key, e)
+	return n, nil
+}
+
+func (c *memoryClient) ZCard(ctx *context.Context, key string) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, err := c.lookup(key, kindZSet)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if e == nil {
+		return 0, nil
+	}
+
+	return int64(len(e.zset)), nil
+}
+
+// sorted returns the members ordered by score then member the way redis orders a sorted set
+func (e *entry) sorted(reverse bool) []cache.Z {
+	zs := make([]cache.Z, 0, len(e.zset))
+	for member, score := range e.zset {
+		zs = append(zs, cache.Z{Score: score, Member: member})
+	}
+
+	sort.Slice(zs, func(i, j int) bool {
+		a, b := zs[i], zs[j]
+		if reverse {
+			a, b = b, a
+		}
+
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		return a.Member.(string) < b.Member.(string)
+	})
+
+	return zs
+}
+
+func members(zs []cache.Z, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	val := make([]string, 0, len(zs))
+	for _, z := range zs {
+		val = append(val, z.Member.(string))
+	}
+
+	return val, nil
+}
+
+// parseScoreBound parses the redis score syntax: a number, "-inf", "+inf" or "(" for an exclusive bound
+func parseScoreBound(s string) (scoreBound, error) {
+	var b scoreBound
+	if strings.HasPrefix(s, "(") {
+		b.exclusive = true
+		s = s[1:]
+	}
+
+	switch s {
+	case "-inf":
+		b.score = math.Inf(-1)
+	case "+inf", "inf":
+		b.score = math.Inf(1)
+	default:
+		score, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return b, errors.New("ERR min or max is not a float")
+		}
+		b.score = score
+	}
+
+	return b, nil
+}
+
+// inRange reports whether score lies between the bounds min and max
+func inRange(score float64, min, max scoreBound) bool {
+	if score < min.score || (min.exclusive && score == min.score) {
+		return false
+	}
+
+	return score < max.score || (!max.exclusive && score == max.score)
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"time"
+)
+
+const (
+	// Hit is a read command that found its key or field
+	Hit Result = "hit"
+	// Miss is a read command on a missing key or field, go-redis reports it as redis.Nil
+	Miss Result = "miss"
+	// Ok is any other command that succeeded
+	Ok Result = "ok"
+	// Error is a command that failed for any other reason
+	Error Result = "error"
+)
+
+type (
+	Result string
+
+	// PoolStats mirrors the go-redis pool stats, counters are cumulative since the client was created
+	PoolStats struct {
+		Hits       uint32
+		Misses     uint32
+		Timeouts   uint32
+		TotalConns uint32
+		IdleConns  uint32
+		StaleConns uint32
+	}
+
+	// Recorder receives the metrics of a cache backend, see Registry for the default implementation
+	Recorder interface {
+		// ObserveCommand is called after every command, the commands of a pipeline are reported
+		// with the latency of the whole pipeline
+		ObserveCommand(command string, result Result, duration time.Duration)
+		// RegisterPool is called once per client with a function returning the current stats of its pool
+		RegisterPool(stats func() PoolStats)
+	}
+)
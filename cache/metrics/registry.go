@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dert12318/Utilities/helper/prometheus"
+)
+
+const DefaultNamespace = "cache"
+
+// DefaultBuckets are the upper bounds in seconds of the latency histogram, from 0.5ms to 1s
+var DefaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+type (
+	Option struct {
+		// Namespace prefixes every metric name, defaults to DefaultNamespace
+		Namespace string
+		// Buckets are the upper bounds in seconds of the latency histogram, defaults to DefaultBuckets
+		Buckets []float64
+	}
+
+	// Registry keeps the metrics in memory and writes them in the Prometheus text format,
+	// it is an http.Handler so it can be mounted as the metrics endpoint.
+	Registry struct {
+		namespace string
+		buckets   []float64
+
+		mu       sync.Mutex
+		commands map[string]*command
+		pools    []func() PoolStats
+	}
+
+	command struct {
+		results  map[Result]uint64
+		duration *prometheus.Histogram
+	}
+)
+
+func NewRegistry(option *Option) *Registry {
+	r := &Registry{
+		namespace: DefaultNamespace,
+		buckets:   DefaultBuckets,
+		commands:  make(map[string]*command),
+	}
+
+	if option != nil {
+		if option.Namespace != "" {
+			r.namespace = option.Namespace
+		}
+		r.buckets = prometheus.Buckets(option.Buckets, DefaultBuckets)
+	}
+
+	return r
+}
+
+func (r *Registry) ObserveCommand(name string, result Result, duration time.Duration) {
+	name = strings.ToLower(name)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.commands[name]
+	if !ok {
+		c = &command{results: make(map[Result]uint64), duration: prometheus.NewHistogram(r.buckets)}
+		r.commands[name] = c
+	}
+
+	c.results[result]++
+	c.duration.Observe(duration.Seconds())
+}
+
+// RegisterPool adds a pool to the registry, the stats of every registered pool are summed
+func (r *Registry) RegisterPool(stats func() PoolStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pools = append(r.pools, stats)
+}
+
+// WritePrometheus writes every metric in the Prometheus text exposition format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.commands))
+	for name := range r.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b := prometheus.NewWriter(w, r.namespace)
+
+	b.Header("commands_total", "counter", "Commands executed by result.")
+	for _, name := range names {
+		c := r.commands[name]
+		for _, result := range []Result{Hit, Miss, Ok, Error} {
+			if n, ok := c.results[result]; ok {
+				b.Value("commands_total", n, prometheus.Label{Name: "command", Value: name}, prometheus.Label{Name: "result", Value: string(result)})
+			}
+		}
+	}
+
+	b.Header("command_duration_seconds", "histogram", "Latency of the commands.")
+	for _, name := range names {
+		b.Histogram("command_duration_seconds", r.commands[name].duration, prometheus.Label{Name: "command", Value: name})
+	}
+
+	pools := r.pools
+	r.mu.Unlock()
+
+	if len(pools) > 0 {
+		var stats PoolStats
+		for _, pool := range pools {
+			s := pool()
+			stats.Hits += s.Hits
+			stats.Misses += s.Misses
+			stats.Timeouts += s.Timeouts
+			stats.TotalConns += s.TotalConns
+			stats.IdleConns += s.IdleConns
+			stats.StaleConns += s.StaleConns
+		}
+
+		b.Header("pool_hits_total", "counter", "Times a free connection was found in the pool.")
+		b.Value("pool_hits_total", uint64(stats.Hits))
+		b.Header("pool_misses_total", "counter", "Times a free connection was not found in the pool.")
+		b.Value("pool_misses_total", uint64(stats.Misses))
+		b.Header("pool_timeouts_total", "counter", "Times waiting for a connection timed out.")
+		b.Value("pool_timeouts_total", uint64(stats.Timeouts))
+		b.Header("pool_stale_connections_total", "counter", "Stale connections removed from the pool.")
+		b.Value("pool_stale_connections_total", uint64(stats.StaleConns))
+		b.Header("pool_connections", "gauge", "Connections in the pool by state.")
+		b.Value("pool_connections", uint64(stats.TotalConns), prometheus.Label{Name: "state", Value: "total"})
+		b.Value("pool_connections", uint64(stats.IdleConns), prometheus.Label{Name: "state", Value: "idle"})
+	}
+
+	return b.Flush()
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	prometheus.Serve(w, r.WritePrometheus)
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(&Option{Namespace: "redis", Buckets: []float64{0.01, 0.001}})
+	r.ObserveCommand("GET", Hit, 500*time.Microsecond)
+	r.ObserveCommand("get", Miss, 5*time.Millisecond)
+	r.ObserveCommand("set", Error, time.Second)
+	r.RegisterPool(func() PoolStats { return PoolStats{Hits: 3, TotalConns: 2, IdleConns: 1} })
+	r.RegisterPool(func() PoolStats { return PoolStats{Hits: 1, TotalConns: 1, IdleConns: 1} })
+
+	var b bytes.Buffer
+	assert.NoError(t, r.WritePrometheus(&b))
+
+	out := b.String()
+	assert.Contains(t, out, "# TYPE redis_commands_total counter\n")
+	assert.Contains(t, out, `redis_commands_total{command="get",result="hit"} 1`)
+	assert.Contains(t, out, `redis_commands_total{command="get",result="miss"} 1`)
+	assert.Contains(t, out, `redis_commands_total{command="set",result="error"} 1`)
+	assert.Contains(t, out, "# TYPE redis_command_duration_seconds histogram\n")
+	assert.Contains(t, out, `redis_command_duration_seconds_bucket{command="get",le="0.001"} 1`)
+	assert.Contains(t, out, `redis_command_duration_seconds_bucket{command="get",le="0.01"} 2`)
+	assert.Contains(t, out, `redis_command_duration_seconds_bucket{command="get",le="+Inf"} 2`)
+	assert.Contains(t, out, `redis_command_duration_seconds_bucket{command="set",le="0.01"} 0`)
+	assert.Contains(t, out, `redis_command_duration_seconds_count{command="set"} 1`)
+	assert.Contains(t, out, "redis_pool_hits_total 4\n")
+	assert.Contains(t, out, `redis_pool_connections{state="total"} 3`)
+	assert.Contains(t, out, `redis_pool_connections{state="idle"} 2`)
+}
@@ -0,0 +1,440 @@
+package pool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+	"github.com/Dert12318/Utilities/logs"
+	"github.com/Dert12318/Utilities/logs/logrus"
+)
+
+const (
+	DefaultMaxActive           = 100
+	DefaultHealthCheckInterval = 30 * time.Second
+	DefaultHealthCheckTimeout  = 5 * time.Second
+)
+
+var (
+	ErrUnknownClient = errors.New("unknown cache client")
+	ErrPoolClosed    = errors.New("cache pool is closed")
+)
+
+type (
+	// Factory connects a client, it is called again to reconnect once the client fails its health check
+	Factory func() (cache.Cache, error)
+
+	Option struct {
+		// Default is the name of the client used by Use and Client, defaults to the only client when there is one
+		Default string
+		// MaxActive bounds how many callbacks may borrow the same client at once, defaults to DefaultMaxActive,
+		// negative is unbounded
+		MaxActive int
+		// HealthCheckInterval is how often connected clients are pinged, negative disables health checks
+		HealthCheckInterval time.Duration
+		HealthCheckTimeout  time.Duration
+		Log                 logs.Logger
+	}
+
+	pool struct {
+		option  Option
+		clients map[string]*client
+		stop    chan struct{}
+		wg      sync.WaitGroup
+
+		mu     sync.RWMutex
+		closed bool
+	}
+
+	client struct {
+		name    string
+		factory Factory
+		slots   chan struct{}
+
+		mu      sync.Mutex
+		current *conn
+		dialing *dial
+		closed  bool
+	}
+
+	// conn is a connected cache, once replaced it is closed when its last borrower returned it
+	conn struct {
+		cache   cache.Cache
+		borrows int
+		retired bool
+	}
+
+	// dial is a call of the factory in progress, done is closed once current is set or err is
+	dial struct {
+		done chan struct{}
+		err  error
+	}
+)
+
+// New returns a cache.Pool over the clients of factories, e.g. one per tenant DB index or per Redis cluster.
+// Clients are connected on first use and reconnected once they fail a Ping.
+func New(factories map[string]Factory, option *Option) (cache.Pool, error) {
+	if len(factories) == 0 {
+		return nil, errors.New("cache pool needs at least one client")
+	}
+
+	o := Option{
+		MaxActive:           DefaultMaxActive,
+		HealthCheckInterval: DefaultHealthCheckInterval,
+		HealthCheckTimeout:  DefaultHealthCheckTimeout,
+		Log:                 logrus.DefaultLog(),
+	}
+
+	if option != nil {
+		o.Default = option.Default
+
+		if option.MaxActive != 0 {
+			o.MaxActive = option.MaxActive
+		}
+		if option.HealthCheckInterval != 0 {
+			o.HealthCheckInterval = option.HealthCheckInterval
+		}
+		if option.HealthCheckTimeout > 0 {
+			o.HealthCheckTimeout = option.HealthCheckTimeout
+		}
+		if option.Log != nil {
+			o.Log = option.Log
+		}
+	}
+
+	if o.Default == "" && len(factories) == 1 {
+		for name := range factories {
+			o.Default = name
+		}
+	}
+
+	p := &pool{
+		option:  o,
+		clients: make(map[string]*client, len(factories)),
+		stop:    make(chan struct{}),
+	}
+
+	for name, factory := range factories {
+		c := &client{name: name, factory: factory}
+		if o.MaxActive > 0 {
+			c.slots = make(chan struct{}, o.MaxActive)
+		}
+		p.clients[name] = c
+	}
+
+	if o.HealthCheckInterval > 0 {
+		p.wg.Add(1)
+		go p.healthCheck()
+	}
+
+	return p, nil
+}
+
+func (p *pool) Use(callback cache.PoolCallback) {
+	ctx := context.Background()
+	if err := p.Borrow(&ctx, p.option.Default, func(client cache.Cache) error {
+		callback(client)
+		return nil
+	}); err != nil {
+		p.option.Log.Error(errors.Wrapf(err, "failed to use cache client %s", p.option.Default))
+	}
+}
+
+func (p *pool) Client() cache.Cache {
+	ctx := context.Background()
+	c, err := p.Get(&ctx, p.option.Default)
+	if err != nil {
+		p.option.Log.Error(errors.Wrapf(err, "failed to get cache client %s", p.option.Default))
+		return nil
+	}
+
+	return c
+}
+
+func (p *pool) Get(ctx *context.Context, name string) (cache.Cache, error) {
+	c, err := p.client(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cn, err := c.connect(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return cn.cache, nil
+}
+
+func (p *pool) Borrow(ctx *context.Context, name string, callback func(client cache.Cache) error) error {
+	c, err := p.client(name)
+	if err != nil {
+		return err
+	}
+
+	if c.slots != nil {
+		select {
+		case c.slots <- struct{}{}:
+			defer func() { <-c.slots }()
+		case <-doneChannel(ctx):
+			return errors.Wrapf((*ctx).Err(), "failed to borrow cache client %s", name)
+		}
+	}
+
+	cn, err := c.connect(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := c.release(cn); err != nil {
+			p.option.Log.Error(err)
+		}
+	}()
+
+	return callback(cn.cache)
+}
+
+func (p *pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	close(p.stop)
+	p.wg.Wait()
+
+	var failed []string
+	for _, name := range p.names() {
+		if err := p.clients[name].close(); err != nil {
+			p.option.Log.Error(err)
+			failed = append(failed, name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("failed to close cache clients %v", failed)
+	}
+
+	return nil
+}
+
+func (p *pool) client(name string) (*client, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+
+	c, ok := p.clients[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownClient, "cache client %s", name)
+	}
+
+	return c, nil
+}
+
+func (p *pool) names() []string {
+	names := make([]string, 0, len(p.clients))
+	for name := range p.clients {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func (p *pool) healthCheck() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.option.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			for _, name := range p.names() {
+				p.check(p.clients[name])
+			}
+		}
+	}
+}
+
+// check pings a connected client and replaces it when the ping fails
+func (p *pool) check(c *client) {
+	cn := c.connected()
+	if cn == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.option.HealthCheckTimeout)
+	defer cancel()
+
+	err := cn.cache.Ping(&ctx)
+	if err == nil {
+		return
+	}
+
+	p.option.Log.Error(errors.Wrapf(err, "cache client %s failed its health check, reconnecting", c.name))
+	if err := c.replace(cn); err != nil {
+		p.option.Log.Error(err)
+	}
+}
+
+// connect returns the connected client, the factory is called outside of the lock when it is not connected
+// yet and the callers arriving meanwhile wait for that call. A borrowed conn must be handed back to release.
+func (c *client) connect(ctx *context.Context, borrow bool) (*conn, error) {
+	c.mu.Lock()
+	for c.current == nil {
+		if c.closed {
+			c.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+
+		d := c.dialing
+		if d == nil {
+			d = &dial{done: make(chan struct{})}
+			c.dialing = d
+			c.mu.Unlock()
+			c.dial(d)
+		} else {
+			c.mu.Unlock()
+		}
+
+		select {
+		case <-d.done:
+		case <-doneChannel(ctx):
+			return nil, errors.Wrapf((*ctx).Err(), "failed to connect cache client %s", c.name)
+		}
+
+		if d.err != nil {
+			return nil, d.err
+		}
+		c.mu.Lock()
+	}
+
+	cn := c.current
+	if borrow {
+		cn.borrows++
+	}
+	c.mu.Unlock()
+
+	return cn, nil
+}
+
+func (c *client) dial(d *dial) {
+	cc, err := c.factory()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer close(d.done)
+
+	c.dialing = nil
+	switch {
+	case err != nil:
+		d.err = errors.Wrapf(err, "failed to connect cache client %s", c.name)
+	case c.closed:
+		_ = cc.Close()
+		d.err = ErrPoolClosed
+	default:
+		c.current = &conn{cache: cc}
+	}
+}
+
+func (c *client) connected() *conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.current
+}
+
+// release hands back a borrowed conn, closing it when it was replaced meanwhile
+func (c *client) release(cn *conn) error {
+	c.mu.Lock()
+	cn.borrows--
+	idle := cn.retired && cn.borrows == 0
+	c.mu.Unlock()
+
+	if idle {
+		return c.retire(cn)
+	}
+
+	return nil
+}
+
+// replace connects a new client in place of cn unless it was already replaced, cn is closed once it is not
+// borrowed anymore. The next connect calls the factory again when the new client can not be connected.
+func (c *client) replace(cn *conn) error {
+	cc, err := c.factory()
+
+	c.mu.Lock()
+	if c.current != cn || c.closed {
+		c.mu.Unlock()
+		if err == nil {
+			_ = cc.Close()
+		}
+		return nil
+	}
+
+	c.current = nil
+	if err == nil {
+		c.current = &conn{cache: cc}
+	}
+	cn.retired = true
+	idle := cn.borrows == 0
+	c.mu.Unlock()
+
+	// cn is closed even when the new client can not be connected, nobody would close it later
+	var retireErr error
+	if idle {
+		retireErr = c.retire(cn)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect cache client %s", c.name)
+	}
+
+	return retireErr
+}
+
+func (c *client) retire(cn *conn) error {
+	if err := cn.cache.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close replaced cache client %s", c.name)
+	}
+
+	return nil
+}
+
+func (c *client) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.current == nil {
+		return nil
+	}
+
+	err := c.current.cache.Close()
+	c.current = nil
+	if err != nil {
+		return errors.Wrapf(err, "failed to close cache client %s", c.name)
+	}
+
+	return nil
+}
+
+func doneChannel(ctx *context.Context) <-chan struct{} {
+	if ctx == nil || *ctx == nil {
+		return nil
+	}
+
+	return (*ctx).Done()
+}
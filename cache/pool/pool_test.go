@@ -0,0 +1,233 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/cache"
+	"github.com/Dert12318/Utilities/cache/memory"
+)
+
+func counting(calls *int32) Factory {
+	return func() (cache.Cache, error) {
+		atomic.AddInt32(calls, 1)
+		return memory.New()
+	}
+}
+
+func TestGet(t *testing.T) {
+	var a, b int32
+	p, err := New(map[string]Factory{"a": counting(&a), "b": counting(&b)}, &Option{Default: "a"})
+	assert.NoError(t, err)
+	defer p.Close()
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&a))
+
+	ctx := context.Background()
+	first, err := p.Get(&ctx, "a")
+	assert.NoError(t, err)
+	second, _ := p.Get(&ctx, "a")
+	assert.Equal(t, first, second)
+	assert.Equal(t, first, p.Client())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&a))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&b))
+
+	_, err = p.Get(&ctx, "c")
+	assert.Equal(t, ErrUnknownClient, errors.Cause(err))
+}
+
+func TestBorrow(t *testing.T) {
+	var calls int32
+	p, _ := New(map[string]Factory{"a": counting(&calls)}, &Option{MaxActive: 1})
+	defer p.Close()
+
+	ctx := context.Background()
+	borrowed := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = p.Borrow(&ctx, "a", func(client cache.Cache) error {
+			close(borrowed)
+			<-release
+			return nil
+		})
+	}()
+	<-borrowed
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	err := p.Borrow(&timeout, "a", func(client cache.Cache) error {
+		return nil
+	})
+	assert.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+
+	close(release)
+	used := false
+	p.Use(func(client cache.Cache) {
+		used = true
+	})
+	assert.True(t, used)
+}
+
+func TestHealthCheckReconnects(t *testing.T) {
+	var calls int32
+	p, _ := New(map[string]Factory{"a": counting(&calls)}, &Option{HealthCheckInterval: 10 * time.Millisecond})
+	defer p.Close()
+
+	c := p.Client()
+	assert.NoError(t, c.Close())
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&calls) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	ctx := context.Background()
+	assert.NoError(t, p.Client().Ping(&ctx))
+}
+
+// flaky fails its Ping once down is set and remembers whether it was closed
+type flaky struct {
+	cache.Cache
+	down   int32
+	closed int32
+}
+
+func (f *flaky) Ping(ctx *context.Context) error {
+	if atomic.LoadInt32(&f.down) == 1 {
+		return errors.New("connection refused")
+	}
+	return f.Cache.Ping(ctx)
+}
+
+func (f *flaky) Close() error {
+	atomic.StoreInt32(&f.closed, 1)
+	return f.Cache.Close()
+}
+
+func TestHealthCheckKeepsBorrowedClientOpen(t *testing.T) {
+	var clients []*flaky
+	var mu sync.Mutex
+	p, _ := New(map[string]Factory{"a": func() (cache.Cache, error) {
+		mc, err := memory.New()
+		mu.Lock()
+		defer mu.Unlock()
+		clients = append(clients, &flaky{Cache: mc})
+		return clients[len(clients)-1], err
+	}}, &Option{HealthCheckInterval: 10 * time.Millisecond})
+	defer p.Close()
+
+	ctx := context.Background()
+	borrowed, release, done := make(chan cache.Cache), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = p.Borrow(&ctx, "a", func(client cache.Cache) error {
+			borrowed <- client
+			<-release
+			return nil
+		})
+	}()
+
+	old := (<-borrowed).(*flaky)
+	atomic.StoreInt32(&old.down, 1)
+
+	// the new client is swapped in while the borrower still uses the old one
+	assert.Eventually(t, func() bool {
+		c, err := p.Get(&ctx, "a")
+		return err == nil && c != old
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&old.closed))
+
+	close(release)
+	<-done
+	assert.Equal(t, int32(1), atomic.LoadInt32(&old.closed))
+}
+
+func TestHealthCheckClosesClientWhenReconnectFails(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		clients []*flaky
+	)
+	p, _ := New(map[string]Factory{"a": func() (cache.Cache, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(clients) > 0 {
+			return nil, errors.New("connection refused")
+		}
+		mc, err := memory.New()
+		clients = append(clients, &flaky{Cache: mc})
+		return clients[0], err
+	}}, &Option{HealthCheckInterval: 10 * time.Millisecond})
+	defer p.Close()
+
+	ctx := context.Background()
+	c, err := p.Get(&ctx, "a")
+	assert.NoError(t, err)
+
+	old := c.(*flaky)
+	atomic.StoreInt32(&old.down, 1)
+
+	// nobody borrows the old client, it is closed although no new client replaces it
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&old.closed) == 1
+	}, time.Second, 10*time.Millisecond)
+	_, err = p.Get(&ctx, "a")
+	assert.Error(t, err)
+}
+
+func TestConnectOutsideLock(t *testing.T) {
+	dialing, release := make(chan struct{}), make(chan struct{})
+	p, _ := New(map[string]Factory{"a": func() (cache.Cache, error) {
+		close(dialing)
+		<-release
+		return memory.New()
+	}}, nil)
+	defer p.Close()
+
+	ctx := context.Background()
+	first := make(chan cache.Cache)
+	go func() {
+		c, _ := p.Get(&ctx, "a")
+		first <- c
+	}()
+	<-dialing
+
+	// a slow connect does not block the callers that give up
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err := p.Get(&timeout, "a")
+	assert.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+
+	close(release)
+	c := <-first
+	assert.NotNil(t, c)
+	second, err := p.Get(&ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, c, second)
+}
+
+func TestDefaultMaxActive(t *testing.T) {
+	p, _ := New(map[string]Factory{"a": counting(new(int32))}, nil)
+	defer p.Close()
+	assert.Equal(t, DefaultMaxActive, cap(p.(*pool).clients["a"].slots))
+
+	p, _ = New(map[string]Factory{"a": counting(new(int32))}, &Option{MaxActive: -1})
+	defer p.Close()
+	assert.Nil(t, p.(*pool).clients["a"].slots)
+}
+
+func TestClose(t *testing.T) {
+	var calls int32
+	p, _ := New(map[string]Factory{"a": counting(&calls)}, nil)
+	assert.NotNil(t, p.Client())
+	assert.NoError(t, p.Close())
+	assert.NoError(t, p.Close())
+
+	ctx := context.Background()
+	_, err := p.Get(&ctx, "a")
+	assert.Equal(t, ErrPoolClosed, err)
+}
@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+	"github.com/Dert12318/Utilities/cache/memory"
+	helperTime "github.com/Dert12318/Utilities/helper/time"
+)
+
+const (
+	// maxAttempts bounds the optimistic transaction retries of a single Allow under contention
+	maxAttempts = 10
+)
+
+type (
+	cacheLimiter struct {
+		cache  cache.Cache
+		option Option
+		// owned is set when the limiter created the cache and has to close it
+		owned bool
+	}
+
+	// state is the per key record of every algorithm, only the fields of the configured one are used
+	state struct {
+		Start  int64   `json:"s,omitempty"`
+		Count  int64   `json:"c,omitempty"`
+		Log    []int64 `json:"l,omitempty"`
+		Tokens float64 `json:"t,omitempty"`
+		Last   int64   `json:"u,omitempty"`
+	}
+)
+
+// New returns a Limiter keeping its state in c. Every Allow is an optimistic transaction (see cache.Cache.Watch)
+// so it is correct with any backend, the redis-universal RateLimiter is cheaper when Redis is available.
+func New(c cache.Cache, option *Option) (Limiter, error) {
+	if option == nil {
+		return nil, errors.New("rate limit option is required")
+	}
+
+	o := *option
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cacheLimiter{cache: c, option: o}, nil
+}
+
+// NewMemory returns a Limiter local to the process, useful as the fallback of a distributed one, see WithFallback.
+func NewMemory(option *Option) (Limiter, error) {
+	c, err := memory.New()
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := New(c, option)
+	if err != nil {
+		return nil, err
+	}
+
+	l.(*cacheLimiter).owned = true
+	return l, nil
+}
+
+func (l *cacheLimiter) Allow(ctx *context.Context, key string) (*Result, error) {
+	k := l.option.Key(key)
+
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var res *Result
+		err = l.cache.Watch(ctx, func(tx cache.Tx) error {
+			var s state
+			if err := tx.Get(k, &s); err != nil && errors.Cause(err) != redis.Nil {
+				return err
+			}
+
+			r, ttl, changed := l.apply(&s, helperTime.Now())
+			res = r
+			if !changed {
+				return nil
+			}
+
+			return tx.Pipelined(func(pipe cache.Pipe) error {
+				pipe.SetWithExpiration(k, s, ttl)
+				return nil
+			})
+		}, k)
+
+		if err == nil {
+			return res, nil
+		}
+
+		if errors.Cause(err) != cache.ErrTxFailed {
+			break
+		}
+	}
+
+	return nil, errors.Wrapf(err, "failed to rate limit key %s", key)
+}
+
+func (l *cacheLimiter) Close() error {
+	if l.owned {
+		return l.cache.Close()
+	}
+
+	return nil
+}
+
+// apply runs the configured algorithm over s at now, it returns whether s changed and how long it must be kept.
+func (l *cacheLimiter) apply(s *state, now time.Time) (*Result, time.Duration, bool) {
+	switch l.option.Algorithm {
+	case SlidingWindowLog:
+		return slidingWindowLog(l.option, s, now)
+	case TokenBucket:
+		return tokenBucket(l.option, s, now)
+	default:
+		return fixedWindow(l.option, s, now)
+	}
+}
+
+func fixedWindow(o Option, s *state, now time.Time) (*Result, time.Duration, bool) {
+	start := now.Truncate(o.Window)
+	if s.Start != start.UnixNano() {
+		s.Start, s.Count = start.UnixNano(), 0
+	}
+
+	reset := start.Add(o.Window).Sub(now)
+	res := &Result{Limit: o.Limit, ResetAfter: reset}
+	if s.Count >= o.Limit {
+		res.RetryAfter = reset
+		return res, reset, false
+	}
+
+	s.Count++
+	res.Allowed = true
+	res.Remaining = o.Limit - s.Count
+	return res, reset, true
+}
+
+func slidingWindowLog(o Option, s *state, now time.Time) (*Result, time.Duration, bool) {
+	from := now.Add(-o.Window).UnixNano()
+	log := s.Log[:0]
+	for _, at := range s.Log {
+		if at > from {
+			log = append(log, at)
+		}
+	}
+	s.Log = log
+
+	res := &Result{Limit: o.Limit}
+	if int64(len(s.Log)) >= o.Limit {
+		res.RetryAfter = time.Duration(s.Log[0] - from)
+		res.ResetAfter = time.Duration(s.Log[len(s.Log)-1] - from)
+		return res, res.ResetAfter, false
+	}
+
+	s.Log = append(s.Log, now.UnixNano())
+	res.Allowed = true
+	res.Remaining = o.Limit - int64(len(s.Log))
+	res.ResetAfter = o.Window
+	return res, o.Window, true
+}
+
+func tokenBucket(o Option, s *state, now time.Time) (*Result, time.Duration, bool) {
+	// rate is in tokens per nanosecond
+	rate := float64(o.Limit) / float64(o.Window)
+	if s.Last == 0 {
+		s.Tokens = float64(o.Burst)
+	} else {
+		s.Tokens = math.Min(float64(o.Burst), s.Tokens+float64(now.UnixNano()-s.Last)*rate)
+	}
+	s.Last = now.UnixNano()
+
+	res := &Result{Limit: o.Burst}
+	if s.Tokens < 1 {
+		res.RetryAfter = time.Duration(math.Ceil((1 - s.Tokens) / rate))
+	} else {
+		s.Tokens--
+		res.Allowed = true
+	}
+
+	res.Remaining = int64(s.Tokens)
+	res.ResetAfter = time.Duration(math.Ceil((float64(o.Burst) - s.Tokens) / rate))
+
+	// a full bucket is the same as a missing one, keep it a bit longer to avoid a zero expiration
+	return res, res.ResetAfter + time.Second, res.Allowed
+}
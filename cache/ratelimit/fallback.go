@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/logs"
+	"github.com/Dert12318/Utilities/logs/logrus"
+)
+
+type (
+	fallbackLimiter struct {
+		primary  Limiter
+		fallback Limiter
+		log      logs.Logger
+	}
+)
+
+// WithFallback uses fallback whenever primary fails, typically a NewMemory limiter in front of a Redis one
+// so requests are still limited per instance while Redis is unavailable.
+func WithFallback(primary, fallback Limiter, log logs.Logger) Limiter {
+	if log == nil {
+		log = logrus.DefaultLog()
+	}
+
+	return &fallbackLimiter{primary: primary, fallback: fallback, log: log}
+}
+
+func (l *fallbackLimiter) Allow(ctx *context.Context, key string) (*Result, error) {
+	res, err := l.primary.Allow(ctx, key)
+	if err == nil {
+		return res, nil
+	}
+
+	l.log.Error(errors.Wrapf(err, "failed to rate limit key %s, using fallback", key))
+	return l.fallback.Allow(ctx, key)
+}
+
+func (l *fallbackLimiter) Close() error {
+	if err := l.primary.Close(); err != nil {
+		return err
+	}
+
+	return l.fallback.Close()
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/apm"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs"
+	"github.com/Dert12318/Utilities/logs/logrus"
+	"github.com/Dert12318/Utilities/response"
+)
+
+const (
+	EventRateLimit = "http_ratelimit"
+
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+type (
+	// KeyFunc returns the key the request is limited by
+	KeyFunc func(ec echo.Context) string
+
+	MiddlewareOption struct {
+		// KeyFunc defaults to DefaultKeyFunc
+		KeyFunc KeyFunc
+		Skipper middleware.Skipper
+		Log     logs.Logger
+		// Apm traces the limiter of the requests whose context does not carry a transaction yet, optional
+		Apm apm.APM
+	}
+)
+
+// DefaultKeyFunc limits by the token of the MandatoryRequest, or by the client IP for anonymous requests.
+// The token is hashed so it is never stored in the cache.
+func DefaultKeyFunc(ec echo.Context) string {
+	c := tntContext.NewWithEcho(ec)
+	c.SetMandatory(tntContext.NewHttpSource())
+
+	if token := c.MandatoryRequest().Token(); token != "" {
+		sum := sha256.Sum256([]byte(token))
+		return "token:" + hex.EncodeToString(sum[:])
+	}
+
+	return "ip:" + ec.RealIP()
+}
+
+// Middleware rejects requests over the limit of limiter with 429 Too Many Requests using the response
+// error envelope. Every response carries the RateLimit-* headers, rejected ones Retry-After as well.
+// Requests are let through when limiter fails so an unavailable cache does not take the API down.
+func Middleware(limiter Limiter, option *MiddlewareOption) echo.MiddlewareFunc {
+	o := MiddlewareOption{
+		KeyFunc: DefaultKeyFunc,
+		Skipper: middleware.DefaultSkipper,
+		Log:     logrus.DefaultLog(),
+	}
+
+	if option != nil {
+		if option.KeyFunc != nil {
+			o.KeyFunc = option.KeyFunc
+		}
+		if option.Skipper != nil {
+			o.Skipper = option.Skipper
+		}
+		if option.Log != nil {
+			o.Log = option.Log
+		}
+		o.Apm = option.Apm
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			if o.Skipper(ec) {
+				return next(ec)
+			}
+
+			c, end := o.context(ec)
+			ctx := c.Context()
+			key := o.KeyFunc(ec)
+			res, err := limiter.Allow(&ctx, key)
+			end()
+			if err != nil {
+				o.Log.Error(errors.Wrapf(err, "failed to rate limit request %s", ec.Request().RequestURI))
+				return next(ec)
+			}
+
+			h := ec.Response().Header()
+			h.Set(HeaderRateLimitLimit, strconv.FormatInt(res.Limit, 10))
+			h.Set(HeaderRateLimitRemaining, strconv.FormatInt(res.Remaining, 10))
+			h.Set(HeaderRateLimitReset, seconds(res.ResetAfter))
+
+			if res.Allowed {
+				return next(ec)
+			}
+
+			h.Set(HeaderRetryAfter, seconds(res.RetryAfter))
+			httpErr := response.ErrorWrap(response.ErrTooManyRequests, errors.Errorf("rate limit exceeded, retry after %s", res.RetryAfter))
+			return ec.JSON(http.StatusTooManyRequests, httpErr.ErrorResponse)
+		}
+	}
+}
+
+// context returns the Context of the request, or builds one from ec whose transaction end finishes
+func (o MiddlewareOption) context(ec echo.Context) (c *tntContext.Context, end func()) {
+	if existing, ok := tntContext.FromContext(ec.Request().Context()); ok {
+		return existing, func() {}
+	}
+
+	c = tntContext.NewWithEchoAndContext(ec, ec.Request().Context())
+	c.SetMandatory(tntContext.NewHttpSource())
+	if o.Apm == nil {
+		return c, func() {}
+	}
+
+	c.Transaction = apm.StartTransactionFromHeaders(o.Apm, fmt.Sprintf("%s:%s", EventRateLimit, ec.Path()), ec.Request().Header)
+	return c, c.Transaction.End
+}
+
+// seconds formats d as the whole number of seconds the headers expect, rounded up
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FixedWindow counts the requests of the current window, it is the cheapest but allows bursts
+	// of up to twice the limit around window boundaries
+	FixedWindow Algorithm = iota
+	// SlidingWindowLog keeps the timestamp of every request of the last window, it is exact but
+	// stores up to Limit entries per key
+	SlidingWindowLog
+	// TokenBucket refills Limit tokens per Window into a bucket of Burst tokens
+	TokenBucket
+)
+
+const (
+	DefaultPrefix = "ratelimit"
+)
+
+type (
+	Algorithm int
+
+	Option struct {
+		Algorithm Algorithm
+		// Limit is the number of requests allowed per Window
+		Limit  int64
+		Window time.Duration
+		// Burst is the token bucket capacity, defaults to Limit
+		Burst int64
+		// Prefix namespaces the keys stored in the cache, defaults to DefaultPrefix
+		Prefix string
+	}
+
+	Result struct {
+		Allowed bool
+		Limit   int64
+		// Remaining is the number of requests still allowed right now
+		Remaining int64
+		// RetryAfter is how long a rejected request has to wait before it can be allowed, zero when allowed
+		RetryAfter time.Duration
+		// ResetAfter is how long until the limit is fully available again
+		ResetAfter time.Duration
+	}
+
+	Limiter interface {
+		// Allow consumes one request of key and reports whether it is allowed
+		Allow(ctx *context.Context, key string) (*Result, error)
+		Close() error
+	}
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case FixedWindow:
+		return "fixed_window"
+	case SlidingWindowLog:
+		return "sliding_window_log"
+	case TokenBucket:
+		return "token_bucket"
+	default:
+		return fmt.Sprintf("algorithm(%d)", int(a))
+	}
+}
+
+// Validate fills the defaults of option and checks it can be used by a Limiter.
+func (o *Option) Validate() error {
+	if o.Limit <= 0 {
+		return errors.New("rate limit must be positive")
+	}
+
+	if o.Window <= 0 {
+		return errors.New("rate limit window must be positive")
+	}
+
+	if o.Algorithm < FixedWindow || o.Algorithm > TokenBucket {
+		return errors.Errorf("unknown rate limit algorithm %s", o.Algorithm)
+	}
+
+	if o.Burst <= 0 {
+		o.Burst = o.Limit
+	}
+
+	if o.Prefix == "" {
+		o.Prefix = DefaultPrefix
+	}
+
+	return nil
+}
+
+// Key returns the cache key the state of key is stored under.
+func (o *Option) Key(key string) string {
+	return fmt.Sprintf("%s:%s:{%s}", o.Prefix, o.Algorithm, key)
+}
@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/apm/disabled"
+	"github.com/Dert12318/Utilities/common/constant/header"
+	tntContext "github.com/Dert12318/Utilities/context"
+	helperTime "github.com/Dert12318/Utilities/helper/time"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx *context.Context, key string) (*Result, error) {
+	return nil, errors.New("redis is down")
+}
+
+func (failingLimiter) Close() error {
+	return nil
+}
+
+func allow(t *testing.T, l Limiter, key string) *Result {
+	ctx := context.Background()
+	res, err := l.Allow(&ctx, key)
+	assert.NoError(t, err)
+	return res
+}
+
+func TestFixedWindow(t *testing.T) {
+	now := time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC)
+	helperTime.Mock(now)
+	defer helperTime.ResetMock()
+
+	l, err := NewMemory(&Option{Algorithm: FixedWindow, Limit: 2, Window: time.Minute})
+	assert.NoError(t, err)
+	defer l.Close()
+
+	assert.Equal(t, &Result{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: time.Minute}, allow(t, l, "a"))
+	assert.True(t, allow(t, l, "a").Allowed)
+
+	helperTime.Mock(now.Add(40 * time.Second))
+	res := allow(t, l, "a")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 20*time.Second, res.RetryAfter)
+	assert.True(t, allow(t, l, "b").Allowed)
+
+	helperTime.Mock(now.Add(time.Minute))
+	assert.True(t, allow(t, l, "a").Allowed)
+}
+
+func TestSlidingWindowLog(t *testing.T) {
+	now := time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC)
+	helperTime.Mock(now)
+	defer helperTime.ResetMock()
+
+	l, _ := NewMemory(&Option{Algorithm: SlidingWindowLog, Limit: 2, Window: time.Minute})
+	defer l.Close()
+
+	assert.True(t, allow(t, l, "a").Allowed)
+	helperTime.Mock(now.Add(40 * time.Second))
+	assert.True(t, allow(t, l, "a").Allowed)
+
+	// a fixed window would have been reset at the minute
+	helperTime.Mock(now.Add(50 * time.Second))
+	res := allow(t, l, "a")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 10*time.Second, res.RetryAfter)
+
+	helperTime.Mock(now.Add(61 * time.Second))
+	res = allow(t, l, "a")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int64(0), res.Remaining)
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC)
+	helperTime.Mock(now)
+	defer helperTime.ResetMock()
+
+	l, _ := NewMemory(&Option{Algorithm: TokenBucket, Limit: 1, Window: time.Second, Burst: 3})
+	defer l.Close()
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, allow(t, l, "a").Allowed)
+	}
+
+	res := allow(t, l, "a")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.ResetAfter)
+
+	helperTime.Mock(now.Add(1500 * time.Millisecond))
+	res = allow(t, l, "a")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int64(0), res.Remaining)
+}
+
+func TestValidate(t *testing.T) {
+	_, err := NewMemory(&Option{Limit: 0, Window: time.Second})
+	assert.Error(t, err)
+
+	_, err = NewMemory(&Option{Algorithm: Algorithm(9), Limit: 1, Window: time.Second})
+	assert.Error(t, err)
+}
+
+func TestWithFallback(t *testing.T) {
+	fallback, _ := NewMemory(&Option{Limit: 1, Window: time.Minute})
+	l := WithFallback(failingLimiter{}, fallback, nil)
+
+	assert.True(t, allow(t, l, "a").Allowed)
+	assert.False(t, allow(t, l, "a").Allowed)
+}
+
+// contextLimiter keeps the context the middleware hands to Allow
+type contextLimiter struct {
+	Limiter
+	ctx *tntContext.Context
+}
+
+func (l *contextLimiter) Allow(ctx *context.Context, key string) (*Result, error) {
+	l.ctx, _ = tntContext.FromContext(*ctx)
+	return l.Limiter.Allow(ctx, key)
+}
+
+func TestMiddlewareContext(t *testing.T) {
+	memory, _ := NewMemory(&Option{Limit: 1, Window: time.Minute})
+	l := &contextLimiter{Limiter: memory}
+	disabledAPM, _ := disabled.New()
+
+	e := echo.New()
+	e.Use(Middleware(l, &MiddlewareOption{Apm: disabledAPM}))
+	e.GET("/", func(ec echo.Context) error {
+		return ec.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(header.HttpAuthorization, "Bearer token")
+	req.Header.Set(header.HttpRequestID, "request")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	// the limiter gets the Context of the request so its commands are traced
+	if assert.NotNil(t, l.ctx) {
+		assert.NotNil(t, l.ctx.Transaction)
+		assert.Equal(t, "request", l.ctx.MandatoryRequest().RequestID())
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	l, _ := NewMemory(&Option{Limit: 1, Window: time.Minute})
+	e := echo.New()
+	e.Use(Middleware(l, nil))
+	e.GET("/", func(ec echo.Context) error {
+		return ec.NoContent(http.StatusOK)
+	})
+
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request("a")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get(HeaderRateLimitLimit))
+	assert.Equal(t, "0", rec.Header().Get(HeaderRateLimitRemaining))
+
+	rec = request("a")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get(HeaderRetryAfter))
+	assert.Contains(t, rec.Body.String(), `"code":429`)
+
+	assert.Equal(t, http.StatusOK, request("b").Code)
+}
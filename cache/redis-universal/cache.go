@@ -2,12 +2,15 @@ package redis_universal
 
 import (
 	"context"
-	"encoding"
-	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/Dert12318/Utilities/cache"
+	"github.com/Dert12318/Utilities/cache/metrics"
+	"github.com/Dert12318/Utilities/encoding"
+	"github.com/Dert12318/Utilities/encoding/jsontier"
+	"github.com/Dert12318/Utilities/logs"
 	"github.com/go-redis/redis"
 	"github.com/pkg/errors"
 )
@@ -25,21 +28,46 @@ type (
 		ReadTimeout  time.Duration
 		WriteTimeout time.Duration
 		MaxConnAge   time.Duration
+		// Redlock makes NewLocker treat every Address as an independent master instead of a cluster
+		Redlock bool
+		// Encoding is used for values that are not strings, numbers, bools, []byte or encoding.BinaryMarshaler,
+		// defaults to jsontier
+		Encoding encoding.Encoding
+		// Metrics receives the latency and result of every command and the pool stats, see metrics.Registry
+		Metrics metrics.Recorder
+		// SlowThreshold logs the commands taking at least that long as a warning, zero disables it
+		SlowThreshold time.Duration
+		// Log is used for the slow commands, defaults to logrus
+		Log logs.Logger
 	}
 
 	redisUniversalClient struct {
 		r        redis.UniversalClient
 		mu       sync.Mutex
 		channels map[string]cache.PubSub
+		encoding encoding.Encoding
 	}
 )
 
 func New(option *Option) (cache.Cache, error) {
-	var client redis.UniversalClient
+	client := newUniversalClient(option, option.Address)
 
-	client = redis.NewUniversalClient(&redis.UniversalOptions{
+	if _, err := client.Ping().Result(); err != nil {
+		return nil, errors.Wrap(err, "Failed to connect to redis!")
+	}
+
+	e := option.Encoding
+	if e == nil {
+		e = jsontier.NewEncoding()
+	}
+
+	return &redisUniversalClient{r: client, encoding: e, channels: make(map[string]cache.PubSub)}, nil
+}
+
+func newUniversalClient(option *Option, address []string) redis.UniversalClient {
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
 		DB:           option.DB,
-		Addrs:        option.Address,
+		Addrs:        address,
 		Password:     option.Password,
 		PoolSize:     option.PoolSize,
 		PoolTimeout:  option.PoolTimeout,
@@ -51,15 +79,14 @@ func New(option *Option) (cache.Cache, error) {
 		ReadOnly:     option.ReadOnly,
 	})
 
-	if _, err := client.Ping().Result(); err != nil {
-		return nil, errors.Wrap(err, "Failed to connect to redis!")
-	}
-
-	return &redisUniversalClient{r: client}, nil
+	instrument(client, option)
+	return client
 }
 
 func (c *redisUniversalClient) Ping(ctx *context.Context) error {
-	if _, err := c.r.Ping().Result(); err != nil {
+	if err := c.process(ctx, "PING", "", func() error {
+		return c.r.Ping().Err()
+	}); err != nil {
 		return errors.WithStack(err)
 	}
 	return nil
@@ -70,7 +97,14 @@ func (c *redisUniversalClient) SetWithExpiration(ctx *context.Context, key strin
 		return err
 	}
 
-	if _, err := c.r.Set(key, value, duration).Result(); err != nil {
+	val, err := cache.Marshal(c.codec(ctx), value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set cache with key %s!", key)
+	}
+
+	if err := c.process(ctx, "SET", key, func() error {
+		return c.r.Set(key, val, duration).Err()
+	}); err != nil {
 		return errors.Wrapf(err, "failed to set cache with key %s!", key)
 	}
 	return nil
@@ -81,15 +115,15 @@ func (c *redisUniversalClient) Set(ctx *context.Context, key string, value inter
 }
 
 func (c *redisUniversalClient) Get(ctx *context.Context, key string, data interface{}) error {
-	if _, ok := data.(encoding.BinaryUnmarshaler); !ok {
-		return errors.New(fmt.Sprintf("failed to get cache with key %s!: redis: can't unmarshal (implement encoding.BinaryUnmarshaler)", key))
-	}
-
 	if err := check(c); err != nil {
 		return err
 	}
 
-	val, err := c.r.Get(key).Result()
+	var val string
+	err := c.process(ctx, "GET", key, func() (err error) {
+		val, err = c.r.Get(key).Result()
+		return err
+	})
 
 	if err == redis.Nil {
 		return errors.Wrapf(err, "key %s does not exits", key)
@@ -99,19 +133,31 @@ func (c *redisUniversalClient) Get(ctx *context.Context, key string, data interf
 		return errors.Wrapf(err, "failed to get key %s!", key)
 	}
 
-	if err := data.(encoding.BinaryUnmarshaler).UnmarshalBinary([]byte(val)); err != nil {
-		return err
+	if err := cache.Unmarshal(c.codec(ctx), []byte(val), data); err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
 	}
 
 	return nil
 }
 
 func (c *redisUniversalClient) Keys(ctx *context.Context, pattern string) ([]string, error) {
-	if err := check(c); err != nil {
+	// SCAN returns a key more than once when it is rehashed or migrated during the iteration
+	val := make([]string, 0)
+	seen := make(map[string]struct{})
+	it := c.Scan(ctx, cache.ScanOption{Match: pattern})
+	for it.Next() {
+		if _, ok := seen[it.Key()]; ok {
+			continue
+		}
+		seen[it.Key()] = struct{}{}
+		val = append(val, it.Key())
+	}
+
+	if err := it.Err(); err != nil {
 		return []string{}, err
 	}
 
-	return c.r.Keys(pattern).Result()
+	return val, nil
 }
 
 func (c *redisUniversalClient) HMSetWithExpiration(ctx *context.Context, key string, value map[string]interface{}, ttl time.Duration) error {
@@ -119,11 +165,20 @@ func (c *redisUniversalClient) HMSetWithExpiration(ctx *context.Context, key str
 		return err
 	}
 
-	if _, err := c.r.HMSet(key, value).Result(); err != nil {
+	fields, err := c.marshalFields(ctx, value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
+	}
+
+	if err := c.process(ctx, "HMSET", key, func() error {
+		return c.r.HMSet(key, fields).Err()
+	}); err != nil {
 		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
 	}
 
-	if _, err := c.r.Expire(key, ttl).Result(); err != nil {
+	if err := c.process(ctx, "EXPIRE", key, func() error {
+		return c.r.Expire(key, ttl).Err()
+	}); err != nil {
 		c.r.Del(key)
 		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
 	}
@@ -135,7 +190,14 @@ func (c *redisUniversalClient) HMSet(ctx *context.Context, key string, value map
 		return err
 	}
 
-	if _, err := c.r.HMSet(key, value).Result(); err != nil {
+	fields, err := c.marshalFields(ctx, value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
+	}
+
+	if err := c.process(ctx, "HMSET", key, func() error {
+		return c.r.HMSet(key, fields).Err()
+	}); err != nil {
 		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
 	}
 	return nil
@@ -146,10 +208,19 @@ func (c *redisUniversalClient) HSetWithExpiration(ctx *context.Context, key, fie
 		return err
 	}
 
-	if _, err := c.r.HSet(key, field, value).Result(); err != nil {
+	val, err := cache.Marshal(c.codec(ctx), value)
+	if err != nil {
 		return errors.Wrapf(err, "failed to HSet cache with key %s!", key)
 	}
-	if _, err := c.r.Expire(key, ttl).Result(); err != nil {
+
+	if err := c.process(ctx, "HSET", key, func() error {
+		return c.r.HSet(key, field, val).Err()
+	}); err != nil {
+		return errors.Wrapf(err, "failed to HSet cache with key %s!", key)
+	}
+	if err := c.process(ctx, "EXPIRE", key, func() error {
+		return c.r.Expire(key, ttl).Err()
+	}); err != nil {
 		c.r.Del(key)
 		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
 	}
@@ -161,7 +232,14 @@ func (c *redisUniversalClient) HSet(ctx *context.Context, key, field string, val
 		return err
 	}
 
-	if _, err := c.r.HSet(key, field, value).Result(); err != nil {
+	val, err := cache.Marshal(c.codec(ctx), value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to HSet cache with key %s!", key)
+	}
+
+	if err := c.process(ctx, "HSET", key, func() error {
+		return c.r.HSet(key, field, val).Err()
+	}); err != nil {
 		return errors.Wrapf(err, "failed to HSet cache with key %s!", key)
 	}
 	return nil
@@ -172,7 +250,11 @@ func (c *redisUniversalClient) HMGet(ctx *context.Context, key string, fields ..
 		return nil, err
 	}
 
-	val, err := c.r.HMGet(key, fields...).Result()
+	var val []interface{}
+	err := c.process(ctx, "HMGET", key, func() (err error) {
+		val, err = c.r.HMGet(key, fields...).Result()
+		return err
+	})
 	if err == redis.Nil {
 		return nil, errors.Wrapf(err, "key %s does not exits", key)
 	}
@@ -189,7 +271,11 @@ func (c *redisUniversalClient) HGetAll(ctx *context.Context, key string) (map[st
 		return nil, err
 	}
 
-	val, err := c.r.HGetAll(key).Result()
+	var val map[string]string
+	err := c.process(ctx, "HGETALL", key, func() (err error) {
+		val, err = c.r.HGetAll(key).Result()
+		return err
+	})
 	if err == redis.Nil {
 		return nil, errors.Wrapf(err, "key %s does not exits", key)
 	}
@@ -202,15 +288,15 @@ func (c *redisUniversalClient) HGetAll(ctx *context.Context, key string) (map[st
 }
 
 func (c *redisUniversalClient) HGet(ctx *context.Context, key, field string, response interface{}) error {
-	if _, ok := response.(encoding.BinaryUnmarshaler); !ok {
-		return errors.New(fmt.Sprintf("failed to get cache with key %s!: redis: can't unmarshal (implement encoding.BinaryUnmarshaler)", key))
-	}
-
 	if err := check(c); err != nil {
 		return err
 	}
 
-	val, err := c.r.HGet(key, field).Result()
+	var val string
+	err := c.process(ctx, "HGET", key, func() (err error) {
+		val, err = c.r.HGet(key, field).Result()
+		return err
+	})
 	if err == redis.Nil {
 		return errors.Wrapf(err, "key %s does not exits", key)
 	}
@@ -219,8 +305,8 @@ func (c *redisUniversalClient) HGet(ctx *context.Context, key, field string, res
 		return errors.Wrapf(err, "failed to get key %s!", key)
 	}
 
-	if err := response.(encoding.BinaryUnmarshaler).UnmarshalBinary([]byte(val)); err != nil {
-		return err
+	if err := cache.Unmarshal(c.codec(ctx), []byte(val), response); err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
 	}
 
 	return nil
@@ -231,7 +317,11 @@ func (c *redisUniversalClient) MGet(ctx *context.Context, key []string) ([]inter
 		return nil, err
 	}
 
-	val, err := c.r.MGet(key...).Result()
+	var val []interface{}
+	err := c.process(ctx, "MGET", strings.Join(key, " "), func() (err error) {
+		val, err = c.r.MGet(key...).Result()
+		return err
+	})
 	if err == redis.Nil {
 		return nil, errors.Wrapf(err, "key %s does not exits", key)
 	}
@@ -248,7 +338,18 @@ func (c *redisUniversalClient) SAdd(ctx context.Context, key string, values ...i
 		return err
 	}
 
-	if err := c.r.SAdd(key, values...).Err(); err != nil {
+	members := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		member, err := cache.Marshal(c.codec(&ctx), value)
+		if err != nil {
+			return errors.Wrapf(err, "failed to set cache with key %s!", key)
+		}
+		members = append(members, member)
+	}
+
+	if err := c.process(&ctx, "SADD", key, func() error {
+		return c.r.SAdd(key, members...).Err()
+	}); err != nil {
 		return errors.Wrapf(err, "failed to set cache with key %s!", key)
 	}
 	return nil
@@ -259,7 +360,16 @@ func (c *redisUniversalClient) SIsMember(ctx context.Context, key string, member
 		return false, err
 	}
 
-	val, err := c.r.SIsMember(key, member).Result()
+	m, err := cache.Marshal(c.codec(&ctx), member)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	var val bool
+	err = c.process(&ctx, "SISMEMBER", key, func() (err error) {
+		val, err = c.r.SIsMember(key, m).Result()
+		return err
+	})
 	if err == redis.Nil {
 		return false, errors.Wrapf(err, "key %s does not exits", key)
 	}
@@ -276,7 +386,11 @@ func (c *redisUniversalClient) SMembers(ctx context.Context, key string) ([]stri
 		return nil, err
 	}
 
-	val, err := c.r.SMembers(key).Result()
+	var val []string
+	err := c.process(&ctx, "SMEMBERS", key, func() (err error) {
+		val, err = c.r.SMembers(key).Result()
+		return err
+	})
 	if err == redis.Nil {
 		return nil, errors.Wrapf(err, "key %s does not exits", key)
 	}
@@ -292,7 +406,9 @@ func (c *redisUniversalClient) Remove(ctx *context.Context, key string) error {
 		return err
 	}
 
-	if _, err := c.r.Del(key).Result(); err != nil {
+	if err := c.process(ctx, "DEL", key, func() error {
+		return c.r.Del(key).Err()
+	}); err != nil {
 		return errors.Wrapf(err, "failed to remove key %s!", key)
 	}
 
@@ -300,29 +416,8 @@ func (c *redisUniversalClient) Remove(ctx *context.Context, key string) error {
 }
 
 func (c *redisUniversalClient) RemoveByPattern(ctx *context.Context, pattern string, countPerLoop int64) error {
-	if err := check(c); err != nil {
-		return err
-	}
-
-	iteration := 1
-	for {
-		keys, _, err := c.r.Scan(0, pattern, countPerLoop).Result()
-		if err != nil {
-			return errors.Wrapf(err, "failed to scan redis pattern %s!", pattern)
-		}
-
-		if len(keys) == 0 {
-			break
-		}
-
-		if _, err := c.r.Del(keys...).Result(); err != nil {
-			return errors.Wrapf(err, "failed iteration-%d to remove key with pattern %s", iteration, pattern)
-		}
-
-		iteration++
-	}
-
-	return nil
+	_, err := c.DeleteByPattern(ctx, cache.ScanOption{Match: pattern, Count: countPerLoop})
+	return err
 }
 
 func (c *redisUniversalClient) FlushDatabase(ctx *context.Context) error {
@@ -330,7 +425,9 @@ func (c *redisUniversalClient) FlushDatabase(ctx *context.Context) error {
 		return err
 	}
 
-	if _, err := c.r.FlushDB().Result(); err != nil {
+	if err := c.process(ctx, "FLUSHDB", "", func() error {
+		return c.r.FlushDB().Err()
+	}); err != nil {
 		return errors.Wrap(err, "failed to flush db!")
 	}
 
@@ -342,7 +439,9 @@ func (c *redisUniversalClient) FlushAll(ctx *context.Context) error {
 		return err
 	}
 
-	if _, err := c.r.FlushAll().Result(); err != nil {
+	if err := c.process(ctx, "FLUSHALL", "", func() error {
+		return c.r.FlushAll().Err()
+	}); err != nil {
 		return errors.Wrap(err, "failed to flush db!")
 	}
 
@@ -357,6 +456,36 @@ func (c *redisUniversalClient) Close() error {
 	return nil
 }
 
+func (c *redisUniversalClient) codec(ctx *context.Context) encoding.Encoding {
+	return cache.EncodingFromContext(ctx, c.encoding)
+}
+
+func (c *redisUniversalClient) marshalFields(ctx *context.Context, value map[string]interface{}) (map[string]interface{}, error) {
+	fields := make(map[string]interface{}, len(value))
+	for field, v := range value {
+		val, err := cache.Marshal(c.codec(ctx), v)
+		if err != nil {
+			return nil, err
+		}
+		fields[field] = val
+	}
+
+	return fields, nil
+}
+
+func (c *redisUniversalClient) marshalAll(ctx *context.Context, values []interface{}) ([]interface{}, error) {
+	vals := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		val, err := cache.Marshal(c.codec(ctx), value)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+	}
+
+	return vals, nil
+}
+
 func check(c *redisUniversalClient) error {
 	if c.r == nil {
 		return errors.New("redis client is not connected")
@@ -370,7 +499,28 @@ func (c *redisUniversalClient) Client() cache.Cache {
 }
 
 func (c *redisUniversalClient) Pipeline() cache.Pipe {
-	return &pipe{instance: c.r.Pipeline()}
+	return newPipe(c.r.Pipeline(), c.encoding)
+}
+
+func (c *redisUniversalClient) TxPipeline() cache.Pipe {
+	return newPipe(c.r.TxPipeline(), c.encoding)
+}
+
+func (c *redisUniversalClient) Watch(ctx *context.Context, fn func(tx cache.Tx) error, keys ...string) error {
+	if err := check(c); err != nil {
+		return err
+	}
+
+	return c.process(ctx, "WATCH", strings.Join(keys, " "), func() error {
+		err := c.r.Watch(func(t *redis.Tx) error {
+			return fn(&tx{tx: t, encoding: c.codec(ctx)})
+		}, keys...)
+
+		if err == redis.TxFailedErr {
+			return errors.Wrapf(cache.ErrTxFailed, "failed to watch keys %v", keys)
+		}
+		return err
+	})
 }
 
 func (c *redisUniversalClient) Subscribe(channel string) (cache.PubSub, error) {
@@ -0,0 +1,105 @@
+package redis_universal
+
+import (
+	"context"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+func (c *redisUniversalClient) LPush(ctx *context.Context, key string, values ...interface{}) (int64, error) {
+	return c.push(ctx, "LPUSH", key, values, c.r.LPush)
+}
+
+func (c *redisUniversalClient) RPush(ctx *context.Context, key string, values ...interface{}) (int64, error) {
+	return c.push(ctx, "RPUSH", key, values, c.r.RPush)
+}
+
+func (c *redisUniversalClient) push(ctx *context.Context, operation, key string, values []interface{}, cmd func(key string, values ...interface{}) *redis.IntCmd) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	vals, err := c.marshalAll(ctx, values)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to push to list with key %s!", key)
+	}
+
+	var n int64
+	if err := c.process(ctx, operation, key, func() (err error) {
+		n, err = cmd(key, vals...).Result()
+		return err
+	}); err != nil {
+		return 0, errors.Wrapf(err, "failed to push to list with key %s!", key)
+	}
+
+	return n, nil
+}
+
+func (c *redisUniversalClient) LPop(ctx *context.Context, key string, object interface{}) error {
+	return c.pop(ctx, "LPOP", key, object, c.r.LPop)
+}
+
+func (c *redisUniversalClient) RPop(ctx *context.Context, key string, object interface{}) error {
+	return c.pop(ctx, "RPOP", key, object, c.r.RPop)
+}
+
+func (c *redisUniversalClient) pop(ctx *context.Context, operation, key string, object interface{}, cmd func(key string) *redis.StringCmd) error {
+	if err := check(c); err != nil {
+		return err
+	}
+
+	var val string
+	err := c.process(ctx, operation, key, func() (err error) {
+		val, err = cmd(key).Result()
+		return err
+	})
+
+	if err == redis.Nil {
+		return errors.Wrapf(err, "key %s does not exits", key)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to pop from list with key %s!", key)
+	}
+
+	if err := cache.Unmarshal(c.codec(ctx), []byte(val), object); err != nil {
+		return errors.Wrapf(err, "failed to pop from list with key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *redisUniversalClient) LRange(ctx *context.Context, key string, start, stop int64) ([]string, error) {
+	if err := check(c); err != nil {
+		return nil, err
+	}
+
+	var val []string
+	if err := c.process(ctx, "LRANGE", key, func() (err error) {
+		val, err = c.r.LRange(key, start, stop).Result()
+		return err
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return val, nil
+}
+
+func (c *redisUniversalClient) LLen(ctx *context.Context, key string) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	if err := c.process(ctx, "LLEN", key, func() (err error) {
+		n, err = c.r.LLen(key).Result()
+		return err
+	}); err != nil {
+		return 0, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return n, nil
+}
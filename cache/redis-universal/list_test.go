@@ -0,0 +1,61 @@
+package redis_universal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	_, c := newTestCache(t)
+
+	n, err := c.RPush(&ctx, "queue", "b", "c")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	n, _ = c.LPush(&ctx, "queue", "a")
+	assert.Equal(t, int64(3), n)
+
+	val, err := c.LRange(&ctx, "queue", 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, val)
+
+	val, _ = c.LRange(&ctx, "queue", -2, 10)
+	assert.Equal(t, []string{"b", "c"}, val)
+
+	var got string
+	assert.NoError(t, c.LPop(&ctx, "queue", &got))
+	assert.Equal(t, "a", got)
+	assert.NoError(t, c.RPop(&ctx, "queue", &got))
+	assert.Equal(t, "c", got)
+
+	n, _ = c.LLen(&ctx, "queue")
+	assert.Equal(t, int64(1), n)
+
+	assert.NoError(t, c.RPop(&ctx, "queue", &got))
+	assert.Equal(t, redis.Nil, errors.Cause(c.RPop(&ctx, "queue", &got)))
+}
+
+func TestListEncoding(t *testing.T) {
+	type job struct {
+		ID int `json:"id"`
+	}
+
+	ctx := context.Background()
+	_, c := newTestCache(t)
+
+	_, err := c.RPush(&ctx, "jobs", job{ID: 1}, job{ID: 2})
+	assert.NoError(t, err)
+
+	var got job
+	assert.NoError(t, c.LPop(&ctx, "jobs", &got))
+	assert.Equal(t, job{ID: 1}, got)
+
+	_, err = c.RPush(&ctx, "jobs", make(chan int))
+	assert.Error(t, err)
+	n, _ := c.LLen(&ctx, "jobs")
+	assert.Equal(t, int64(1), n)
+}
@@ -0,0 +1,306 @@
+package redis_universal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+const (
+	DefaultLockTTL           = 30 * time.Second
+	DefaultLockRetryInterval = 100 * time.Millisecond
+
+	// lockClockDriftFactor is the clock drift allowance of the Redlock algorithm
+	lockClockDriftFactor = 0.01
+)
+
+var (
+	// obtainScript sets the lock only when it is free and returns the next fencing token, or 0 when it is taken
+	obtainScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0`)
+
+	// fencingScript raises the fencing counter to ARGV[1] when it is lower
+	fencingScript = redis.NewScript(`
+if tonumber(redis.call("GET", KEYS[1]) or "0") < tonumber(ARGV[1]) then
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+return 1`)
+
+	refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+type (
+	locker struct {
+		clients []redis.UniversalClient
+	}
+
+	lock struct {
+		locker *locker
+		key    string
+		value  string
+		token  int64
+		ttl    time.Duration
+		stop   chan struct{}
+		done   chan struct{}
+
+		stopOnce sync.Once
+		doneOnce sync.Once
+	}
+)
+
+// NewLocker returns a cache.Locker backed by option.Address. When option.Redlock is set and more than one
+// address is given, every address is used as an independent master and locks need a majority of them.
+func NewLocker(option *Option) (cache.Locker, error) {
+	addresses := [][]string{option.Address}
+	if option.Redlock && len(option.Address) > 1 {
+		addresses = make([][]string, 0, len(option.Address))
+		for _, address := range option.Address {
+			addresses = append(addresses, []string{address})
+		}
+	}
+
+	l := &locker{clients: make([]redis.UniversalClient, 0, len(addresses))}
+	for _, address := range addresses {
+		client := newUniversalClient(option, address)
+		if _, err := client.Ping().Result(); err != nil {
+			_ = l.Close()
+			_ = client.Close()
+			return nil, errors.Wrapf(err, "Failed to connect to redis %v!", address)
+		}
+		l.clients = append(l.clients, client)
+	}
+
+	return l, nil
+}
+
+func (l *locker) Obtain(ctx *context.Context, key string, option cache.LockOption) (cache.Lock, error) {
+	if option.TTL <= 0 {
+		option.TTL = DefaultLockTTL
+	}
+
+	if option.RetryInterval <= 0 {
+		option.RetryInterval = DefaultLockRetryInterval
+	}
+
+	value := uuid.New().String()
+	deadline := time.Now().Add(option.WaitTimeout)
+
+	for {
+		token, err := l.obtain(key, value, option.TTL)
+		if err == nil {
+			lk := &lock{
+				locker: l,
+				key:    key,
+				value:  value,
+				token:  token,
+				ttl:    option.TTL,
+				stop:   make(chan struct{}),
+				done:   make(chan struct{}),
+			}
+
+			if option.AutoRenew {
+				go lk.renew()
+			}
+			return lk, nil
+		}
+
+		if errors.Cause(err) != cache.ErrLockNotObtained || !time.Now().Add(option.RetryInterval).Before(deadline) {
+			return nil, err
+		}
+
+		select {
+		case <-doneChannel(ctx):
+			return nil, errors.Wrapf((*ctx).Err(), "failed to obtain lock %s", key)
+		case <-time.After(option.RetryInterval):
+		}
+	}
+}
+
+func (l *locker) Close() error {
+	for _, client := range l.clients {
+		if err := client.Close(); err != nil {
+			return errors.Wrap(err, "failed to close redis client")
+		}
+	}
+
+	return nil
+}
+
+func (l *locker) obtain(key, value string, ttl time.Duration) (int64, error) {
+	var (
+		start    = time.Now()
+		acquired int
+		token    int64
+		lastErr  error
+	)
+
+	for _, client := range l.clients {
+		res, err := obtainScript.Run(client, []string{lockKey(key), fencingKey(key)}, value, ttl.Milliseconds()).Int64()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if res == 0 {
+			continue
+		}
+
+		acquired++
+		if res > token {
+			token = res
+		}
+	}
+
+	drift := time.Duration(float64(ttl)*lockClockDriftFactor) + 2*time.Millisecond
+	if acquired >= l.quorum() && l.fence(key, token) && ttl-time.Since(start)-drift > 0 {
+		return token, nil
+	}
+
+	_, _ = l.eval(nil, releaseScript, key, value)
+
+	if len(l.clients) == 1 && lastErr != nil {
+		return 0, errors.Wrapf(lastErr, "failed to obtain lock %s", key)
+	}
+
+	return 0, errors.Wrapf(cache.ErrLockNotObtained, "failed to obtain lock %s", key)
+}
+
+// fence raises the fencing counter of every node to token. The counters are per node and token is their maximum,
+// so once a majority holds it any later majority, which shares a node with it, hands out a greater token.
+func (l *locker) fence(key string, token int64) bool {
+	if len(l.clients) == 1 {
+		return true
+	}
+
+	var fenced int
+	for _, client := range l.clients {
+		if err := fencingScript.Run(client, []string{fencingKey(key)}, token).Err(); err == nil {
+			fenced++
+		}
+	}
+
+	return fenced >= l.quorum()
+}
+
+// eval runs a compare-and-x script on every client and returns how many of them applied it,
+// the remaining clients are skipped once ctx is done.
+func (l *locker) eval(ctx *context.Context, script *redis.Script, key, value string, args ...interface{}) (int, error) {
+	var applied int
+	for _, client := range l.clients {
+		select {
+		case <-doneChannel(ctx):
+			return applied, (*ctx).Err()
+		default:
+		}
+
+		res, err := script.Run(client, []string{lockKey(key)}, append([]interface{}{value}, args...)...).Int64()
+		if err == nil && res == 1 {
+			applied++
+		}
+	}
+
+	return applied, nil
+}
+
+func (l *locker) quorum() int {
+	return len(l.clients)/2 + 1
+}
+
+func (lk *lock) Key() string {
+	return lk.key
+}
+
+func (lk *lock) Token() int64 {
+	return lk.token
+}
+
+func (lk *lock) Refresh(ctx *context.Context, ttl time.Duration) error {
+	applied, err := lk.locker.eval(ctx, refreshScript, lk.key, lk.value, ttl.Milliseconds())
+	if err != nil {
+		return errors.Wrapf(err, "failed to refresh lock %s", lk.key)
+	}
+
+	if applied < lk.locker.quorum() {
+		return errors.Wrapf(cache.ErrLockNotHeld, "failed to refresh lock %s", lk.key)
+	}
+
+	return nil
+}
+
+func (lk *lock) Release(ctx *context.Context) error {
+	lk.stopOnce.Do(func() { close(lk.stop) })
+	defer lk.finish()
+
+	applied, err := lk.locker.eval(ctx, releaseScript, lk.key, lk.value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to release lock %s", lk.key)
+	}
+
+	if applied < lk.locker.quorum() {
+		return errors.Wrapf(cache.ErrLockNotHeld, "failed to release lock %s", lk.key)
+	}
+
+	return nil
+}
+
+func (lk *lock) Done() <-chan struct{} {
+	return lk.done
+}
+
+func (lk *lock) renew() {
+	ticker := time.NewTicker(lk.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lk.stop:
+			return
+		case <-ticker.C:
+			if err := lk.Refresh(nil, lk.ttl); err != nil {
+				lk.finish()
+				return
+			}
+		}
+	}
+}
+
+func (lk *lock) finish() {
+	lk.doneOnce.Do(func() { close(lk.done) })
+}
+
+// lockKey hash-tags the key so the lock and its fencing counter land on the same cluster slot.
+func lockKey(key string) string {
+	return fmt.Sprintf("lock:{%s}", key)
+}
+
+func fencingKey(key string) string {
+	return lockKey(key) + ":fencing"
+}
+
+func doneChannel(ctx *context.Context) <-chan struct{} {
+	if ctx == nil || *ctx == nil {
+		return nil
+	}
+
+	return (*ctx).Done()
+}
@@ -0,0 +1,170 @@
+package redis_universal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+func newTestLocker(t *testing.T, redlock bool, servers ...*miniredis.Miniredis) cache.Locker {
+	address := make([]string, 0, len(servers))
+	for _, s := range servers {
+		address = append(address, s.Addr())
+	}
+
+	l, err := NewLocker(&Option{Address: address, Redlock: redlock, DialTimeout: 100 * time.Millisecond})
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	return l
+}
+
+func TestLockObtainAndRelease(t *testing.T) {
+	s := miniredis.RunT(t)
+	l := newTestLocker(t, false, s)
+	ctx := context.Background()
+
+	first, err := l.Obtain(&ctx, "order", cache.LockOption{TTL: time.Minute})
+	assert.NoError(t, err)
+	assert.Equal(t, "order", first.Key())
+	assert.Equal(t, int64(1), first.Token())
+
+	// contention
+	_, err = l.Obtain(&ctx, "order", cache.LockOption{TTL: time.Minute})
+	assert.True(t, errors.Is(err, cache.ErrLockNotObtained))
+
+	start := time.Now()
+	_, err = l.Obtain(&ctx, "order", cache.LockOption{TTL: time.Minute, WaitTimeout: 50 * time.Millisecond, RetryInterval: 10 * time.Millisecond})
+	assert.True(t, errors.Is(err, cache.ErrLockNotObtained))
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(30*time.Millisecond))
+
+	assert.NoError(t, first.Release(&ctx))
+	assert.False(t, s.Exists(lockKey("order")))
+	<-first.Done()
+
+	second, err := l.Obtain(&ctx, "order", cache.LockOption{TTL: time.Minute})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), second.Token())
+	assert.True(t, errors.Is(first.Release(&ctx), cache.ErrLockNotHeld), "released twice")
+	assert.True(t, s.Exists(lockKey("order")))
+}
+
+func TestLockExpiryAndWrongToken(t *testing.T) {
+	s := miniredis.RunT(t)
+	l := newTestLocker(t, false, s)
+	ctx := context.Background()
+
+	first, err := l.Obtain(&ctx, "order", cache.LockOption{TTL: time.Second})
+	assert.NoError(t, err)
+
+	s.FastForward(2 * time.Second)
+	assert.True(t, errors.Is(first.Refresh(&ctx, time.Second), cache.ErrLockNotHeld))
+
+	second, err := l.Obtain(&ctx, "order", cache.LockOption{TTL: time.Minute})
+	assert.NoError(t, err)
+	assert.Greater(t, second.Token(), first.Token())
+
+	// the expired holder cannot release the lock of the new one
+	assert.True(t, errors.Is(first.Release(&ctx), cache.ErrLockNotHeld))
+	assert.True(t, s.Exists(lockKey("order")))
+
+	assert.NoError(t, second.Refresh(&ctx, time.Hour))
+	assert.Equal(t, time.Hour, s.TTL(lockKey("order")))
+	assert.NoError(t, second.Release(&ctx))
+}
+
+func TestLockAutoRenew(t *testing.T) {
+	s := miniredis.RunT(t)
+	l := newTestLocker(t, false, s)
+	ctx := context.Background()
+
+	lk, err := l.Obtain(&ctx, "order", cache.LockOption{TTL: 30 * time.Millisecond, AutoRenew: true})
+	assert.NoError(t, err)
+
+	// miniredis does not expire keys on its own, the renewal resets the TTL that FastForward consumes
+	for i := 0; i < 3; i++ {
+		time.Sleep(20 * time.Millisecond)
+		s.FastForward(20 * time.Millisecond)
+		assert.True(t, s.Exists(lockKey("order")))
+	}
+
+	// the renewal fails once the lock is taken over, Done reports it
+	s.Set(lockKey("order"), "other")
+	select {
+	case <-lk.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the lost lock was not reported")
+	}
+	assert.True(t, errors.Is(lk.Release(&ctx), cache.ErrLockNotHeld))
+}
+
+func TestRedlock(t *testing.T) {
+	servers := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t), miniredis.RunT(t)}
+	l := newTestLocker(t, true, servers...)
+	ctx := context.Background()
+
+	// the counter of one node is ahead, every node is raised to the token
+	servers[0].Set(fencingKey("order"), "10")
+	first, err := l.Obtain(&ctx, "order", cache.LockOption{TTL: time.Minute})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), first.Token())
+	for _, s := range servers {
+		assert.True(t, s.Exists(lockKey("order")))
+		fencing, _ := s.Get(fencingKey("order"))
+		assert.Equal(t, "11", fencing)
+	}
+	assert.NoError(t, first.Release(&ctx))
+
+	// a majority that does not include the node that was ahead still hands out a greater token
+	servers[0].Close()
+	second, err := l.Obtain(&ctx, "order", cache.LockOption{TTL: time.Minute})
+	assert.NoError(t, err)
+	assert.Greater(t, second.Token(), first.Token())
+	assert.NoError(t, second.Release(&ctx))
+
+	// losing the quorum, the node that was locked is released
+	servers[1].Set(lockKey("order"), "other")
+	_, err = l.Obtain(&ctx, "order", cache.LockOption{TTL: time.Minute})
+	assert.True(t, errors.Is(err, cache.ErrLockNotObtained))
+	assert.False(t, servers[2].Exists(lockKey("order")))
+}
+
+func TestRedlockRefreshNeedsQuorum(t *testing.T) {
+	servers := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t), miniredis.RunT(t)}
+	l := newTestLocker(t, true, servers...)
+	ctx := context.Background()
+
+	lk, err := l.Obtain(&ctx, "order", cache.LockOption{TTL: time.Minute})
+	assert.NoError(t, err)
+
+	servers[0].Del(lockKey("order"))
+	assert.NoError(t, lk.Refresh(&ctx, time.Minute))
+
+	servers[1].Del(lockKey("order"))
+	assert.True(t, errors.Is(lk.Refresh(&ctx, time.Minute), cache.ErrLockNotHeld))
+}
+
+func TestLockHonoursContext(t *testing.T) {
+	s := miniredis.RunT(t)
+	l := newTestLocker(t, false, s)
+	ctx := context.Background()
+
+	lk, err := l.Obtain(&ctx, "order", cache.LockOption{TTL: time.Minute})
+	assert.NoError(t, err)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	assert.True(t, errors.Is(lk.Refresh(&cancelled, time.Hour), context.Canceled))
+	assert.Greater(t, int64(s.TTL(lockKey("order"))), int64(0))
+	assert.LessOrEqual(t, int64(s.TTL(lockKey("order"))), int64(time.Minute))
+
+	assert.True(t, errors.Is(lk.Release(&cancelled), context.Canceled))
+	assert.True(t, s.Exists(lockKey("order")))
+	assert.NoError(t, lk.Release(&ctx))
+}
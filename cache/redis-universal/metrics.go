@@ -0,0 +1,150 @@
+package redis_universal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/Dert12318/Utilities/cache/metrics"
+	"github.com/Dert12318/Utilities/logs"
+	"github.com/Dert12318/Utilities/logs/logrus"
+)
+
+type (
+	instrumentation struct {
+		metrics       metrics.Recorder
+		slowThreshold time.Duration
+		log           logs.Logger
+	}
+
+	poolStater interface {
+		PoolStats() *redis.PoolStats
+	}
+)
+
+// instrument hooks every command and pipeline of client to report option.Metrics and log the slow commands.
+// The commands of a Watch transaction run on their own connection and are not reported.
+func instrument(client redis.UniversalClient, option *Option) {
+	if option.Metrics == nil && option.SlowThreshold <= 0 {
+		return
+	}
+
+	i := &instrumentation{metrics: option.Metrics, slowThreshold: option.SlowThreshold, log: option.Log}
+	if i.log == nil {
+		i.log = logrus.DefaultLog()
+	}
+
+	client.WrapProcess(func(process func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			start := time.Now()
+			err := process(cmd)
+			i.observe(time.Since(start), cmd)
+			return err
+		}
+	})
+	client.WrapProcessPipeline(func(process func(cmds []redis.Cmder) error) func(cmds []redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			start := time.Now()
+			err := process(cmds)
+			i.observe(time.Since(start), cmds...)
+			return err
+		}
+	})
+
+	if stater, ok := client.(poolStater); ok && option.Metrics != nil {
+		option.Metrics.RegisterPool(func() metrics.PoolStats {
+			stats := stater.PoolStats()
+			return metrics.PoolStats{
+				Hits:       stats.Hits,
+				Misses:     stats.Misses,
+				Timeouts:   stats.Timeouts,
+				TotalConns: stats.TotalConns,
+				IdleConns:  stats.IdleConns,
+				StaleConns: stats.StaleConns,
+			}
+		})
+	}
+}
+
+func (i *instrumentation) observe(duration time.Duration, cmds ...redis.Cmder) {
+	if i.metrics != nil {
+		for _, cmd := range cmds {
+			i.metrics.ObserveCommand(cmd.Name(), metricResult(cmd), duration)
+		}
+	}
+
+	if i.slowThreshold > 0 && duration >= i.slowThreshold {
+		if len(cmds) == 1 {
+			i.log.Warnf("slow redis command %s %s took %s", cmds[0].Name(), commandKey(cmds[0]), duration)
+			return
+		}
+
+		i.log.Warnf("slow redis pipeline of %d commands took %s", len(cmds), duration)
+	}
+}
+
+// readCommands are reported as a hit or a miss, the other commands as ok
+var readCommands = map[string]bool{
+	"get": true, "mget": true, "getrange": true, "strlen": true, "exists": true, "type": true, "ttl": true, "pttl": true,
+	"hget": true, "hmget": true, "hgetall": true, "hexists": true, "hkeys": true, "hvals": true, "hlen": true,
+	"lindex": true, "lrange": true, "llen": true,
+	"smembers": true, "sismember": true, "scard": true,
+	"zscore": true, "zrank": true, "zrevrank": true, "zrange": true, "zrevrange": true, "zrangebyscore": true, "zcard": true,
+}
+
+func metricResult(cmd redis.Cmder) metrics.Result {
+	err := cmd.Err()
+	if err != nil && err != redis.Nil {
+		return metrics.Error
+	}
+
+	if !readCommands[cmd.Name()] {
+		return metrics.Ok
+	}
+	if err == redis.Nil || missed(cmd) {
+		return metrics.Miss
+	}
+	return metrics.Hit
+}
+
+// missed reports whether the reply of a read command is the one of a missing key, most of them do not reply
+// nil but an empty value: no element, a count of 0, a TTL of -2 or the type "none"
+func missed(cmd redis.Cmder) bool {
+	switch cmd := cmd.(type) {
+	case *redis.StringCmd:
+		return cmd.Name() == "getrange" && cmd.Val() == ""
+	case *redis.SliceCmd:
+		for _, val := range cmd.Val() {
+			if val == nil {
+				return true
+			}
+		}
+		return len(cmd.Val()) == 0
+	case *redis.StringSliceCmd:
+		return len(cmd.Val()) == 0
+	case *redis.StringStringMapCmd:
+		return len(cmd.Val()) == 0
+	case *redis.ZSliceCmd:
+		return len(cmd.Val()) == 0
+	case *redis.BoolCmd:
+		return !cmd.Val()
+	case *redis.StatusCmd:
+		return cmd.Val() == "none"
+	case *redis.DurationCmd:
+		return cmd.Val() == -2*time.Second || cmd.Val() == -2*time.Millisecond
+	case *redis.IntCmd:
+		// a rank of 0 is the first member, a missing one replies nil
+		return cmd.Name() != "zrank" && cmd.Name() != "zrevrank" && cmd.Val() == 0
+	}
+	return false
+}
+
+// commandKey is the first argument of cmd, the key for most commands, values and AUTH passwords are never logged
+func commandKey(cmd redis.Cmder) string {
+	if args := cmd.Args(); len(args) > 1 && cmd.Name() != "auth" {
+		return fmt.Sprint(args[1])
+	}
+
+	return ""
+}
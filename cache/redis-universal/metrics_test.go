@@ -0,0 +1,81 @@
+package redis_universal
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/cache/metrics"
+	"github.com/Dert12318/Utilities/logs/logrus"
+)
+
+func TestMetrics(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	var logged bytes.Buffer
+	log := logrus.DefaultLog()
+	log.SetOutput(&logged)
+
+	registry := metrics.NewRegistry(nil)
+	c, err := New(&Option{
+		Address:       []string{s.Addr()},
+		Metrics:       registry,
+		SlowThreshold: 1,
+		Log:           log,
+	})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	ctx := context.Background()
+	var value string
+	assert.NoError(t, c.Set(&ctx, "user:1", "a"))
+	assert.NoError(t, c.Get(&ctx, "user:1", &value))
+	assert.Error(t, c.Get(&ctx, "user:2", &value))
+
+	p := c.Pipeline()
+	p.Incr("counter")
+	p.Get("user:3")
+	assert.NoError(t, p.Exec())
+	_, err = c.Incr(&ctx, "user:1")
+	assert.Error(t, err)
+
+	// these reads do not reply nil for a missing key but an empty value
+	all, err := c.HGetAll(&ctx, "missing")
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+	_, err = c.TTL(&ctx, "missing")
+	assert.Error(t, err)
+	_, err = c.TTL(&ctx, "user:1")
+	assert.NoError(t, err)
+	_, err = c.MGet(&ctx, []string{"user:1", "missing"})
+	assert.NoError(t, err)
+
+	var b bytes.Buffer
+	assert.NoError(t, registry.WritePrometheus(&b))
+
+	out := b.String()
+	assert.Contains(t, out, `cache_commands_total{command="set",result="ok"} 1`)
+	assert.Contains(t, out, `cache_commands_total{command="get",result="hit"} 1`)
+	assert.Contains(t, out, `cache_commands_total{command="get",result="miss"} 2`)
+	assert.Contains(t, out, `cache_commands_total{command="incr",result="ok"} 1`)
+	assert.Contains(t, out, `cache_commands_total{command="incrby",result="error"} 1`)
+	assert.Contains(t, out, `cache_commands_total{command="hgetall",result="miss"} 1`)
+	assert.Contains(t, out, `cache_commands_total{command="pttl",result="hit"} 1`)
+	assert.Contains(t, out, `cache_commands_total{command="pttl",result="miss"} 1`)
+	assert.Contains(t, out, `cache_commands_total{command="mget",result="miss"} 1`)
+	assert.Contains(t, out, `cache_command_duration_seconds_count{command="get"} 3`)
+	assert.Contains(t, out, `cache_pool_connections{state="total"} 1`)
+
+	assert.Contains(t, logged.String(), "slow redis command get user:2")
+	assert.Contains(t, logged.String(), "slow redis pipeline of 2 commands")
+}
+
+func TestCommandKeyHidesPassword(t *testing.T) {
+	assert.Equal(t, "user:1", commandKey(redis.NewStringCmd("get", "user:1")))
+	assert.Equal(t, "", commandKey(redis.NewStatusCmd("auth", "secret")))
+	assert.Equal(t, "", commandKey(redis.NewStatusCmd("ping")))
+}
@@ -1,50 +1,196 @@
 package redis_universal
 
 import (
-	"encoding"
-	"fmt"
+	"time"
+
+	"github.com/Dert12318/Utilities/cache"
+	"github.com/Dert12318/Utilities/encoding"
 	gr "github.com/go-redis/redis"
 	"github.com/pkg/errors"
-	"time"
 )
 
 type (
 	pipe struct {
 		instance gr.Pipeliner
+		encoding encoding.Encoding
+		state    *pipeState
+	}
+
+	// pipeState is shared by a pipe and its results so they know when the commands ran
+	pipeState struct {
+		executed bool
 	}
 )
 
-func (p *pipe) Set(key string, value interface{}) error {
+func newPipe(instance gr.Pipeliner, e encoding.Encoding) *pipe {
+	return &pipe{instance: instance, encoding: e, state: &pipeState{}}
+}
+
+func (p *pipe) Set(key string, value interface{}) cache.StatusResult {
 	return p.SetWithExpiration(key, value, 0)
 }
 
-func (p *pipe) SetWithExpiration(key string, value interface{}, expired time.Duration) error {
-	return p.instance.Set(key, value, expired).Err()
+func (p *pipe) SetWithExpiration(key string, value interface{}, expired time.Duration) cache.StatusResult {
+	val, err := cache.Marshal(p.encoding, value)
+	if err != nil {
+		return &statusResult{result: p.failed(key, err)}
+	}
+
+	cmd := p.instance.Set(key, val, expired)
+	return &statusResult{result: p.result(key, cmd)}
+}
+
+func (p *pipe) Get(key string) cache.StringResult {
+	cmd := p.instance.Get(key)
+	return &stringResult{result: p.result(key, cmd), cmd: cmd, encoding: p.encoding}
+}
+
+func (p *pipe) Del(keys ...string) cache.IntResult {
+	cmd := p.instance.Del(keys...)
+	return &intResult{result: p.result(keys, cmd), cmd: cmd}
+}
+
+func (p *pipe) Expire(key string, expiration time.Duration) cache.BoolResult {
+	cmd := p.instance.Expire(key, expiration)
+	return &boolResult{result: p.result(key, cmd), cmd: cmd}
 }
 
-func (p *pipe) Get(key string, object interface{}) error {
-	if _, ok := object.(encoding.BinaryUnmarshaler); !ok {
-		return errors.New(fmt.Sprintf("failed to get cache with key %s!: redis: can't unmarshal (implement encoding.BinaryUnmarshaler)", key))
+func (p *pipe) Incr(key string) cache.IntResult {
+	cmd := p.instance.Incr(key)
+	return &intResult{result: p.result(key, cmd), cmd: cmd}
+}
+
+func (p *pipe) IncrBy(key string, value int64) cache.IntResult {
+	cmd := p.instance.IncrBy(key, value)
+	return &intResult{result: p.result(key, cmd), cmd: cmd}
+}
+
+func (p *pipe) HSet(key, field string, value interface{}) cache.BoolResult {
+	val, err := cache.Marshal(p.encoding, value)
+	if err != nil {
+		return &boolResult{result: p.failed(key, err), cmd: &gr.BoolCmd{}}
 	}
 
-	val, err := p.instance.Get(key).Result()
+	cmd := p.instance.HSet(key, field, val)
+	return &boolResult{result: p.result(key, cmd), cmd: cmd}
+}
+
+func (p *pipe) HMSet(key string, value map[string]interface{}) cache.StatusResult {
+	fields := make(map[string]interface{}, len(value))
+	for field, v := range value {
+		val, err := cache.Marshal(p.encoding, v)
+		if err != nil {
+			return &statusResult{result: p.failed(key, err)}
+		}
+		fields[field] = val
+	}
+
+	cmd := p.instance.HMSet(key, fields)
+	return &statusResult{result: p.result(key, cmd)}
+}
+
+func (p *pipe) HGet(key, field string) cache.StringResult {
+	cmd := p.instance.HGet(key, field)
+	return &stringResult{result: p.result(key, cmd), cmd: cmd, encoding: p.encoding}
+}
+
+func (p *pipe) HMGet(key string, fields ...string) cache.SliceResult {
+	cmd := p.instance.HMGet(key, fields...)
+	return &sliceResult{result: p.result(key, cmd), cmd: cmd}
+}
+
+func (p *pipe) HGetAll(key string) cache.StringMapResult {
+	cmd := p.instance.HGetAll(key)
+	return &stringMapResult{result: p.result(key, cmd), cmd: cmd}
+}
+
+func (p *pipe) HDel(key string, fields ...string) cache.IntResult {
+	cmd := p.instance.HDel(key, fields...)
+	return &intResult{result: p.result(key, cmd), cmd: cmd}
+}
 
-	if err == gr.Nil {
-		return errors.Wrapf(err, "key %s does not exits", key)
+func (p *pipe) SAdd(key string, members ...interface{}) cache.IntResult {
+	vals, err := p.marshalAll(members)
+	if err != nil {
+		return &intResult{result: p.failed(key, err), cmd: &gr.IntCmd{}}
 	}
 
+	cmd := p.instance.SAdd(key, vals...)
+	return &intResult{result: p.result(key, cmd), cmd: cmd}
+}
+
+func (p *pipe) SRem(key string, members ...interface{}) cache.IntResult {
+	vals, err := p.marshalAll(members)
 	if err != nil {
-		return errors.Wrapf(err, "failed to get key %s", key)
+		return &intResult{result: p.failed(key, err), cmd: &gr.IntCmd{}}
 	}
 
-	if err := object.(encoding.BinaryUnmarshaler).UnmarshalBinary([]byte(val)); err != nil {
-		return errors.Wrapf(err, "failed to unmarshal object")
+	cmd := p.instance.SRem(key, vals...)
+	return &intResult{result: p.result(key, cmd), cmd: cmd}
+}
+
+func (p *pipe) SIsMember(key string, member interface{}) cache.BoolResult {
+	val, err := cache.Marshal(p.encoding, member)
+	if err != nil {
+		return &boolResult{result: p.failed(key, err), cmd: &gr.BoolCmd{}}
 	}
 
-	return nil
+	cmd := p.instance.SIsMember(key, val)
+	return &boolResult{result: p.result(key, cmd), cmd: cmd}
+}
+
+func (p *pipe) SMembers(key string) cache.StringSliceResult {
+	cmd := p.instance.SMembers(key)
+	return &stringSliceResult{result: p.result(key, cmd), cmd: cmd}
 }
 
 func (p *pipe) Exec() error {
 	_, err := p.instance.Exec()
+	p.state.executed = true
+	// go-redis resets the queue on Exec, the commands queued from now on get a fresh state
+	p.state = &pipeState{}
+	return execError(err)
+}
+
+func (p *pipe) Discard() error {
+	if err := p.instance.Discard(); err != nil {
+		return errors.Wrap(err, "failed to discard universal pipeline")
+	}
+
+	return nil
+}
+
+func (p *pipe) result(key interface{}, cmd gr.Cmder) result {
+	return result{key: key, cmd: cmd, state: p.state}
+}
+
+// failed is the result of a command that could not be queued
+func (p *pipe) failed(key interface{}, err error) result {
+	return result{key: key, err: errors.Wrapf(err, "failed to queue command for key %v", key), state: p.state}
+}
+
+func (p *pipe) marshalAll(values []interface{}) ([]interface{}, error) {
+	vals := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		val, err := cache.Marshal(p.encoding, value)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+	}
+
+	return vals, nil
+}
+
+// execError drops redis.Nil from the pipeline error, a missing key is reported on its own result.
+func execError(err error) error {
+	if err == nil || err == gr.Nil {
+		return nil
+	}
+
+	if err == gr.TxFailedErr {
+		return errors.Wrap(cache.ErrTxFailed, "failed to universal pipeline")
+	}
+
 	return errors.Wrapf(err, "failed to universal pipeline")
 }
@@ -0,0 +1,157 @@
+package redis_universal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+func newTestCache(t *testing.T) (*miniredis.Miniredis, cache.Cache) {
+	s := miniredis.RunT(t)
+
+	c, err := New(&Option{Address: []string{s.Addr()}})
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	return s, c
+}
+
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+	_, c := newTestCache(t)
+
+	p := c.Pipeline()
+	set := p.Set("a", "1")
+	incr := p.Incr("counter")
+	get := p.Get("a")
+	missing := p.Get("missing")
+	hset := p.HSet("h", "f", 1)
+	members := p.SMembers("missing")
+
+	var got string
+	assert.Error(t, c.Get(&ctx, "a", &got))
+	assert.Equal(t, cache.ErrPipeNotExecuted, get.Err())
+
+	assert.NoError(t, p.Exec())
+	assert.NoError(t, set.Err())
+	assert.Equal(t, int64(1), incr.Val())
+	assert.NoError(t, get.Scan(&got))
+	assert.Equal(t, "1", got)
+	assert.Equal(t, redis.Nil, errors.Cause(missing.Err()))
+	assert.True(t, hset.Val())
+	assert.Empty(t, members.Val())
+
+	// the queue is reset, new commands are not executed yet
+	assert.Equal(t, cache.ErrPipeNotExecuted, p.Get("a").Err())
+}
+
+func TestPipelineErrors(t *testing.T) {
+	ctx := context.Background()
+	_, c := newTestCache(t)
+	assert.NoError(t, c.Set(&ctx, "name", "john"))
+
+	p := c.Pipeline()
+	incr := p.Incr("name")
+	set := p.Set("a", "1")
+	unencodable := p.Set("b", make(chan int))
+
+	// a value that cannot be encoded is not queued, the other commands still run
+	assert.Error(t, unencodable.Err())
+	assert.NotEqual(t, cache.ErrPipeNotExecuted, unencodable.Err())
+
+	err := p.Exec()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not an integer")
+	assert.Contains(t, incr.Err().Error(), "failed to incr key name")
+	assert.NoError(t, set.Err())
+
+	var got string
+	assert.NoError(t, c.Get(&ctx, "a", &got))
+	assert.Error(t, c.Get(&ctx, "b", &got))
+}
+
+func TestTxPipelineDiscard(t *testing.T) {
+	ctx := context.Background()
+	_, c := newTestCache(t)
+
+	p := c.TxPipeline()
+	p.Set("a", "1")
+	assert.NoError(t, p.Discard())
+	assert.NoError(t, p.Exec())
+
+	var got string
+	assert.Error(t, c.Get(&ctx, "a", &got))
+}
+
+func TestWatch(t *testing.T) {
+	ctx := context.Background()
+	_, c := newTestCache(t)
+	assert.NoError(t, c.Set(&ctx, "balance", 10))
+
+	var result cache.IntResult
+	err := c.Watch(&ctx, func(tx cache.Tx) error {
+		var balance int
+		if err := tx.Get("balance", &balance); err != nil {
+			return err
+		}
+
+		return tx.Pipelined(func(pipe cache.Pipe) error {
+			pipe.Set("balance", balance-3)
+			result = pipe.Incr("withdrawals")
+			return nil
+		})
+	}, "balance")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.Val())
+
+	var balance int
+	assert.NoError(t, c.Get(&ctx, "balance", &balance))
+	assert.Equal(t, 7, balance)
+}
+
+func TestWatchConflict(t *testing.T) {
+	ctx := context.Background()
+	s, c := newTestCache(t)
+	assert.NoError(t, c.HSet(&ctx, "h", "f", "1"))
+
+	var result cache.StatusResult
+	err := c.Watch(&ctx, func(tx cache.Tx) error {
+		var f string
+		assert.NoError(t, tx.HGet("h", "f", &f))
+		assert.Equal(t, "1", f)
+
+		// written by someone else between the read and the transaction
+		s.HSet("h", "f", "2")
+
+		return tx.Pipelined(func(pipe cache.Pipe) error {
+			result = pipe.Set("other", "1")
+			return nil
+		})
+	}, "h")
+	assert.Equal(t, cache.ErrTxFailed, errors.Cause(err))
+	assert.Equal(t, cache.ErrTxFailed, errors.Cause(result.Err()))
+	assert.False(t, s.Exists("other"))
+}
+
+func TestWatchCallbackError(t *testing.T) {
+	ctx := context.Background()
+	s, c := newTestCache(t)
+
+	failed := errors.New("insufficient balance")
+	err := c.Watch(&ctx, func(tx cache.Tx) error {
+		var balance int
+		assert.Equal(t, redis.Nil, errors.Cause(tx.Get("balance", &balance)))
+
+		return tx.Pipelined(func(pipe cache.Pipe) error {
+			pipe.Set("balance", 1)
+			return failed
+		})
+	}, "balance")
+	assert.Equal(t, failed, errors.Cause(err))
+	assert.False(t, s.Exists("balance"))
+}
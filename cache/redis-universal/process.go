@@ -0,0 +1,90 @@
+package redis_universal
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/go-redis/redis"
+	relic "github.com/newrelic/go-agent/v3/newrelic"
+
+	"github.com/Dert12318/Utilities/apm"
+	"github.com/Dert12318/Utilities/apm/disabled"
+	tntContext "github.com/Dert12318/Utilities/context"
+)
+
+// process runs a redis command honoring ctx: it fails fast when ctx is already done and stops waiting
+// once ctx is cancelled. go-redis v6 can not abort a command in flight, so the command itself keeps
+// running until ReadTimeout/WriteTimeout. A datastore segment is recorded when ctx carries a
+// Utilities context with a Transaction.
+func (c *redisUniversalClient) process(ctx *context.Context, operation, key string, fn func() error) error {
+	var done <-chan struct{}
+	if ctx != nil && *ctx != nil {
+		if err := (*ctx).Err(); err != nil {
+			return err
+		}
+		done = (*ctx).Done()
+	}
+
+	segment := startSegment(ctx, operation, key)
+	defer segment.End()
+
+	if done == nil {
+		return record(segment, fn())
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- fn()
+	}()
+
+	select {
+	case err := <-result:
+		return record(segment, err)
+	case <-done:
+		return record(segment, (*ctx).Err())
+	}
+}
+
+func startSegment(ctx *context.Context, operation, key string) apm.Segment {
+	if ctx == nil || *ctx == nil {
+		return disabled.NewSegment()
+	}
+
+	tc, ok := tntContext.FromContext(*ctx)
+	if !ok || tc.Transaction == nil {
+		return disabled.NewSegment()
+	}
+
+	pattern := keyPattern(key)
+	segment := tc.Transaction.StartDataStoreSegment(apm.DatastoreSegmentDTO{
+		Collection:       pattern,
+		Operation:        operation,
+		DatabaseName:     "redis",
+		DatastoreProduct: relic.DatastoreRedis,
+	})
+	segment.AddAttribute("operation", operation)
+	segment.AddAttribute("key_pattern", pattern)
+	return segment
+}
+
+func record(segment apm.Segment, err error) error {
+	if err != nil && err != redis.Nil {
+		segment.AddAttribute("error", err.Error())
+	}
+
+	return err
+}
+
+// keyPattern masks the ids of a key so segments group by shape, e.g. "user:123:profile" becomes
+// "user:*:profile". Every ':' separated part that contains a digit is considered an id.
+func keyPattern(key string) string {
+	parts := strings.Split(key, ":")
+	for i, part := range parts {
+		if strings.IndexFunc(part, unicode.IsDigit) >= 0 {
+			parts[i] = "*"
+		}
+	}
+
+	return strings.Join(parts, ":")
+}
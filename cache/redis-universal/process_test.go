@@ -0,0 +1,50 @@
+package redis_universal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyPattern(t *testing.T) {
+	assert.Equal(t, "user:*:profile", keyPattern("user:123:profile"))
+	assert.Equal(t, "session:*", keyPattern("session:6f1c2a"))
+	assert.Equal(t, "config", keyPattern("config"))
+}
+
+func TestProcess(t *testing.T) {
+	c := &redisUniversalClient{}
+
+	t.Run("done context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		called := false
+		err := c.process(&ctx, "GET", "a", func() error {
+			called = true
+			return nil
+		})
+		assert.Equal(t, context.Canceled, err)
+		assert.False(t, called)
+	})
+
+	t.Run("deadline while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		release := make(chan struct{})
+		defer close(release)
+
+		err := c.process(&ctx, "GET", "a", func() error {
+			<-release
+			return nil
+		})
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+
+	t.Run("nil context", func(t *testing.T) {
+		assert.NoError(t, c.process(nil, "GET", "a", func() error { return nil }))
+	})
+}
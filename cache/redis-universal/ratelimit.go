@@ -0,0 +1,170 @@
+package redis_universal
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache/ratelimit"
+)
+
+// The rate limit scripts read the clock with TIME so every instance agrees on it, they reply
+// {allowed, remaining, retry after ms, reset after ms}.
+var (
+	fixedWindowScript = redis.NewScript(`
+redis.replicate_commands()
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local window, limit = tonumber(ARGV[1]), tonumber(ARGV[2])
+
+local start = now - (now % window)
+local reset = start + window - now
+local count = 0
+local stored = redis.call("HMGET", KEYS[1], "s", "c")
+if tonumber(stored[1]) == start then
+	count = tonumber(stored[2])
+end
+
+if count >= limit then
+	return {0, 0, reset, reset}
+end
+
+count = count + 1
+redis.call("HMSET", KEYS[1], "s", start, "c", count)
+redis.call("PEXPIRE", KEYS[1], reset)
+return {1, limit - count, 0, reset}`)
+
+	slidingWindowLogScript = redis.NewScript(`
+redis.replicate_commands()
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local window, limit = tonumber(ARGV[1]), tonumber(ARGV[2])
+
+local from = now - window
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", from)
+local count = redis.call("ZCARD", KEYS[1])
+if count >= limit then
+	local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+	local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+	return {0, 0, tonumber(oldest[2]) - from, tonumber(newest[2]) - from}
+end
+
+redis.call("ZADD", KEYS[1], now, ARGV[4])
+redis.call("PEXPIRE", KEYS[1], window)
+return {1, limit - count - 1, 0, window}`)
+
+	tokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local window, limit, burst = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+
+local rate = limit / window
+local tokens = burst
+local stored = redis.call("HMGET", KEYS[1], "t", "u")
+if stored[1] then
+	tokens = math.min(burst, tonumber(stored[1]) + (now - tonumber(stored[2])) * rate)
+end
+
+local allowed, retry = 0, 0
+if tokens < 1 then
+	retry = math.ceil((1 - tokens) / rate)
+else
+	tokens = tokens - 1
+	allowed = 1
+end
+
+local reset = math.ceil((burst - tokens) / rate)
+if allowed == 1 then
+	redis.call("HMSET", KEYS[1], "t", tokens, "u", now)
+	redis.call("PEXPIRE", KEYS[1], reset + 1000)
+end
+return {allowed, math.floor(tokens), retry, reset}`)
+)
+
+type (
+	rateLimiter struct {
+		client *redisUniversalClient
+		option ratelimit.Option
+		script *redis.Script
+	}
+)
+
+// NewRateLimiter returns a ratelimit.Limiter that runs the algorithm of limit atomically on Redis with a Lua script.
+func NewRateLimiter(option *Option, limit *ratelimit.Option) (ratelimit.Limiter, error) {
+	if limit == nil {
+		return nil, errors.New("rate limit option is required")
+	}
+
+	o := *limit
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	if o.Window < time.Millisecond {
+		return nil, errors.New("rate limit window must be at least a millisecond")
+	}
+
+	client := newUniversalClient(option, option.Address)
+	if _, err := client.Ping().Result(); err != nil {
+		_ = client.Close()
+		return nil, errors.Wrap(err, "Failed to connect to redis!")
+	}
+
+	script := fixedWindowScript
+	switch o.Algorithm {
+	case ratelimit.SlidingWindowLog:
+		script = slidingWindowLogScript
+	case ratelimit.TokenBucket:
+		script = tokenBucketScript
+	}
+
+	return &rateLimiter{client: &redisUniversalClient{r: client}, option: o, script: script}, nil
+}
+
+func (l *rateLimiter) Allow(ctx *context.Context, key string) (*ratelimit.Result, error) {
+	k := l.option.Key(key)
+	args := []interface{}{
+		l.option.Window.Milliseconds(),
+		l.option.Limit,
+		l.option.Burst,
+		uuid.New().String(),
+	}
+
+	var reply interface{}
+	if err := l.client.process(ctx, "EVALSHA", k, func() (err error) {
+		reply, err = l.script.Run(l.client.r, []string{k}, args...).Result()
+		return err
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to rate limit key %s", key)
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 4 {
+		return nil, errors.Errorf("failed to rate limit key %s, unexpected reply %v", key, reply)
+	}
+
+	limit := l.option.Limit
+	if l.option.Algorithm == ratelimit.TokenBucket {
+		limit = l.option.Burst
+	}
+
+	return &ratelimit.Result{
+		Allowed:    values[0].(int64) == 1,
+		Limit:      limit,
+		Remaining:  values[1].(int64),
+		RetryAfter: time.Duration(values[2].(int64)) * time.Millisecond,
+		ResetAfter: time.Duration(values[3].(int64)) * time.Millisecond,
+	}, nil
+}
+
+func (l *rateLimiter) Close() error {
+	if err := l.client.r.Close(); err != nil {
+		return errors.Wrap(err, "failed to close redis client")
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package redis_universal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/cache/ratelimit"
+)
+
+// newTestRateLimiter runs the scripts on miniredis, whose TIME is the one set with SetTime
+func newTestRateLimiter(t *testing.T, limit *ratelimit.Option) (*miniredis.Miniredis, ratelimit.Limiter) {
+	s := miniredis.RunT(t)
+	s.SetTime(time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC))
+
+	l, err := NewRateLimiter(&Option{Address: []string{s.Addr()}}, limit)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	return s, l
+}
+
+func allow(t *testing.T, l ratelimit.Limiter, key string) *ratelimit.Result {
+	ctx := context.Background()
+	res, err := l.Allow(&ctx, key)
+	assert.NoError(t, err)
+	return res
+}
+
+func TestFixedWindowScript(t *testing.T) {
+	now := time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC)
+	s, l := newTestRateLimiter(t, &ratelimit.Option{Algorithm: ratelimit.FixedWindow, Limit: 2, Window: time.Minute})
+
+	assert.Equal(t, &ratelimit.Result{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: time.Minute}, allow(t, l, "a"))
+	assert.True(t, allow(t, l, "a").Allowed)
+
+	s.SetTime(now.Add(40 * time.Second))
+	res := allow(t, l, "a")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, int64(0), res.Remaining)
+	assert.Equal(t, 20*time.Second, res.RetryAfter)
+	assert.Equal(t, 20*time.Second, res.ResetAfter)
+	assert.True(t, allow(t, l, "b").Allowed)
+
+	s.SetTime(now.Add(time.Minute))
+	res = allow(t, l, "a")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int64(1), res.Remaining)
+	assert.Equal(t, time.Duration(0), res.RetryAfter)
+}
+
+func TestSlidingWindowLogScript(t *testing.T) {
+	now := time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC)
+	s, l := newTestRateLimiter(t, &ratelimit.Option{Algorithm: ratelimit.SlidingWindowLog, Limit: 2, Window: time.Minute})
+
+	assert.Equal(t, &ratelimit.Result{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: time.Minute}, allow(t, l, "a"))
+
+	s.SetTime(now.Add(40 * time.Second))
+	assert.True(t, allow(t, l, "a").Allowed)
+
+	// a fixed window would have been reset at the minute
+	s.SetTime(now.Add(50 * time.Second))
+	res := allow(t, l, "a")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 10*time.Second, res.RetryAfter)
+	assert.Equal(t, 50*time.Second, res.ResetAfter)
+
+	s.SetTime(now.Add(61 * time.Second))
+	res = allow(t, l, "a")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int64(0), res.Remaining)
+}
+
+func TestTokenBucketScript(t *testing.T) {
+	now := time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC)
+	s, l := newTestRateLimiter(t, &ratelimit.Option{Algorithm: ratelimit.TokenBucket, Limit: 1, Window: time.Second, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		res := allow(t, l, "a")
+		assert.True(t, res.Allowed)
+		assert.Equal(t, int64(3), res.Limit)
+		assert.Equal(t, int64(2-i), res.Remaining)
+	}
+
+	res := allow(t, l, "a")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.ResetAfter)
+
+	s.SetTime(now.Add(1500 * time.Millisecond))
+	res = allow(t, l, "a")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int64(0), res.Remaining)
+	assert.Equal(t, 2500*time.Millisecond, res.ResetAfter)
+}
+
+func TestRateLimiterValidate(t *testing.T) {
+	s := miniredis.RunT(t)
+	option := &Option{Address: []string{s.Addr()}}
+
+	_, err := NewRateLimiter(option, nil)
+	assert.Error(t, err)
+	_, err = NewRateLimiter(option, &ratelimit.Option{Limit: 0, Window: time.Second})
+	assert.Error(t, err)
+	_, err = NewRateLimiter(option, &ratelimit.Option{Limit: 1, Window: time.Microsecond})
+	assert.Error(t, err)
+}
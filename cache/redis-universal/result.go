@@ -0,0 +1,118 @@
+package redis_universal
+
+import (
+	"github.com/Dert12318/Utilities/cache"
+	"github.com/Dert12318/Utilities/encoding"
+	gr "github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+type (
+	result struct {
+		key   interface{}
+		cmd   gr.Cmder
+		err   error
+		state *pipeState
+	}
+
+	statusResult struct {
+		result
+	}
+
+	stringResult struct {
+		result
+		cmd      *gr.StringCmd
+		encoding encoding.Encoding
+	}
+
+	intResult struct {
+		result
+		cmd *gr.IntCmd
+	}
+
+	boolResult struct {
+		result
+		cmd *gr.BoolCmd
+	}
+
+	sliceResult struct {
+		result
+		cmd *gr.SliceCmd
+	}
+
+	stringSliceResult struct {
+		result
+		cmd *gr.StringSliceCmd
+	}
+
+	stringMapResult struct {
+		result
+		cmd *gr.StringStringMapCmd
+	}
+)
+
+func (r result) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if !r.state.executed {
+		return cache.ErrPipeNotExecuted
+	}
+
+	err := r.cmd.Err()
+	if err == gr.Nil {
+		return errors.Wrapf(err, "key %v does not exits", r.key)
+	}
+
+	// go-redis sets the error of the aborted transaction on each of its commands
+	if err == gr.TxFailedErr {
+		return errors.Wrapf(cache.ErrTxFailed, "failed to %s key %v", r.cmd.Name(), r.key)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to %s key %v", r.cmd.Name(), r.key)
+	}
+
+	return nil
+}
+
+func (r *stringResult) Val() string {
+	if r.cmd == nil {
+		return ""
+	}
+
+	return r.cmd.Val()
+}
+
+func (r *stringResult) Scan(object interface{}) error {
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	if err := cache.Unmarshal(r.encoding, []byte(r.cmd.Val()), object); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal object")
+	}
+
+	return nil
+}
+
+func (r *intResult) Val() int64 {
+	return r.cmd.Val()
+}
+
+func (r *boolResult) Val() bool {
+	return r.cmd.Val()
+}
+
+func (r *sliceResult) Val() []interface{} {
+	return r.cmd.Val()
+}
+
+func (r *stringSliceResult) Val() []string {
+	return r.cmd.Val()
+}
+
+func (r *stringMapResult) Val() map[string]string {
+	return r.cmd.Val()
+}
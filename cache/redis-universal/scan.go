@@ -0,0 +1,208 @@
+package redis_universal
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+const (
+	DefaultScanCount = 100
+)
+
+type (
+	// node is a single redis server the keys are scanned from
+	node interface {
+		Process(cmd redis.Cmder) error
+	}
+
+	scanIterator struct {
+		client *redisUniversalClient
+		ctx    *context.Context
+		option cache.ScanOption
+
+		nodes   []node
+		node    int
+		cursor  uint64
+		started bool
+
+		keys []string
+		pos  int
+		key  string
+		err  error
+	}
+)
+
+func (c *redisUniversalClient) Scan(ctx *context.Context, option cache.ScanOption) cache.Iterator {
+	if option.Match == "" {
+		option.Match = "*"
+	}
+
+	if option.Count <= 0 {
+		option.Count = DefaultScanCount
+	}
+
+	it := &scanIterator{client: c, ctx: ctx, option: option}
+	if err := check(c); err != nil {
+		it.err = err
+	}
+
+	return it
+}
+
+func (it *scanIterator) Next() bool {
+	for {
+		if it.pos < len(it.keys) {
+			it.key = it.keys[it.pos]
+			it.pos++
+			return true
+		}
+
+		if it.err != nil {
+			return false
+		}
+
+		if it.nodes == nil {
+			if it.nodes, it.err = it.client.masters(it.ctx); it.err != nil {
+				return false
+			}
+		}
+
+		if it.started && it.cursor == 0 {
+			it.node++
+			it.started = false
+		}
+
+		if it.node >= len(it.nodes) {
+			return false
+		}
+
+		it.keys, it.cursor, it.err = it.scan(it.nodes[it.node])
+		it.pos = 0
+		it.started = true
+	}
+}
+
+func (it *scanIterator) Key() string {
+	return it.key
+}
+
+func (it *scanIterator) Err() error {
+	return it.err
+}
+
+func (it *scanIterator) scan(n node) ([]string, uint64, error) {
+	args := []interface{}{"scan", it.cursor, "match", it.option.Match, "count", it.option.Count}
+	if it.option.Type != "" {
+		args = append(args, "type", it.option.Type)
+	}
+
+	cmd := redis.NewScanCmd(n.Process, args...)
+	if err := it.client.process(it.ctx, "SCAN", it.option.Match, func() error {
+		return n.Process(cmd)
+	}); err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to scan redis pattern %s!", it.option.Match)
+	}
+
+	keys, cursor := cmd.Val()
+	return keys, cursor, nil
+}
+
+// masters returns every master of the cluster, or the client itself when it is not a cluster
+func (c *redisUniversalClient) masters(ctx *context.Context) ([]node, error) {
+	cluster, ok := c.r.(*redis.ClusterClient)
+	if !ok {
+		return []node{c.r}, nil
+	}
+
+	var (
+		mu    sync.Mutex
+		nodes []node
+	)
+	err := c.process(ctx, "CLUSTER NODES", "", func() error {
+		return cluster.ForEachMaster(func(client *redis.Client) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			nodes = append(nodes, client)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list redis cluster masters!")
+	}
+
+	return nodes, nil
+}
+
+func (c *redisUniversalClient) DeleteByPattern(ctx *context.Context, option cache.ScanOption) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	if option.Count <= 0 {
+		option.Count = DefaultScanCount
+	}
+
+	it := c.Scan(ctx, option)
+	batch := make([]string, 0, option.Count)
+	var removed int64
+	for it.Next() {
+		batch = append(batch, it.Key())
+		if int64(len(batch)) < option.Count {
+			continue
+		}
+
+		n, err := c.unlink(ctx, batch)
+		removed += n
+		if err != nil {
+			return removed, errors.Wrapf(err, "failed to remove key with pattern %s", option.Match)
+		}
+		batch = batch[:0]
+	}
+
+	if err := it.Err(); err != nil {
+		return removed, err
+	}
+
+	n, err := c.unlink(ctx, batch)
+	removed += n
+	if err != nil {
+		return removed, errors.Wrapf(err, "failed to remove key with pattern %s", option.Match)
+	}
+
+	return removed, nil
+}
+
+// unlink removes keys with one UNLINK per key in a single pipeline, keys of a batch may live in different
+// cluster slots so they can not be sent in one command.
+func (c *redisUniversalClient) unlink(ctx *context.Context, keys []string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	var cmds []redis.Cmder
+	err := c.process(ctx, "UNLINK", keys[0], func() (err error) {
+		cmds, err = c.r.Pipelined(func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				pipe.Unlink(key)
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for _, cmd := range cmds {
+		n += cmd.(*redis.IntCmd).Val()
+	}
+
+	return n, nil
+}
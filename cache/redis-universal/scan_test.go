@@ -0,0 +1,97 @@
+package redis_universal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/cache"
+	"github.com/Dert12318/Utilities/encoding/jsontier"
+)
+
+// newTestCluster splits the slots between two miniredis masters, miniredis does not implement CLUSTER SLOTS
+func newTestCluster(t *testing.T) ([]*miniredis.Miniredis, *redisUniversalClient) {
+	servers := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t)}
+
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func() ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{
+				{Start: 0, End: 8191, Nodes: []redis.ClusterNode{{Addr: servers[0].Addr()}}},
+				{Start: 8192, End: 16383, Nodes: []redis.ClusterNode{{Addr: servers[1].Addr()}}},
+			}, nil
+		},
+	})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return servers, &redisUniversalClient{r: client, encoding: jsontier.NewEncoding(), channels: make(map[string]cache.PubSub)}
+}
+
+func TestScanClusterMasters(t *testing.T) {
+	ctx := context.Background()
+	servers, c := newTestCluster(t)
+
+	// miniredis answers COMMAND in a format go-redis v6 can not parse so the cluster client routes the keys
+	// at random, the keys are written to the masters directly
+	for i := 0; i < 20; i++ {
+		servers[i%2].Set(fmt.Sprintf("user:%d", i), "1")
+	}
+	servers[0].Set("order:1", "1")
+
+	var keys []string
+	it := c.Scan(&ctx, cache.ScanOption{Match: "user:*", Count: 3})
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	assert.NoError(t, it.Err())
+	assert.Len(t, keys, 20)
+
+	// a key being migrated lives on both masters, Keys returns it once
+	servers[1].Set("user:0", "1")
+	keys, err := c.Keys(&ctx, "user:*")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 20)
+	sort.Strings(keys)
+	assert.Equal(t, "user:0", keys[0])
+	assert.Equal(t, "user:1", keys[1])
+}
+
+func TestDeleteByPattern(t *testing.T) {
+	ctx := context.Background()
+	s := miniredis.RunT(t)
+
+	var commands []string
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	client.WrapProcessPipeline(func(process func(cmds []redis.Cmder) error) func(cmds []redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			for _, cmd := range cmds {
+				commands = append(commands, cmd.Name())
+			}
+			return process(cmds)
+		}
+	})
+	defer client.Close()
+	c := &redisUniversalClient{r: client, encoding: jsontier.NewEncoding(), channels: make(map[string]cache.PubSub)}
+
+	for i := 0; i < 10; i++ {
+		s.Set(fmt.Sprintf("session:%d", i), "1")
+	}
+	s.Set("user:1", "1")
+
+	// the keys are removed in batches of Count while the iteration goes on
+	removed, err := c.DeleteByPattern(&ctx, cache.ScanOption{Match: "session:*", Count: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), removed)
+	assert.Equal(t, []string{"user:1"}, s.Keys())
+
+	removed, err = c.DeleteByPattern(&ctx, cache.ScanOption{Match: "session:*"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), removed)
+
+	assert.Contains(t, commands, "unlink")
+	assert.NotContains(t, commands, "del")
+}
@@ -0,0 +1,108 @@
+package redis_universal
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+func (c *redisUniversalClient) SetNX(ctx *context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if err := check(c); err != nil {
+		return false, err
+	}
+
+	val, err := cache.Marshal(c.codec(ctx), value)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to set cache with key %s!", key)
+	}
+
+	var ok bool
+	if err := c.process(ctx, "SETNX", key, func() (err error) {
+		ok, err = c.r.SetNX(key, val, ttl).Result()
+		return err
+	}); err != nil {
+		return false, errors.Wrapf(err, "failed to set cache with key %s!", key)
+	}
+
+	return ok, nil
+}
+
+func (c *redisUniversalClient) Incr(ctx *context.Context, key string) (int64, error) {
+	return c.IncrBy(ctx, key, 1)
+}
+
+func (c *redisUniversalClient) IncrBy(ctx *context.Context, key string, value int64) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	var val int64
+	if err := c.process(ctx, "INCRBY", key, func() (err error) {
+		val, err = c.r.IncrBy(key, value).Result()
+		return err
+	}); err != nil {
+		return 0, errors.Wrapf(err, "failed to increment key %s!", key)
+	}
+
+	return val, nil
+}
+
+func (c *redisUniversalClient) Expire(ctx *context.Context, key string, ttl time.Duration) (bool, error) {
+	if err := check(c); err != nil {
+		return false, err
+	}
+
+	var ok bool
+	if err := c.process(ctx, "EXPIRE", key, func() (err error) {
+		ok, err = c.r.Expire(key, ttl).Result()
+		return err
+	}); err != nil {
+		return false, errors.Wrapf(err, "failed to expire key %s!", key)
+	}
+
+	return ok, nil
+}
+
+func (c *redisUniversalClient) TTL(ctx *context.Context, key string) (time.Duration, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	var ttl time.Duration
+	if err := c.process(ctx, "PTTL", key, func() (err error) {
+		ttl, err = c.r.PTTL(key).Result()
+		return err
+	}); err != nil {
+		return 0, errors.Wrapf(err, "failed to get ttl of key %s!", key)
+	}
+
+	// PTTL replies -2 for a missing key and -1 for a persistent one, scaled to milliseconds by go-redis
+	switch ttl {
+	case -2 * time.Millisecond:
+		return 0, errors.Wrapf(redis.Nil, "key %s does not exits", key)
+	case -1 * time.Millisecond:
+		return cache.NoExpiration, nil
+	}
+
+	return ttl, nil
+}
+
+func (c *redisUniversalClient) Persist(ctx *context.Context, key string) (bool, error) {
+	if err := check(c); err != nil {
+		return false, err
+	}
+
+	var ok bool
+	if err := c.process(ctx, "PERSIST", key, func() (err error) {
+		ok, err = c.r.Persist(key).Result()
+		return err
+	}); err != nil {
+		return false, errors.Wrapf(err, "failed to persist key %s!", key)
+	}
+
+	return ok, nil
+}
@@ -0,0 +1,58 @@
+package redis_universal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+func TestCounterAndTTL(t *testing.T) {
+	ctx := context.Background()
+	s, c := newTestCache(t)
+
+	ok, err := c.SetNX(&ctx, "lock", "a", 0)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, _ = c.SetNX(&ctx, "lock", "b", 0)
+	assert.False(t, ok)
+
+	n, err := c.Incr(&ctx, "counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, _ = c.IncrBy(&ctx, "counter", 9)
+	assert.Equal(t, int64(10), n)
+	_, err = c.Incr(&ctx, "lock")
+	assert.Error(t, err)
+
+	ttl, err := c.TTL(&ctx, "counter")
+	assert.NoError(t, err)
+	assert.Equal(t, cache.NoExpiration, ttl)
+
+	ok, _ = c.Expire(&ctx, "counter", time.Minute)
+	assert.True(t, ok)
+	ttl, _ = c.TTL(&ctx, "counter")
+	assert.Equal(t, time.Minute, ttl)
+
+	ok, _ = c.Persist(&ctx, "counter")
+	assert.True(t, ok)
+	ttl, _ = c.TTL(&ctx, "counter")
+	assert.Equal(t, cache.NoExpiration, ttl)
+
+	_, err = c.TTL(&ctx, "missing")
+	assert.Equal(t, redis.Nil, errors.Cause(err))
+	ok, _ = c.Expire(&ctx, "missing", time.Minute)
+	assert.False(t, ok)
+
+	// a lock set with a ttl expires and can be set again
+	ok, _ = c.SetNX(&ctx, "lease", "a", time.Second)
+	assert.True(t, ok)
+	s.FastForward(2 * time.Second)
+	ok, _ = c.SetNX(&ctx, "lease", "b", time.Second)
+	assert.True(t, ok)
+}
@@ -0,0 +1,285 @@
+package redis_universal
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+	"github.com/Dert12318/Utilities/logs"
+	"github.com/Dert12318/Utilities/logs/logrus"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+const (
+	DefaultSubscriberHealthCheck      = time.Minute
+	DefaultSubscriberReconnectBackoff = time.Second
+)
+
+type (
+	SubscriberOption struct {
+		// RetryMax is how many times a failed handler is retried before the error handler is dispatched
+		RetryMax int
+		// HealthCheckInterval is how long the connection may stay silent before it is pinged
+		HealthCheckInterval time.Duration
+		// ReconnectBackoff is the delay between attempts to reconnect after the connection is lost
+		ReconnectBackoff time.Duration
+		Log              logs.Logger
+	}
+
+	subscriber struct {
+		client redis.UniversalClient
+		ps     *redis.PubSub
+		option SubscriberOption
+
+		mu       sync.RWMutex
+		channels map[string]messaging.Dispatcher
+		patterns map[string]messaging.Dispatcher
+
+		stop chan struct{}
+		done chan struct{}
+		once sync.Once
+	}
+)
+
+// NewSubscriber returns a cache.Subscriber with its own connection to option.Address,
+// go-redis reconnects and subscribes again to every channel and pattern when the connection is lost.
+func NewSubscriber(option *Option, sub *SubscriberOption) (cache.Subscriber, error) {
+	o := SubscriberOption{
+		HealthCheckInterval: DefaultSubscriberHealthCheck,
+		ReconnectBackoff:    DefaultSubscriberReconnectBackoff,
+		Log:                 logrus.DefaultLog(),
+	}
+
+	if sub != nil {
+		if sub.RetryMax > 0 {
+			o.RetryMax = sub.RetryMax
+		}
+		if sub.HealthCheckInterval > 0 {
+			o.HealthCheckInterval = sub.HealthCheckInterval
+		}
+		if sub.ReconnectBackoff > 0 {
+			o.ReconnectBackoff = sub.ReconnectBackoff
+		}
+		if sub.Log != nil {
+			o.Log = sub.Log
+		}
+	}
+
+	client := newUniversalClient(option, option.Address)
+	if _, err := client.Ping().Result(); err != nil {
+		_ = client.Close()
+		return nil, errors.Wrap(err, "Failed to connect to redis!")
+	}
+
+	s := &subscriber{
+		client:   client,
+		ps:       client.Subscribe(),
+		option:   o,
+		channels: make(map[string]messaging.Dispatcher),
+		patterns: make(map[string]messaging.Dispatcher),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go s.receive()
+
+	return s, nil
+}
+
+func (s *subscriber) Subscribe(channel string, dispatcher messaging.Dispatcher) error {
+	s.mu.Lock()
+	previous, subscribed := s.channels[channel]
+	s.channels[channel] = dispatcher
+	s.mu.Unlock()
+
+	if err := s.ps.Subscribe(channel); err != nil {
+		s.mu.Lock()
+		if subscribed {
+			s.channels[channel] = previous
+		} else {
+			delete(s.channels, channel)
+			// go-redis keeps the failed channel to subscribe to it again once reconnected
+			_ = s.ps.Unsubscribe(channel)
+		}
+		s.mu.Unlock()
+
+		return errors.Wrapf(err, "failed to subscribe to channel %s", channel)
+	}
+
+	return nil
+}
+
+func (s *subscriber) PSubscribe(pattern string, dispatcher messaging.Dispatcher) error {
+	s.mu.Lock()
+	previous, subscribed := s.patterns[pattern]
+	s.patterns[pattern] = dispatcher
+	s.mu.Unlock()
+
+	if err := s.ps.PSubscribe(pattern); err != nil {
+		s.mu.Lock()
+		if subscribed {
+			s.patterns[pattern] = previous
+		} else {
+			delete(s.patterns, pattern)
+			// go-redis keeps the failed pattern to subscribe to it again once reconnected
+			_ = s.ps.PUnsubscribe(pattern)
+		}
+		s.mu.Unlock()
+
+		return errors.Wrapf(err, "failed to subscribe to pattern %s", pattern)
+	}
+
+	return nil
+}
+
+func (s *subscriber) Unsubscribe(channels ...string) error {
+	if err := s.ps.Unsubscribe(channels...); err != nil {
+		return errors.Wrapf(err, "failed to unsubscribe from channels %v", channels)
+	}
+
+	s.mu.Lock()
+	for _, channel := range channels {
+		delete(s.channels, channel)
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *subscriber) PUnsubscribe(patterns ...string) error {
+	if err := s.ps.PUnsubscribe(patterns...); err != nil {
+		return errors.Wrapf(err, "failed to unsubscribe from patterns %v", patterns)
+	}
+
+	s.mu.Lock()
+	for _, pattern := range patterns {
+		delete(s.patterns, pattern)
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *subscriber) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.stop)
+		// closing the pubsub unblocks the pending receive
+		err = s.ps.Close()
+		<-s.done
+
+		if cerr := s.client.Close(); err == nil {
+			err = cerr
+		}
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "failed to close subscriber")
+	}
+
+	return nil
+}
+
+func (s *subscriber) receive() {
+	defer close(s.done)
+
+	lost := false
+	for {
+		msg, err := s.ps.ReceiveTimeout(s.option.HealthCheckInterval)
+		if s.stopped() {
+			return
+		}
+
+		if err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				// a failed ping drops the connection, it is replaced on the next receive
+				err = s.ps.Ping()
+			}
+
+			if err != nil {
+				if !lost {
+					s.option.Log.Error(errors.Wrap(err, "lost connection of subscriber, reconnecting"))
+				}
+				lost = true
+				s.wait()
+			}
+			continue
+		}
+
+		if lost {
+			s.option.Log.Info("subscriber reconnected")
+			lost = false
+		}
+
+		if m, ok := msg.(*redis.Message); ok {
+			s.dispatch(m)
+		}
+	}
+}
+
+// dispatch retries the handler up to RetryMax times before dispatching the error, like the kafka consumer
+func (s *subscriber) dispatch(m *redis.Message) {
+	s.mu.RLock()
+	dispatcher, ok := s.channels[m.Channel]
+	if m.Pattern != "" {
+		dispatcher, ok = s.patterns[m.Pattern]
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	attributes := map[string]string{cache.MessageChannel: m.Channel}
+	if m.Pattern != "" {
+		attributes[cache.MessagePattern] = m.Pattern
+	}
+
+	var err error
+	var message = messaging.DispatchDTO{
+		Type:    messaging.Handle,
+		Source:  fmt.Sprintf("Redis PubSub - %s", m.Channel),
+		Topic:   m.Channel,
+		MsgType: m.Channel,
+		Msg: messaging.Message{
+			MsgID:         uuid.New().String(),
+			MsgData:       []byte(m.Payload),
+			MsgAttributes: attributes,
+		},
+		Log: s.option.Log,
+	}
+	for i := 0; i <= s.option.RetryMax; i++ {
+		if err = dispatcher.Dispatch(message); err == nil {
+			return
+		}
+
+		s.option.Log.Error("error on dispatch message from redis pubsub: ", err.Error())
+	}
+
+	errMessage := message
+	errMessage.Type = messaging.Error
+	errMessage.Err = err
+	_ = dispatcher.Dispatch(errMessage)
+}
+
+func (s *subscriber) wait() {
+	select {
+	case <-s.stop:
+	case <-time.After(s.option.ReconnectBackoff):
+	}
+}
+
+func (s *subscriber) stopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
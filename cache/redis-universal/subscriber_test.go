@@ -0,0 +1,135 @@
+package redis_universal
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/cache"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+type received struct {
+	mu       sync.Mutex
+	messages []messaging.Message
+	errs     []error
+}
+
+func (r *received) dispatcher(fail int) messaging.Dispatcher {
+	d := messaging.NewSingleEventDispatcher()
+	d.AddHandler(func(ctx *tntContext.Context, msg messaging.Message) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.messages = append(r.messages, msg)
+		if fail > 0 {
+			fail--
+			return errors.New("handler failed")
+		}
+		return nil
+	}, func(ctx *tntContext.Context, msg messaging.Message, err error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.errs = append(r.errs, err)
+	})
+	return d
+}
+
+func (r *received) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.messages)
+}
+
+func TestSubscriber(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	sub, err := NewSubscriber(&Option{Address: []string{s.Addr()}}, &SubscriberOption{RetryMax: 1})
+	assert.NoError(t, err)
+	defer sub.Close()
+
+	channel, pattern := &received{}, &received{}
+	assert.NoError(t, sub.Subscribe("orders", channel.dispatcher(0)))
+	assert.NoError(t, sub.PSubscribe("users.*", pattern.dispatcher(2)))
+
+	assert.Eventually(t, func() bool {
+		return len(s.PubSubChannels("")) == 1 && s.PubSubNumPat() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	s.Publish("orders", "created")
+	s.Publish("users.1", "updated")
+
+	assert.Eventually(t, func() bool { return channel.count() == 1 && pattern.count() == 2 }, time.Second, 10*time.Millisecond)
+
+	msg := channel.messages[0]
+	assert.Equal(t, "created", string(msg.MsgData))
+	assert.Equal(t, map[string]string{cache.MessageChannel: "orders"}, msg.MsgAttributes)
+
+	msg = pattern.messages[0]
+	assert.Equal(t, "updated", string(msg.MsgData))
+	assert.Equal(t, map[string]string{cache.MessageChannel: "users.1", cache.MessagePattern: "users.*"}, msg.MsgAttributes)
+
+	pattern.mu.Lock()
+	assert.Len(t, pattern.errs, 1)
+	pattern.mu.Unlock()
+
+	assert.NoError(t, sub.Unsubscribe("orders"))
+	assert.Eventually(t, func() bool { return len(s.PubSubChannels("")) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestSubscriberReconnect(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	sub, err := NewSubscriber(&Option{Address: []string{s.Addr()}}, &SubscriberOption{
+		HealthCheckInterval: 20 * time.Millisecond,
+		ReconnectBackoff:    10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	defer sub.Close()
+
+	r := &received{}
+	assert.NoError(t, sub.PSubscribe("orders.*", r.dispatcher(0)))
+
+	s.Close()
+	assert.NoError(t, s.Restart())
+
+	// messages published before the subscription is restored are lost
+	assert.Eventually(t, func() bool {
+		s.Publish("orders.1", "created")
+		return r.count() > 0
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestSubscribeFailureKeepsNoDispatcher(t *testing.T) {
+	s := miniredis.RunT(t)
+	addr := s.Addr()
+	s.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+
+	r := &received{}
+	previous := r.dispatcher(0)
+	sub := &subscriber{
+		client:   client,
+		ps:       client.Subscribe(),
+		channels: map[string]messaging.Dispatcher{"users": previous},
+		patterns: make(map[string]messaging.Dispatcher),
+	}
+	defer sub.ps.Close()
+
+	assert.Error(t, sub.Subscribe("orders", r.dispatcher(0)))
+	assert.Error(t, sub.PSubscribe("orders.*", r.dispatcher(0)))
+	assert.Error(t, sub.Subscribe("users", r.dispatcher(0)))
+
+	assert.Equal(t, map[string]messaging.Dispatcher{"users": previous}, sub.channels)
+	assert.Empty(t, sub.patterns)
+}
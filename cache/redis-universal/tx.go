@@ -0,0 +1,70 @@
+package redis_universal
+
+import (
+	"github.com/Dert12318/Utilities/cache"
+	"github.com/Dert12318/Utilities/encoding"
+	gr "github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+type (
+	tx struct {
+		tx       *gr.Tx
+		encoding encoding.Encoding
+	}
+)
+
+func (t *tx) Get(key string, object interface{}) error {
+	val, err := t.tx.Get(key).Result()
+
+	if err == gr.Nil {
+		return errors.Wrapf(err, "key %s does not exits", key)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if err := cache.Unmarshal(t.encoding, []byte(val), object); err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return nil
+}
+
+func (t *tx) HGet(key, field string, object interface{}) error {
+	val, err := t.tx.HGet(key, field).Result()
+
+	if err == gr.Nil {
+		return errors.Wrapf(err, "key %s does not exits", key)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if err := cache.Unmarshal(t.encoding, []byte(val), object); err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return nil
+}
+
+func (t *tx) Pipelined(fn func(pipe cache.Pipe) error) error {
+	var (
+		p     *pipe
+		fnErr error
+	)
+	_, err := t.tx.Pipelined(func(instance gr.Pipeliner) error {
+		p = newPipe(instance, t.encoding)
+		fnErr = fn(p)
+		return fnErr
+	})
+
+	if fnErr != nil {
+		return fnErr
+	}
+
+	p.state.executed = true
+	return execError(err)
+}
@@ -0,0 +1,271 @@
+package redis_universal
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"strconv"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+func (c *redisUniversalClient) ZAdd(ctx *context.Context, key string, members ...cache.Z) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	zs := make([]redis.Z, 0, len(members))
+	for _, member := range members {
+		m, err := cache.Marshal(c.codec(ctx), member.Member)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to add to sorted set with key %s!", key)
+		}
+		zs = append(zs, redis.Z{Score: member.Score, Member: m})
+	}
+
+	var n int64
+	if err := c.process(ctx, "ZADD", key, func() (err error) {
+		n, err = c.r.ZAdd(key, zs...).Result()
+		return err
+	}); err != nil {
+		return 0, errors.Wrapf(err, "failed to add to sorted set with key %s!", key)
+	}
+
+	return n, nil
+}
+
+func (c *redisUniversalClient) ZIncrBy(ctx *context.Context, key string, increment float64, member interface{}) (float64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	m, err := c.member(ctx, member)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to increment sorted set with key %s!", key)
+	}
+
+	var score float64
+	if err := c.process(ctx, "ZINCRBY", key, func() (err error) {
+		score, err = c.r.ZIncrBy(key, increment, m).Result()
+		return err
+	}); err != nil {
+		return 0, errors.Wrapf(err, "failed to increment sorted set with key %s!", key)
+	}
+
+	return score, nil
+}
+
+func (c *redisUniversalClient) ZScore(ctx *context.Context, key string, member interface{}) (float64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	m, err := c.member(ctx, member)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	var score float64
+	err = c.process(ctx, "ZSCORE", key, func() (err error) {
+		score, err = c.r.ZScore(key, m).Result()
+		return err
+	})
+
+	if err == redis.Nil {
+		return 0, errors.Wrapf(err, "key %s does not exits", key)
+	}
+
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return score, nil
+}
+
+func (c *redisUniversalClient) ZRank(ctx *context.Context, key string, member interface{}) (int64, error) {
+	return c.rank(ctx, "ZRANK", key, member, c.r.ZRank)
+}
+
+func (c *redisUniversalClient) ZRevRank(ctx *context.Context, key string, member interface{}) (int64, error) {
+	return c.rank(ctx, "ZREVRANK", key, member, c.r.ZRevRank)
+}
+
+func (c *redisUniversalClient) rank(ctx *context.Context, operation, key string, member interface{}, cmd func(key, member string) *redis.IntCmd) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	m, err := c.member(ctx, member)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	var rank int64
+	err = c.process(ctx, operation, key, func() (err error) {
+		rank, err = cmd(key, m).Result()
+		return err
+	})
+
+	if err == redis.Nil {
+		return 0, errors.Wrapf(err, "key %s does not exits", key)
+	}
+
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return rank, nil
+}
+
+func (c *redisUniversalClient) ZRange(ctx *context.Context, key string, start, stop int64) ([]string, error) {
+	return c.zrange(ctx, "ZRANGE", key, func() ([]string, error) {
+		return c.r.ZRange(key, start, stop).Result()
+	})
+}
+
+func (c *redisUniversalClient) ZRevRange(ctx *context.Context, key string, start, stop int64) ([]string, error) {
+	return c.zrange(ctx, "ZREVRANGE", key, func() ([]string, error) {
+		return c.r.ZRevRange(key, start, stop).Result()
+	})
+}
+
+func (c *redisUniversalClient) ZRangeByScore(ctx *context.Context, key string, opt cache.ZRangeBy) ([]string, error) {
+	return c.zrange(ctx, "ZRANGEBYSCORE", key, func() ([]string, error) {
+		return c.r.ZRangeByScore(key, redis.ZRangeBy(opt)).Result()
+	})
+}
+
+func (c *redisUniversalClient) zrange(ctx *context.Context, operation, key string, cmd func() ([]string, error)) ([]string, error) {
+	if err := check(c); err != nil {
+		return nil, err
+	}
+
+	var val []string
+	if err := c.process(ctx, operation, key, func() (err error) {
+		val, err = cmd()
+		return err
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return val, nil
+}
+
+func (c *redisUniversalClient) ZRangeWithScores(ctx *context.Context, key string, start, stop int64) ([]cache.Z, error) {
+	return c.zrangeWithScores(ctx, "ZRANGE", key, func() ([]redis.Z, error) {
+		return c.r.ZRangeWithScores(key, start, stop).Result()
+	})
+}
+
+func (c *redisUniversalClient) ZRevRangeWithScores(ctx *context.Context, key string, start, stop int64) ([]cache.Z, error) {
+	return c.zrangeWithScores(ctx, "ZREVRANGE", key, func() ([]redis.Z, error) {
+		return c.r.ZRevRangeWithScores(key, start, stop).Result()
+	})
+}
+
+func (c *redisUniversalClient) zrangeWithScores(ctx *context.Context, operation, key string, cmd func() ([]redis.Z, error)) ([]cache.Z, error) {
+	if err := check(c); err != nil {
+		return nil, err
+	}
+
+	var zs []redis.Z
+	if err := c.process(ctx, operation, key, func() (err error) {
+		zs, err = cmd()
+		return err
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	val := make([]cache.Z, 0, len(zs))
+	for _, z := range zs {
+		val = append(val, cache.Z{Score: z.Score, Member: z.Member})
+	}
+
+	return val, nil
+}
+
+func (c *redisUniversalClient) ZRem(ctx *context.Context, key string, members ...interface{}) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	vals, err := c.marshalAll(ctx, members)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to remove from sorted set with key %s!", key)
+	}
+
+	var n int64
+	if err := c.process(ctx, "ZREM", key, func() (err error) {
+		n, err = c.r.ZRem(key, vals...).Result()
+		return err
+	}); err != nil {
+		return 0, errors.Wrapf(err, "failed to remove from sorted set with key %s!", key)
+	}
+
+	return n, nil
+}
+
+func (c *redisUniversalClient) ZRemRangeByScore(ctx *context.Context, key string, min, max string) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	if err := c.process(ctx, "ZREMRANGEBYSCORE", key, func() (err error) {
+		n, err = c.r.ZRemRangeByScore(key, min, max).Result()
+		return err
+	}); err != nil {
+		return 0, errors.Wrapf(err, "failed to remove from sorted set with key %s!", key)
+	}
+
+	return n, nil
+}
+
+func (c *redisUniversalClient) ZCard(ctx *context.Context, key string) (int64, error) {
+	if err := check(c); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	if err := c.process(ctx, "ZCARD", key, func() (err error) {
+		n, err = c.r.ZCard(key).Result()
+		return err
+	}); err != nil {
+		return 0, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return n, nil
+}
+
+// member marshals member and converts it the same way go-redis writes command arguments,
+// ZSCORE, ZRANK and ZINCRBY take the member as a string in go-redis v6
+func (c *redisUniversalClient) member(ctx *context.Context, member interface{}) (string, error) {
+	m, err := cache.Marshal(c.codec(ctx), member)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := m.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 64), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case encoding.BinaryMarshaler:
+		b, err := v.MarshalBinary()
+		return string(b), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
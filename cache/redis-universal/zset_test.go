@@ -0,0 +1,54 @@
+package redis_universal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+func TestSortedSet(t *testing.T) {
+	ctx := context.Background()
+	_, c := newTestCache(t)
+
+	n, err := c.ZAdd(&ctx, "board", cache.Z{Score: 10, Member: "alice"}, cache.Z{Score: 5, Member: "bob"}, cache.Z{Score: 7, Member: "carol"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	score, err := c.ZIncrBy(&ctx, "board", 6, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(11), score)
+
+	score, err = c.ZScore(&ctx, "board", "carol")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(7), score)
+	_, err = c.ZScore(&ctx, "board", "dave")
+	assert.Equal(t, redis.Nil, errors.Cause(err))
+
+	val, _ := c.ZRevRange(&ctx, "board", 0, 1)
+	assert.Equal(t, []string{"bob", "alice"}, val)
+
+	zs, _ := c.ZRangeWithScores(&ctx, "board", 0, 0)
+	assert.Equal(t, []cache.Z{{Score: 7, Member: "carol"}}, zs)
+
+	rank, err := c.ZRevRank(&ctx, "board", "carol")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), rank)
+	_, err = c.ZRank(&ctx, "board", "dave")
+	assert.Equal(t, redis.Nil, errors.Cause(err))
+
+	val, _ = c.ZRangeByScore(&ctx, "board", cache.ZRangeBy{Min: "(7", Max: "+inf"})
+	assert.Equal(t, []string{"alice", "bob"}, val)
+
+	n, _ = c.ZRemRangeByScore(&ctx, "board", "-inf", "10")
+	assert.Equal(t, int64(2), n)
+	n, _ = c.ZRem(&ctx, "board", "bob")
+	assert.Equal(t, int64(1), n)
+
+	n, _ = c.ZCard(&ctx, "board")
+	assert.Equal(t, int64(0), n)
+}
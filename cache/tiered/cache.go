@@ -0,0 +1,315 @@
+package tiered
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+	"github.com/Dert12318/Utilities/encoding"
+	"github.com/Dert12318/Utilities/encoding/jsontier"
+	"github.com/Dert12318/Utilities/logs"
+	"github.com/Dert12318/Utilities/logs/logrus"
+)
+
+const (
+	DefaultSize    = 10000
+	DefaultTTL     = time.Minute
+	DefaultChannel = "cache:invalidation"
+)
+
+type (
+	Option struct {
+		// Size is the maximum number of keys kept in the local layer
+		Size int
+		// TTL bounds how long a key may live in the local layer when an invalidation is missed
+		TTL time.Duration
+		// Channel is the pub/sub channel invalidations are broadcast on, every instance must use the same one
+		Channel string
+		// Encoding must match the encoding of the remote cache, defaults to jsontier
+		Encoding encoding.Encoding
+		Log      logs.Logger
+	}
+
+	tieredCache struct {
+		cache.Cache
+		local    *lru
+		pubsub   cache.PubSub
+		encoding encoding.Encoding
+		json     encoding.Encoding
+		log      logs.Logger
+	}
+
+	invalidation struct {
+		Keys    []string `json:"keys,omitempty"`
+		Pattern string   `json:"pattern,omitempty"`
+		All     bool     `json:"all,omitempty"`
+	}
+)
+
+// New wraps remote with a bounded in-process LRU layer for Get and HGet. Writes made through any
+// instance are broadcast on Option.Channel so every instance drops its stale local entries.
+func New(remote cache.Cache, option *Option) (cache.Cache, error) {
+	o := Option{
+		Size:     DefaultSize,
+		TTL:      DefaultTTL,
+		Channel:  DefaultChannel,
+		Encoding: jsontier.NewEncoding(),
+		Log:      logrus.DefaultLog(),
+	}
+
+	if option != nil {
+		if option.Size > 0 {
+			o.Size = option.Size
+		}
+		if option.TTL > 0 {
+			o.TTL = option.TTL
+		}
+		if option.Channel != "" {
+			o.Channel = option.Channel
+		}
+		if option.Encoding != nil {
+			o.Encoding = option.Encoding
+		}
+		if option.Log != nil {
+			o.Log = option.Log
+		}
+	}
+
+	ps, err := remote.Subscribe(o.Channel)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to subscribe to invalidation channel %s", o.Channel)
+	}
+
+	if err := ps.Receive(); err != nil {
+		return nil, errors.Wrapf(err, "failed to subscribe to invalidation channel %s", o.Channel)
+	}
+
+	c := &tieredCache{
+		Cache:    remote,
+		local:    newLRU(o.Size, o.TTL),
+		pubsub:   ps,
+		encoding: o.Encoding,
+		json:     jsontier.NewEncoding(),
+		log:      o.Log,
+	}
+
+	go c.listen()
+
+	return c, nil
+}
+
+func (c *tieredCache) Get(ctx *context.Context, key string, data interface{}) error {
+	codec := cache.EncodingFromContext(ctx, c.encoding)
+
+	if val, ok := c.local.get(key); ok {
+		return cache.Unmarshal(codec, val, data)
+	}
+
+	gen := c.local.generation()
+
+	var val []byte
+	if err := c.Cache.Get(ctx, key, &val); err != nil {
+		return err
+	}
+
+	if ttl, ok := c.remaining(ctx, key); ok {
+		c.local.set(key, val, ttl, gen)
+	}
+
+	if err := cache.Unmarshal(codec, val, data); err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *tieredCache) HGet(ctx *context.Context, key, field string, response interface{}) error {
+	codec := cache.EncodingFromContext(ctx, c.encoding)
+
+	if val, ok := c.local.getField(key, field); ok {
+		return cache.Unmarshal(codec, val, response)
+	}
+
+	gen := c.local.generation()
+
+	var val []byte
+	if err := c.Cache.HGet(ctx, key, field, &val); err != nil {
+		return err
+	}
+
+	if ttl, ok := c.remaining(ctx, key); ok {
+		c.local.setField(key, field, val, ttl, gen)
+	}
+
+	if err := cache.Unmarshal(codec, val, response); err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return nil
+}
+
+// remaining returns the TTL left on the remote key, NoExpiration for a persistent one. A key that expired or
+// whose TTL can not be read is not kept locally.
+func (c *tieredCache) remaining(ctx *context.Context, key string) (time.Duration, bool) {
+	ttl, err := c.Cache.TTL(ctx, key)
+	if err != nil {
+		return 0, false
+	}
+	return ttl, true
+}
+
+func (c *tieredCache) SetWithExpiration(ctx *context.Context, key string, value interface{}, duration time.Duration) error {
+	defer c.invalidate(invalidation{Keys: []string{key}})
+	return c.Cache.SetWithExpiration(ctx, key, value, duration)
+}
+
+func (c *tieredCache) Set(ctx *context.Context, key string, value interface{}) error {
+	defer c.invalidate(invalidation{Keys: []string{key}})
+	return c.Cache.Set(ctx, key, value)
+}
+
+func (c *tieredCache) HMSetWithExpiration(ctx *context.Context, key string, value map[string]interface{}, ttl time.Duration) error {
+	defer c.invalidate(invalidation{Keys: []string{key}})
+	return c.Cache.HMSetWithExpiration(ctx, key, value, ttl)
+}
+
+func (c *tieredCache) HMSet(ctx *context.Context, key string, value map[string]interface{}) error {
+	defer c.invalidate(invalidation{Keys: []string{key}})
+	return c.Cache.HMSet(ctx, key, value)
+}
+
+func (c *tieredCache) HSetWithExpiration(ctx *context.Context, key string, field string, value interface{}, ttl time.Duration) error {
+	defer c.invalidate(invalidation{Keys: []string{key}})
+	return c.Cache.HSetWithExpiration(ctx, key, field, value, ttl)
+}
+
+func (c *tieredCache) HSet(ctx *context.Context, key string, field string, value interface{}) error {
+	defer c.invalidate(invalidation{Keys: []string{key}})
+	return c.Cache.HSet(ctx, key, field, value)
+}
+
+func (c *tieredCache) SetNX(ctx *context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	defer c.invalidate(invalidation{Keys: []string{key}})
+	return c.Cache.SetNX(ctx, key, value, ttl)
+}
+
+func (c *tieredCache) Incr(ctx *context.Context, key string) (int64, error) {
+	defer c.invalidate(invalidation{Keys: []string{key}})
+	return c.Cache.Incr(ctx, key)
+}
+
+func (c *tieredCache) IncrBy(ctx *context.Context, key string, value int64) (int64, error) {
+	defer c.invalidate(invalidation{Keys: []string{key}})
+	return c.Cache.IncrBy(ctx, key, value)
+}
+
+func (c *tieredCache) Expire(ctx *context.Context, key string, ttl time.Duration) (bool, error) {
+	defer c.invalidate(invalidation{Keys: []string{key}})
+	return c.Cache.Expire(ctx, key, ttl)
+}
+
+func (c *tieredCache) Persist(ctx *context.Context, key string) (bool, error) {
+	defer c.invalidate(invalidation{Keys: []string{key}})
+	return c.Cache.Persist(ctx, key)
+}
+
+func (c *tieredCache) Remove(ctx *context.Context, key string) error {
+	defer c.invalidate(invalidation{Keys: []string{key}})
+	return c.Cache.Remove(ctx, key)
+}
+
+func (c *tieredCache) RemoveByPattern(ctx *context.Context, pattern string, countPerLoop int64) error {
+	defer c.invalidate(invalidation{Pattern: pattern})
+	return c.Cache.RemoveByPattern(ctx, pattern, countPerLoop)
+}
+
+func (c *tieredCache) DeleteByPattern(ctx *context.Context, option cache.ScanOption) (int64, error) {
+	pattern := option.Match
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	defer c.invalidate(invalidation{Pattern: pattern})
+	return c.Cache.DeleteByPattern(ctx, option)
+}
+
+func (c *tieredCache) FlushDatabase(ctx *context.Context) error {
+	defer c.invalidate(invalidation{All: true})
+	return c.Cache.FlushDatabase(ctx)
+}
+
+func (c *tieredCache) FlushAll(ctx *context.Context) error {
+	defer c.invalidate(invalidation{All: true})
+	return c.Cache.FlushAll(ctx)
+}
+
+func (c *tieredCache) Close() error {
+	if err := c.pubsub.Close(); err != nil {
+		return errors.Wrap(err, "failed to close invalidation subscription")
+	}
+
+	return c.Cache.Close()
+}
+
+func (c *tieredCache) Client() cache.Cache {
+	return c
+}
+
+func (c *tieredCache) Pipeline() cache.Pipe {
+	return &pipe{Pipe: c.Cache.Pipeline(), cache: c}
+}
+
+func (c *tieredCache) TxPipeline() cache.Pipe {
+	return &pipe{Pipe: c.Cache.TxPipeline(), cache: c}
+}
+
+func (c *tieredCache) Watch(ctx *context.Context, fn func(tx cache.Tx) error, keys ...string) error {
+	return c.Cache.Watch(ctx, func(t cache.Tx) error {
+		return fn(&tx{Tx: t, cache: c})
+	}, keys...)
+}
+
+// invalidate drops the entries locally and broadcasts the invalidation to the other instances.
+// It runs even when the remote write failed since the remote state is unknown at that point.
+func (c *tieredCache) invalidate(msg invalidation) {
+	c.apply(msg)
+
+	data, err := c.json.Marshal(msg)
+	if err != nil {
+		c.log.Error(errors.Wrap(err, "failed to marshal cache invalidation"))
+		return
+	}
+
+	if err := c.pubsub.Publish(string(data)); err != nil {
+		c.log.Error(errors.Wrap(err, "failed to publish cache invalidation"))
+	}
+}
+
+func (c *tieredCache) apply(msg invalidation) {
+	if msg.All {
+		c.local.purge()
+		return
+	}
+
+	if msg.Pattern != "" {
+		c.local.removePattern(msg.Pattern)
+	}
+
+	if len(msg.Keys) > 0 {
+		c.local.remove(msg.Keys...)
+	}
+}
+
+func (c *tieredCache) listen() {
+	for m := range c.pubsub.Channel() {
+		var msg invalidation
+		if err := c.json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+			c.log.Error(errors.Wrapf(err, "failed to unmarshal cache invalidation %s", m.Payload))
+			continue
+		}
+
+		c.apply(msg)
+	}
+}
@@ -0,0 +1,81 @@
+package tiered
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/cache/memory"
+	helperTime "github.com/Dert12318/Utilities/helper/time"
+)
+
+func TestGet(t *testing.T) {
+	ctx := context.Background()
+	remote, _ := memory.New()
+	c, err := New(remote, nil)
+	assert.NoError(t, err)
+	defer c.Close()
+
+	assert.NoError(t, c.Set(&ctx, "a", "1"))
+
+	var got string
+	assert.NoError(t, c.Get(&ctx, "a", &got))
+	assert.Equal(t, "1", got)
+
+	// written by another instance without going through this one, served from the local layer
+	assert.NoError(t, remote.Set(&ctx, "a", "2"))
+	assert.NoError(t, c.Get(&ctx, "a", &got))
+	assert.Equal(t, "1", got)
+
+	ps, _ := remote.Subscribe(DefaultChannel)
+	assert.NoError(t, ps.Publish(`{"keys":["a"]}`))
+
+	assert.Eventually(t, func() bool {
+		return c.Get(&ctx, "a", &got) == nil && got == "2"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWriteInvalidatesLocal(t *testing.T) {
+	ctx := context.Background()
+	remote, _ := memory.New()
+	c, _ := New(remote, &Option{Size: 1})
+	defer c.Close()
+
+	assert.NoError(t, c.HSet(&ctx, "h", "f", "1"))
+
+	var got string
+	assert.NoError(t, c.HGet(&ctx, "h", "f", &got))
+	assert.Equal(t, "1", got)
+
+	assert.NoError(t, c.HSet(&ctx, "h", "f", "2"))
+	assert.NoError(t, c.HGet(&ctx, "h", "f", &got))
+	assert.Equal(t, "2", got)
+
+	assert.NoError(t, c.RemoveByPattern(&ctx, "h*", 10))
+	assert.Error(t, c.HGet(&ctx, "h", "f", &got))
+}
+
+func TestLocalExpiresWithRemoteKey(t *testing.T) {
+	now := time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC)
+	helperTime.Mock(now)
+	defer helperTime.ResetMock()
+
+	ctx := context.Background()
+	remote, _ := memory.New()
+	c, _ := New(remote, &Option{TTL: time.Hour})
+	defer c.Close()
+
+	assert.NoError(t, c.SetWithExpiration(&ctx, "a", "1", time.Second))
+	assert.NoError(t, c.HSetWithExpiration(&ctx, "h", "f", "1", time.Second))
+
+	var got string
+	assert.NoError(t, c.Get(&ctx, "a", &got))
+	assert.NoError(t, c.HGet(&ctx, "h", "f", &got))
+
+	// the local layer would keep the keys for an hour
+	helperTime.Mock(now.Add(2 * time.Second))
+	assert.Error(t, c.Get(&ctx, "a", &got))
+	assert.Error(t, c.HGet(&ctx, "h", "f", &got))
+}
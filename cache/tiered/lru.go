@@ -0,0 +1,180 @@
+package tiered
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/Dert12318/Utilities/cache"
+	helperTime "github.com/Dert12318/Utilities/helper/time"
+)
+
+type (
+	lru struct {
+		mu    sync.Mutex
+		size  int
+		ttl   time.Duration
+		items map[string]*list.Element
+		order *list.List
+		// gen is bumped on every invalidation so a read that raced with a write does not store a stale value
+		gen uint64
+	}
+
+	localEntry struct {
+		key       string
+		value     []byte
+		hasValue  bool
+		fields    map[string][]byte
+		expiredAt time.Time
+	}
+)
+
+func newLRU(size int, ttl time.Duration) *lru {
+	return &lru{
+		size:  size,
+		ttl:   ttl,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+func (l *lru) get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.lookup(key)
+	if e == nil || !e.hasValue {
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+func (l *lru) getField(key, field string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.lookup(key)
+	if e == nil {
+		return nil, false
+	}
+
+	val, ok := e.fields[field]
+	return val, ok
+}
+
+func (l *lru) generation() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.gen
+}
+
+func (l *lru) set(key string, value []byte, ttl time.Duration, gen uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if gen != l.gen {
+		return
+	}
+
+	e := l.lookupOrCreate(key, ttl)
+	e.value = value
+	e.hasValue = true
+}
+
+func (l *lru) setField(key, field string, value []byte, ttl time.Duration, gen uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if gen != l.gen {
+		return
+	}
+
+	e := l.lookupOrCreate(key, ttl)
+	e.fields[field] = value
+}
+
+func (l *lru) remove(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.gen++
+	for _, key := range keys {
+		if el, ok := l.items[key]; ok {
+			l.removeElement(el)
+		}
+	}
+}
+
+func (l *lru) removePattern(pattern string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.gen++
+	for key, el := range l.items {
+		if cache.Match(pattern, key) {
+			l.removeElement(el)
+		}
+	}
+}
+
+func (l *lru) purge() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.gen++
+	l.items = make(map[string]*list.Element)
+	l.order.Init()
+}
+
+// lookup returns the live entry for key and marks it as recently used. The caller must hold the lock.
+func (l *lru) lookup(key string) *localEntry {
+	el, ok := l.items[key]
+	if !ok {
+		return nil
+	}
+
+	e := el.Value.(*localEntry)
+	if !helperTime.Now().Before(e.expiredAt) {
+		l.removeElement(el)
+		return nil
+	}
+
+	l.order.MoveToFront(el)
+	return e
+}
+
+// lookupOrCreate is lookup for writers, evicting the least recently used entry when full. The entry expires
+// after ttl, the remaining TTL of the remote key, when it is shorter than the TTL of the layer.
+// The caller must hold the lock.
+func (l *lru) lookupOrCreate(key string, ttl time.Duration) *localEntry {
+	expiredAt := helperTime.Now().Add(l.ttl)
+	if ttl > 0 && ttl < l.ttl {
+		expiredAt = helperTime.Now().Add(ttl)
+	}
+
+	if e := l.lookup(key); e != nil {
+		if expiredAt.Before(e.expiredAt) {
+			e.expiredAt = expiredAt
+		}
+		return e
+	}
+
+	for l.order.Len() >= l.size {
+		l.removeElement(l.order.Back())
+	}
+
+	e := &localEntry{
+		key:       key,
+		fields:    make(map[string][]byte),
+		expiredAt: expiredAt,
+	}
+	l.items[key] = l.order.PushFront(e)
+	return e
+}
+
+func (l *lru) removeElement(el *list.Element) {
+	l.order.Remove(el)
+	delete(l.items, el.Value.(*localEntry).key)
+}
@@ -0,0 +1,110 @@
+package tiered
+
+import (
+	"time"
+
+	"github.com/Dert12318/Utilities/cache"
+)
+
+type (
+	// pipe remembers the keys written through it and invalidates them once executed
+	pipe struct {
+		cache.Pipe
+		cache *tieredCache
+		keys  []string
+	}
+)
+
+func (p *pipe) Set(key string, value interface{}) cache.StatusResult {
+	return p.SetWithExpiration(key, value, 0)
+}
+
+func (p *pipe) SetWithExpiration(key string, value interface{}, expired time.Duration) cache.StatusResult {
+	p.keys = append(p.keys, key)
+	return p.Pipe.SetWithExpiration(key, value, expired)
+}
+
+func (p *pipe) Del(keys ...string) cache.IntResult {
+	p.keys = append(p.keys, keys...)
+	return p.Pipe.Del(keys...)
+}
+
+func (p *pipe) Expire(key string, expiration time.Duration) cache.BoolResult {
+	p.keys = append(p.keys, key)
+	return p.Pipe.Expire(key, expiration)
+}
+
+func (p *pipe) Incr(key string) cache.IntResult {
+	p.keys = append(p.keys, key)
+	return p.Pipe.Incr(key)
+}
+
+func (p *pipe) IncrBy(key string, value int64) cache.IntResult {
+	p.keys = append(p.keys, key)
+	return p.Pipe.IncrBy(key, value)
+}
+
+func (p *pipe) HSet(key, field string, value interface{}) cache.BoolResult {
+	p.keys = append(p.keys, key)
+	return p.Pipe.HSet(key, field, value)
+}
+
+func (p *pipe) HMSet(key string, value map[string]interface{}) cache.StatusResult {
+	p.keys = append(p.keys, key)
+	return p.Pipe.HMSet(key, value)
+}
+
+func (p *pipe) HDel(key string, fields ...string) cache.IntResult {
+	p.keys = append(p.keys, key)
+	return p.Pipe.HDel(key, fields...)
+}
+
+func (p *pipe) SAdd(key string, members ...interface{}) cache.IntResult {
+	p.keys = append(p.keys, key)
+	return p.Pipe.SAdd(key, members...)
+}
+
+func (p *pipe) SRem(key string, members ...interface{}) cache.IntResult {
+	p.keys = append(p.keys, key)
+	return p.Pipe.SRem(key, members...)
+}
+
+func (p *pipe) Exec() error {
+	keys := p.keys
+	p.keys = nil
+
+	if len(keys) > 0 {
+		defer p.cache.invalidate(invalidation{Keys: keys})
+	}
+
+	return p.Pipe.Exec()
+}
+
+func (p *pipe) Discard() error {
+	p.keys = nil
+	return p.Pipe.Discard()
+}
+
+type (
+	// tx hands out tracking pipes so the writes of a transaction are invalidated as well
+	tx struct {
+		cache.Tx
+		cache *tieredCache
+	}
+)
+
+func (t *tx) Pipelined(fn func(pipe cache.Pipe) error) error {
+	var keys []string
+	err := t.Tx.Pipelined(func(p cache.Pipe) error {
+		tp := &pipe{Pipe: p, cache: t.cache}
+		err := fn(tp)
+		keys = tp.keys
+		return err
+	})
+
+	if len(keys) > 0 {
+		t.cache.invalidate(invalidation{Keys: keys})
+	}
+
+	return err
+}
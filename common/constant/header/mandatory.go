@@ -1,6 +1,37 @@
 package header
 
+import "strings"
+
 const (
 	MessagingRequestID     = "requestId"
 	MessagingAuthorization = "authorization"
+	// MessagingMessageID carries the MsgID of a kafka message whose key is its partition key
+	MessagingMessageID = "messageId"
+
+	// set on the messages forwarded to a retry or dead-letter topic
+	MessagingOriginalTopic     = "originalTopic"
+	MessagingOriginalPartition = "originalPartition"
+	MessagingOriginalOffset    = "originalOffset"
+	MessagingAttempt           = "attempt"
+	MessagingFailureReason     = "failureReason"
+
+	HttpRequestID     = "X-Request-Id"
+	HttpAuthorization = "Authorization"
 )
+
+// IsCredential reports whether key is an attribute or header carrying credentials,
+// such values must never be logged or sent to the APM
+func IsCredential(key string) bool {
+	return strings.EqualFold(key, MessagingAuthorization) || strings.EqualFold(key, HttpAuthorization)
+}
+
+// WithoutCredentials copies attributes without the keys IsCredential reports, to log them or send them to the APM
+func WithoutCredentials(attributes map[string]string) map[string]string {
+	recorded := make(map[string]string, len(attributes))
+	for key, attr := range attributes {
+		if !IsCredential(key) {
+			recorded[key] = attr
+		}
+	}
+	return recorded
+}
@@ -8,6 +8,10 @@ import (
 	"github.com/Dert12318/Utilities/apm"
 )
 
+// detached creates the echo contexts of the Contexts that are not built from a request,
+// echo.New allocates a whole router so it is shared
+var detached = echo.New()
+
 type (
 	Context struct {
 		ec          echo.Context
@@ -24,7 +28,7 @@ func New() *Context {
 }
 
 func NewWithContext(ctx context.Context) *Context {
-	ec := echo.New().NewContext(nil, nil)
+	ec := detached.NewContext(nil, nil)
 	return &Context{
 		Ctx: ctx,
 		ec:  ec,
@@ -50,3 +54,26 @@ func NewWithEchoAndContext(ec echo.Context, ctx context.Context) *Context {
 func (c Context) MandatoryRequest() MandatoryRequest {
 	return c.mandatory
 }
+
+type contextKey struct{}
+
+// Context returns Ctx carrying c, for APIs that only accept a standard context.Context
+// but still need the mandatory request or the APM transaction, see FromContext.
+func (c *Context) Context() context.Context {
+	ctx := c.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the Context stored by Context.Context.
+func FromContext(ctx context.Context) (*Context, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	c, ok := ctx.Value(contextKey{}).(*Context)
+	return c, ok
+}
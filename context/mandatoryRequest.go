@@ -18,6 +18,11 @@ type (
 	}
 )
 
+// NewMessagingSource reads the mandatory request from the attributes of a message.
+func NewMessagingSource(header map[string]string) Source {
+	return messagingSource{header: header}
+}
+
 func (m messagingSource) Apply(c *Context) {
 	requestID := m.header[header.MessagingRequestID]
 	token := m.header[header.MessagingAuthorization]
@@ -28,6 +33,23 @@ func (m messagingSource) Apply(c *Context) {
 	}
 }
 
+// NewHttpSource reads the mandatory request from the headers of the echo request of the Context.
+func NewHttpSource() Source {
+	return httpSource{}
+}
+
+func (h httpSource) Apply(c *Context) {
+	if c.ec == nil || c.ec.Request() == nil {
+		return
+	}
+
+	r := c.ec.Request()
+	c.mandatory = MandatoryRequest{
+		requestID: r.Header.Get(header.HttpRequestID),
+		token:     r.Header.Get(header.HttpAuthorization),
+	}
+}
+
 func (m MandatoryRequest) RequestID() string {
 	return m.requestID
 }
@@ -0,0 +1,63 @@
+package gzip
+
+import (
+	"bytes"
+	compress "compress/gzip"
+	"io"
+
+	"github.com/Dert12318/Utilities/encoding"
+)
+
+type (
+	implementation struct {
+		encoding encoding.Encoding
+		level    int
+	}
+)
+
+func (i implementation) Marshal(val interface{}) ([]byte, error) {
+	data, err := i.encoding.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w, err := compress.NewWriterLevel(&buf, i.level)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (i implementation) Unmarshal(data []byte, val interface{}) error {
+	r, err := compress.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	decompressed, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return i.encoding.Unmarshal(decompressed, val)
+}
+
+// NewEncoding compresses the output of the given encoding
+func NewEncoding(e encoding.Encoding) encoding.Encoding {
+	return NewEncodingLevel(e, compress.DefaultCompression)
+}
+
+func NewEncodingLevel(e encoding.Encoding, level int) encoding.Encoding {
+	return &implementation{encoding: e, level: level}
+}
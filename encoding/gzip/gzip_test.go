@@ -0,0 +1,59 @@
+package gzip
+
+import (
+	compress "compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/encoding"
+	"github.com/Dert12318/Utilities/encoding/jsontier"
+)
+
+type user struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, e := range []encoding.Encoding{NewEncoding(jsontier.NewEncoding()), NewEncodingLevel(jsontier.NewEncoding(), compress.BestSpeed)} {
+		data, err := e.Marshal(user{Name: "john", Roles: []string{"admin"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x1f, 0x8b}, data[:2], "gzip magic number")
+
+		var got user
+		assert.NoError(t, e.Unmarshal(data, &got))
+		assert.Equal(t, user{Name: "john", Roles: []string{"admin"}}, got)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	e := NewEncoding(jsontier.NewEncoding())
+
+	data, err := e.Marshal("")
+	assert.NoError(t, err)
+
+	var got string
+	assert.NoError(t, e.Unmarshal(data, &got))
+	assert.Equal(t, "", got)
+
+	assert.Error(t, e.Unmarshal(nil, &got))
+	assert.Error(t, e.Unmarshal([]byte{}, &got))
+}
+
+func TestCorrupt(t *testing.T) {
+	e := NewEncoding(jsontier.NewEncoding())
+
+	var got user
+	assert.Error(t, e.Unmarshal([]byte(`{"name":"john"}`), &got), "not compressed")
+
+	data, _ := e.Marshal(user{Name: "john"})
+	assert.Error(t, e.Unmarshal(data[:len(data)-4], &got), "truncated")
+
+	data, _ = e.Marshal(user{Name: "john"})
+	data[len(data)-8] ^= 0xff
+	assert.Error(t, e.Unmarshal(data, &got), "checksum mismatch")
+
+	_, err := NewEncodingLevel(jsontier.NewEncoding(), 42).Marshal(user{})
+	assert.Error(t, err, "invalid level")
+}
@@ -0,0 +1,23 @@
+package msgpack
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/Dert12318/Utilities/encoding"
+)
+
+type (
+	implementation struct{}
+)
+
+func (i implementation) Marshal(val interface{}) ([]byte, error) {
+	return msgpack.Marshal(val)
+}
+
+func (i implementation) Unmarshal(data []byte, val interface{}) error {
+	return msgpack.Unmarshal(data, val)
+}
+
+func NewEncoding() encoding.Encoding {
+	return &implementation{}
+}
@@ -0,0 +1,64 @@
+package msgpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type order struct {
+	ID        int64             `msgpack:"id"`
+	Items     []string          `msgpack:"items"`
+	Meta      map[string]string `msgpack:"meta"`
+	CreatedAt time.Time         `msgpack:"created_at"`
+	Note      *string           `msgpack:"note,omitempty"`
+}
+
+func TestStructRoundTrip(t *testing.T) {
+	e := NewEncoding()
+	want := order{
+		ID:        1,
+		Items:     []string{"a", "b"},
+		Meta:      map[string]string{"source": "web"},
+		CreatedAt: time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC),
+	}
+
+	data, err := e.Marshal(want)
+	assert.NoError(t, err)
+
+	var got order
+	assert.NoError(t, e.Unmarshal(data, &got))
+	assert.True(t, want.CreatedAt.Equal(got.CreatedAt))
+	got.CreatedAt = want.CreatedAt
+	assert.Equal(t, want, got)
+}
+
+func TestMapRoundTrip(t *testing.T) {
+	e := NewEncoding()
+
+	data, err := e.Marshal(map[string]interface{}{"name": "john", "age": 30, "tags": []string{"a"}})
+	assert.NoError(t, err)
+
+	var got map[string]interface{}
+	assert.NoError(t, e.Unmarshal(data, &got))
+	assert.Equal(t, "john", got["name"])
+	assert.EqualValues(t, 30, got["age"])
+	assert.Equal(t, []interface{}{"a"}, got["tags"])
+
+	typed := make(map[string]int)
+	data, _ = e.Marshal(map[string]int{"a": 1, "b": 2})
+	assert.NoError(t, e.Unmarshal(data, &typed))
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, typed)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	e := NewEncoding()
+
+	var got order
+	assert.Error(t, e.Unmarshal(nil, &got))
+	assert.Error(t, e.Unmarshal([]byte{0xc1}, &got), "never used code")
+
+	data, _ := e.Marshal("text")
+	assert.Error(t, e.Unmarshal(data, &got), "wrong type")
+}
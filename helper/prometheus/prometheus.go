@@ -0,0 +1,114 @@
+// Package prometheus writes in memory metrics in the Prometheus text exposition format,
+// it backs the registries of the cache and messaging metrics.
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type (
+	// Label is written as name="value", the labels of a sample are written in the given order
+	Label struct {
+		Name  string
+		Value string
+	}
+
+	// Histogram counts observations per bucket, it is not safe for concurrent use
+	Histogram struct {
+		buckets []float64
+		// counts holds the observations per bucket, the last one is +Inf
+		counts []uint64
+		sum    float64
+		count  uint64
+	}
+
+	// Writer buffers the samples of a namespace, Flush must be called once everything is written
+	Writer struct {
+		namespace string
+		b         *bufio.Writer
+	}
+)
+
+// Buckets returns a sorted copy of buckets, or defaults when buckets is empty
+func Buckets(buckets []float64, defaults []float64) []float64 {
+	if len(buckets) == 0 {
+		return defaults
+	}
+
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// NewHistogram creates a histogram with the sorted upper bounds buckets, see Buckets
+func NewHistogram(buckets []float64) *Histogram {
+	return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.counts[sort.SearchFloat64s(h.buckets, value)]++
+	h.sum += value
+	h.count++
+}
+
+func NewWriter(w io.Writer, namespace string) *Writer {
+	return &Writer{namespace: namespace, b: bufio.NewWriter(w)}
+}
+
+// Header writes the HELP and TYPE lines of the metric name, kind is e.g. counter, gauge or histogram
+func (w *Writer) Header(name, kind, help string) {
+	fmt.Fprintf(w.b, "# HELP %s_%s %s\n# TYPE %s_%s %s\n", w.namespace, name, help, w.namespace, name, kind)
+}
+
+// Value writes a sample of a counter or a gauge
+func (w *Writer) Value(name string, value uint64, labels ...Label) {
+	fmt.Fprintf(w.b, "%s_%s%s %d\n", w.namespace, name, format(labels), value)
+}
+
+// Histogram writes the cumulative buckets, the sum and the count of h
+func (w *Writer) Histogram(name string, h *Histogram, labels ...Label) {
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		w.Value(name+"_bucket", cumulative, with(labels, "le", strconv.FormatFloat(bound, 'g', -1, 64))...)
+	}
+	w.Value(name+"_bucket", h.count, with(labels, "le", "+Inf")...)
+	fmt.Fprintf(w.b, "%s_%s_sum%s %s\n", w.namespace, name, format(labels), strconv.FormatFloat(h.sum, 'g', -1, 64))
+	w.Value(name+"_count", h.count, labels...)
+}
+
+func (w *Writer) Flush() error {
+	return w.b.Flush()
+}
+
+// Serve writes the metrics of write as the response of a metrics endpoint
+func Serve(w http.ResponseWriter, write func(io.Writer) error) {
+	w.Header().Set("Content-Type", ContentType)
+	_ = write(w)
+}
+
+func format(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", label.Name, label.Value))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// with appends a label without modifying the array of labels
+func with(labels []Label, name, value string) []Label {
+	return append(labels[:len(labels):len(labels)], Label{Name: name, Value: value})
+}
@@ -0,0 +1,42 @@
+package prometheus
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuckets(t *testing.T) {
+	defaults := []float64{1, 2}
+	assert.Equal(t, defaults, Buckets(nil, defaults))
+
+	buckets := []float64{0.5, 0.1}
+	assert.Equal(t, []float64{0.1, 0.5}, Buckets(buckets, defaults))
+	assert.Equal(t, []float64{0.5, 0.1}, buckets, "the buckets of the caller are not sorted in place")
+}
+
+func TestWriter(t *testing.T) {
+	h := NewHistogram([]float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	var out bytes.Buffer
+	w := NewWriter(&out, "app")
+	w.Header("requests_total", "counter", "Requests.")
+	w.Value("requests_total", 3, Label{Name: "path", Value: "/"}, Label{Name: "code", Value: "200"})
+	w.Value("up", 1)
+	w.Histogram("duration_seconds", h, Label{Name: "path", Value: "/"})
+	assert.NoError(t, w.Flush())
+
+	assert.Equal(t, "# HELP app_requests_total Requests.\n"+
+		"# TYPE app_requests_total counter\n"+
+		"app_requests_total{path=\"/\",code=\"200\"} 3\n"+
+		"app_up 1\n"+
+		"app_duration_seconds_bucket{path=\"/\",le=\"0.1\"} 1\n"+
+		"app_duration_seconds_bucket{path=\"/\",le=\"1\"} 2\n"+
+		"app_duration_seconds_bucket{path=\"/\",le=\"+Inf\"} 3\n"+
+		"app_duration_seconds_sum{path=\"/\"} 5.55\n"+
+		"app_duration_seconds_count{path=\"/\"} 3\n", out.String())
+}
@@ -26,11 +26,14 @@ type (
 	DispatchDTO  struct {
 		Type      DispatchType
 		Source    string
+		Topic     string
 		RequestID string
 		MsgType   string
 		Msg       Message
 		Log       logs.Logger
 		Err       error
+		// Context is passed to the handlers, a new one is created when nil
+		Context *context.Context
 	}
 	Dispatcher interface {
 		AddHandler(handler HandlerFunc, errorHandler ErrorHandlerFunc, msgType ...string)
@@ -0,0 +1,186 @@
+package envelope
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/encoding"
+	"github.com/Dert12318/Utilities/encoding/jsontier"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+// SchemaVersion is the attribute holding the schema version of the event, the event type is stored
+// in messaging.MessageType so that a RoutingDispatcher routes on it
+const SchemaVersion = "schema_version"
+
+var (
+	// ErrUnexpectedEventType is returned when decoding a message holding another event type
+	ErrUnexpectedEventType = errors.New("unexpected event type")
+	// ErrUnsupportedVersion is returned when decoding a message newer than the event, or with an invalid version
+	ErrUnsupportedVersion = errors.New("unsupported schema version")
+	// ErrMissingUpcaster is returned when an older message can not be upcasted to the version of the event
+	ErrMissingUpcaster = errors.New("missing upcaster")
+)
+
+type (
+	// Event is a typed message payload. EventType and SchemaVersion are called on the zero value of the event,
+	// they must not depend on its fields. SchemaVersion starts at 1 and is bumped on every breaking change.
+	Event interface {
+		EventType() string
+		SchemaVersion() int
+	}
+
+	// Upcaster migrates the decoded payload of an event from a version to the next one
+	Upcaster func(payload map[string]interface{}) (map[string]interface{}, error)
+
+	// Codec encodes the events into messages and decodes them back, upcasting the older versions
+	Codec struct {
+		encoding encoding.Encoding
+
+		mu        sync.RWMutex
+		upcasters map[string]map[int]Upcaster
+	}
+
+	// EventHandlerFunc handles a decoded event, msg is the message it was decoded from
+	EventHandlerFunc[T Event] func(ctx *tntContext.Context, event T, msg messaging.Message) error
+)
+
+// NewCodec returns a Codec using enc, json when nil.
+func NewCodec(enc encoding.Encoding) *Codec {
+	if enc == nil {
+		enc = jsontier.NewEncoding()
+	}
+
+	return &Codec{
+		encoding:  enc,
+		upcasters: make(map[string]map[int]Upcaster),
+	}
+}
+
+// RegisterUpcaster registers the migration of eventType from version to version+1. Decoding a message
+// of version n into an event of version m applies the upcasters of n to m-1 in order.
+func (c *Codec) RegisterUpcaster(eventType string, version int, upcaster Upcaster) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.upcasters[eventType] == nil {
+		c.upcasters[eventType] = make(map[int]Upcaster)
+	}
+	c.upcasters[eventType][version] = upcaster
+}
+
+func (c *Codec) upcaster(eventType string, version int) Upcaster {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.upcasters[eventType][version]
+}
+
+// TypeOf returns the event type of T, e.g. to register its handler on a RoutingDispatcher.
+func TypeOf[T Event]() string {
+	var event T
+	return event.EventType()
+}
+
+// Encode encodes event into the MsgData of a message stamped with its event type and schema version.
+func Encode[T Event](c *Codec, event T, options ...Option) (messaging.Message, error) {
+	data, err := c.encoding.Marshal(event)
+	if err != nil {
+		return messaging.Message{}, errors.Wrapf(err, "failed to encode event %s", event.EventType())
+	}
+
+	msg := messaging.Message{
+		MsgData:       data,
+		MsgAttributes: make(map[string]string),
+	}
+	for _, opt := range options {
+		opt.Apply(&msg)
+	}
+
+	msg.MsgAttributes[messaging.MessageType] = event.EventType()
+	msg.MsgAttributes[SchemaVersion] = strconv.Itoa(event.SchemaVersion())
+	return msg, nil
+}
+
+// Decode decodes msg into T, upcasting it first when it was encoded with an older version of T.
+// A message without schema version is version 1.
+func Decode[T Event](c *Codec, msg messaging.Message) (T, error) {
+	var event T
+	eventType, current := event.EventType(), event.SchemaVersion()
+
+	if t, ok := msg.MsgAttributes[messaging.MessageType]; ok && t != eventType {
+		return event, errors.Wrapf(ErrUnexpectedEventType, "message %s is %s, not %s", msg.MsgID, t, eventType)
+	}
+
+	version := 1
+	if v, ok := msg.MsgAttributes[SchemaVersion]; ok {
+		var err error
+		if version, err = strconv.Atoi(v); err != nil || version < 1 {
+			return event, errors.Wrapf(ErrUnsupportedVersion, "message %s has version %q", msg.MsgID, v)
+		}
+	}
+	if version > current {
+		return event, errors.Wrapf(ErrUnsupportedVersion, "message %s is %s version %d, newer than version %d",
+			msg.MsgID, eventType, version, current)
+	}
+
+	data := msg.MsgData
+	if version < current {
+		var err error
+		if data, err = c.upcast(eventType, version, current, data); err != nil {
+			return event, errors.Wrapf(err, "failed to upcast message %s", msg.MsgID)
+		}
+	}
+
+	if err := c.encoding.Unmarshal(data, &event); err != nil {
+		return event, errors.Wrapf(err, "failed to decode message %s into %s", msg.MsgID, eventType)
+	}
+	return event, nil
+}
+
+func (c *Codec) upcast(eventType string, from, to int, data []byte) ([]byte, error) {
+	var payload map[string]interface{}
+	if err := c.encoding.Unmarshal(data, &payload); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s version %d", eventType, from)
+	}
+
+	for version := from; version < to; version++ {
+		upcaster := c.upcaster(eventType, version)
+		if upcaster == nil {
+			return nil, errors.Wrapf(ErrMissingUpcaster, "%s version %d", eventType, version)
+		}
+
+		var err error
+		if payload, err = upcaster(payload); err != nil {
+			return nil, errors.Wrapf(err, "failed to upcast %s version %d", eventType, version)
+		}
+	}
+
+	return c.encoding.Marshal(payload)
+}
+
+// Publish encodes event and publishes it to topic.
+func Publish[T Event](ctx *tntContext.Context, queue messaging.Queue, c *Codec, topic string, event T, options ...Option) error {
+	msg, err := Encode(c, event, options...)
+	if err != nil {
+		return err
+	}
+
+	return queue.PublishWithContext(ctx, topic, msg)
+}
+
+// Handle adapts handler to a messaging.HandlerFunc decoding the messages into T. Register it for TypeOf[T]
+// on a RoutingDispatcher when a topic carries several event types.
+func Handle[T Event](c *Codec, handler EventHandlerFunc[T]) messaging.HandlerFunc {
+	return func(ctx *tntContext.Context, msg messaging.Message) error {
+		event, err := Decode[T](c, msg)
+		if err != nil {
+			return err
+		}
+
+		return handler(ctx, event, msg)
+	}
+}
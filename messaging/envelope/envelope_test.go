@@ -0,0 +1,161 @@
+package envelope
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/encoding/msgpack"
+	"github.com/Dert12318/Utilities/messaging"
+	"github.com/Dert12318/Utilities/messaging/memory"
+)
+
+type (
+	orderCreated struct {
+		OrderID  string `json:"order_id" msgpack:"order_id"`
+		Amount   int64  `json:"amount" msgpack:"amount"`
+		Currency string `json:"currency" msgpack:"currency"`
+	}
+	orderCreatedV1 struct {
+		OrderID string `json:"order_id" msgpack:"order_id"`
+		Amount  int64  `json:"amount" msgpack:"amount"`
+	}
+	orderCancelled struct {
+		OrderID string `json:"order_id"`
+	}
+)
+
+func (orderCreated) EventType() string  { return "order.created" }
+func (orderCreated) SchemaVersion() int { return 2 }
+
+func (orderCreatedV1) EventType() string  { return "order.created" }
+func (orderCreatedV1) SchemaVersion() int { return 1 }
+
+func (orderCancelled) EventType() string  { return "order.cancelled" }
+func (orderCancelled) SchemaVersion() int { return 1 }
+
+func addCurrency(payload map[string]interface{}) (map[string]interface{}, error) {
+	payload["currency"] = "IDR"
+	return payload, nil
+}
+
+func TestEncodeDecode(t *testing.T) {
+	c := NewCodec(nil)
+
+	msg, err := Encode(c, orderCreated{OrderID: "1", Amount: 10, Currency: "USD"},
+		WithMsgID("order-1"), WithAttribute("source", "test"), WithAttribute(SchemaVersion, "9"))
+	assert.NoError(t, err)
+	assert.Equal(t, "order-1", msg.MsgID)
+	assert.Equal(t, "order.created", msg.MsgAttributes[messaging.MessageType])
+	assert.Equal(t, "2", msg.MsgAttributes[SchemaVersion])
+	assert.Equal(t, "test", msg.MsgAttributes["source"])
+
+	event, err := Decode[orderCreated](c, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, orderCreated{OrderID: "1", Amount: 10, Currency: "USD"}, event)
+}
+
+func TestDecodeUpcasts(t *testing.T) {
+	c := NewCodec(nil)
+	c.RegisterUpcaster("order.created", 1, addCurrency)
+
+	msg, err := Encode(c, orderCreatedV1{OrderID: "1", Amount: 10})
+	assert.NoError(t, err)
+
+	event, err := Decode[orderCreated](c, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, orderCreated{OrderID: "1", Amount: 10, Currency: "IDR"}, event)
+
+	// a message without version is version 1
+	delete(msg.MsgAttributes, SchemaVersion)
+	event, err = Decode[orderCreated](c, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, "IDR", event.Currency)
+}
+
+func TestDecodeUpcastsWithMsgpack(t *testing.T) {
+	c := NewCodec(msgpack.NewEncoding())
+	c.RegisterUpcaster("order.created", 1, addCurrency)
+
+	msg, err := Encode(c, orderCreatedV1{OrderID: "1", Amount: 10})
+	assert.NoError(t, err)
+
+	event, err := Decode[orderCreated](c, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, orderCreated{OrderID: "1", Amount: 10, Currency: "IDR"}, event)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	c := NewCodec(nil)
+
+	msg, _ := Encode(c, orderCreatedV1{OrderID: "1"})
+	_, err := Decode[orderCreated](c, msg)
+	assert.True(t, errors.Is(err, ErrMissingUpcaster))
+
+	msg, _ = Encode(c, orderCreated{OrderID: "1"})
+	_, err = Decode[orderCreatedV1](c, msg)
+	assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+
+	msg.MsgAttributes[SchemaVersion] = "two"
+	_, err = Decode[orderCreated](c, msg)
+	assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+
+	_, err = Decode[orderCancelled](c, msg)
+	assert.True(t, errors.Is(err, ErrUnexpectedEventType))
+
+	c.RegisterUpcaster("order.created", 1, func(map[string]interface{}) (map[string]interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	msg, _ = Encode(c, orderCreatedV1{OrderID: "1"})
+	_, err = Decode[orderCreated](c, msg)
+	assert.EqualError(t, errors.Unwrap(errors.Unwrap(err)), "failed to upcast order.created version 1: boom")
+}
+
+func TestPublishAndHandle(t *testing.T) {
+	c := NewCodec(nil)
+	c.RegisterUpcaster("order.created", 1, addCurrency)
+
+	q, err := memory.New()
+	assert.NoError(t, err)
+	defer q.Close()
+
+	var (
+		mu        sync.Mutex
+		created   []orderCreated
+		cancelled []orderCancelled
+	)
+	d := messaging.NewRoutingDispatcher()
+	d.AddHandler(Handle(c, func(ctx *tntContext.Context, event orderCreated, msg messaging.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		created = append(created, event)
+		return nil
+	}), nil, TypeOf[orderCreated]())
+	d.AddHandler(Handle(c, func(ctx *tntContext.Context, event orderCancelled, msg messaging.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		cancelled = append(cancelled, event)
+		return nil
+	}), nil, TypeOf[orderCancelled]())
+
+	assert.NoError(t, q.Subscribe("orders", d))
+	q.Listen()
+
+	ctx := tntContext.New()
+	assert.NoError(t, Publish(ctx, q, c, "orders", orderCreated{OrderID: "1", Amount: 10, Currency: "USD"}))
+	assert.NoError(t, Publish(ctx, q, c, "orders", orderCreatedV1{OrderID: "2", Amount: 20}))
+	assert.NoError(t, Publish(ctx, q, c, "orders", orderCancelled{OrderID: "1"}))
+	assert.NoError(t, q.WaitForIdle(time.Second))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []orderCreated{
+		{OrderID: "1", Amount: 10, Currency: "USD"},
+		{OrderID: "2", Amount: 20, Currency: "IDR"},
+	}, created)
+	assert.Equal(t, []orderCancelled{{OrderID: "1"}}, cancelled)
+}
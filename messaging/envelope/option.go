@@ -0,0 +1,34 @@
+package envelope
+
+import (
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+type (
+	// Option customizes the message an event is encoded into
+	Option interface {
+		Apply(msg *messaging.Message)
+	}
+)
+
+type withMsgID string
+
+// WithMsgID sets the MsgID, which is also the partition key of kafka, a random one is generated by default.
+func WithMsgID(msgID string) Option {
+	return withMsgID(msgID)
+}
+
+func (w withMsgID) Apply(msg *messaging.Message) {
+	msg.MsgID = string(w)
+}
+
+type withAttribute struct{ key, value string }
+
+// WithAttribute adds an attribute to the message, the event type and schema version can not be overridden.
+func WithAttribute(key, value string) Option {
+	return withAttribute{key: key, value: value}
+}
+
+func (w withAttribute) Apply(msg *messaging.Message) {
+	msg.MsgAttributes[w.key] = w.value
+}
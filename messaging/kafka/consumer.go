@@ -1,13 +1,16 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"sync"
 
 	"github.com/Shopify/sarama"
 
 	"github.com/Dert12318/Utilities/apm"
 	"github.com/Dert12318/Utilities/common/constant/header"
+	tntContext "github.com/Dert12318/Utilities/context"
 	"github.com/Dert12318/Utilities/messaging"
 )
 
@@ -20,6 +23,18 @@ type (
 		listening bool
 		option    option
 		apm       apm.APM
+		// stages maps a retry topic to how many times its messages already failed
+		stages map[string]int
+		// forwarder publishes to the retry and dead-letter topics
+		forwarder sarama.SyncProducer
+		// transforms are the topics consumed exactly once, see SubscribeTransform
+		transforms    map[string]transform
+		transactional *transactional
+		// group is paused and resumed per topic, paused survives the rebalances unlike the sarama pause
+		group  sarama.ConsumerGroup
+		paused map[string]bool
+		// claims are the partitions of the current session
+		claims map[string][]int32
 	}
 	messageShown struct {
 		MsgID         string            `json:"msg_id"`
@@ -37,32 +52,127 @@ func (c *consumer) Setup(session sarama.ConsumerGroupSession) error {
 		c.topics = make(map[string]messaging.Dispatcher)
 	}
 
-	c.option.Log.Info("Start Listening")
+	c.mu.Lock()
 	c.listening = true
+	c.claims = session.Claims()
+	c.mu.Unlock()
+
+	c.option.Log.Info("Start Listening")
+	select {
+	case c.ready <- true:
+	default:
+	}
+
+	if c.option.OnAssign != nil {
+		c.option.OnAssign(session.Claims())
+	}
 	return nil
 }
 
+// Cleanup runs once every ConsumeClaim of the session returned, the marked offsets are committed afterwards
 func (c *consumer) Cleanup(session sarama.ConsumerGroupSession) error {
+	c.mu.Lock()
+	c.listening = false
+	c.claims = nil
+	c.mu.Unlock()
+
+	c.option.Log.Info("Stop Listening")
+	if c.option.OnRevoke != nil {
+		c.option.OnRevoke(session.Claims())
+	}
 	return nil
 }
 
+// active reports whether a group session is running
+func (c *consumer) active() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.listening
+}
+
+func (c *consumer) pause(topics []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, topic := range topics {
+		c.paused[topic] = true
+	}
+	if partitions := c.partitions(topics); len(partitions) > 0 {
+		c.group.Pause(partitions)
+	}
+}
+
+func (c *consumer) resume(topics []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, topic := range topics {
+		delete(c.paused, topic)
+	}
+	if partitions := c.partitions(topics); len(partitions) > 0 {
+		c.group.Resume(partitions)
+	}
+}
+
+// partitions returns the claimed partitions of topics, c.mu must be locked
+func (c *consumer) partitions(topics []string) map[string][]int32 {
+	partitions := make(map[string][]int32)
+	for _, topic := range topics {
+		if claimed, ok := c.claims[topic]; ok {
+			partitions[topic] = claimed
+		}
+	}
+	return partitions
+}
+
+// pauseClaim pauses the claim again when its topic was paused during a previous session
+func (c *consumer) pauseClaim(claim sarama.ConsumerGroupClaim) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused[claim.Topic()] {
+		c.group.Pause(map[string][]int32{claim.Topic(): {claim.Partition()}})
+	}
+}
+
 func (c *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	// NOTE:
 	// Do not move the code below to a goroutine.
 	// The `ConsumeClaim` itself is called within a goroutine, see:
 	// https://github.com/Shopify/sarama/blob/master/consumer_group.go#L27-L29
-	for message := range claim.Messages() {
-		dispatcher := c.topics[message.Topic]
+	// The messages are handled by the workers, the loop blocks while the worker of a message is saturated.
+	if c.group != nil {
+		c.pauseClaim(claim)
+	}
+
+	workers := newWorkers(c, session)
+	defer workers.stop()
 
+	for message := range claim.Messages() {
 		if message == nil {
 			continue
 		}
 
+		if t, ok := c.transforms[message.Topic]; ok {
+			if !c.transform(session, message, t) {
+				return nil
+			}
+			continue
+		}
+
+		dispatcher := c.topics[message.Topic]
 		if dispatcher == nil {
 			continue
 		}
 
-		c.processMessage(message.Topic, session, message, dispatcher)
+		if !c.awaitRetry(session, message) {
+			return nil
+		}
+
+		if !workers.submit(message, dispatcher) {
+			return nil
+		}
 	}
 
 	return nil
@@ -74,41 +184,61 @@ func (c *consumer) getMessage(msg sarama.ConsumerMessage) messaging.Message {
 		attributes[string(attr.Key)] = string(attr.Value)
 	}
 
+	// the key is the partition key when the producer set one
+	msgID := string(msg.Key)
+	if id, ok := attributes[header.MessagingMessageID]; ok {
+		msgID = id
+		delete(attributes, header.MessagingMessageID)
+	}
+
 	return messaging.Message{
-		MsgID:         string(msg.Key),
+		MsgID:         msgID,
 		MsgData:       msg.Value,
 		MsgAttributes: attributes,
 	}
 }
 
-func (c *consumer) processMessage(topic string, session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, dispatcher messaging.Dispatcher) {
+// processMessage dispatches msg and reports whether it is done with, it is not when the session ended
+// before msg could be forwarded to a retry or dead-letter topic
+func (c *consumer) processMessage(topic string, session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, dispatcher messaging.Dispatcher) bool {
 	messageData := c.getMessage(*msg)
 	requestID := messageData.MsgAttributes[header.MessagingRequestID]
-	msgType := messageData.MsgAttributes["message"]
+	msgType := messageData.MsgAttributes[messaging.MessageType]
+
+	ctx := c.context(topic, messageData)
+	if ctx.Transaction != nil {
+		defer ctx.Transaction.End()
+	}
 
 	var err error
 	var message = messaging.DispatchDTO{
 		Type:      messaging.Handle,
 		Source:    fmt.Sprintf("Kafka - %s", topic),
+		Topic:     topic,
 		RequestID: requestID,
 		MsgType:   msgType,
 		Msg:       messageData,
 		Log:       c.option.Log,
+		Context:   ctx,
 	}
 	for i := 0; i <= c.option.ConsumerRetryMax; i++ {
 		message.Err = nil
 		if err = dispatcher.Dispatch(message); err == nil {
-			session.MarkMessage(msg, "")
-			return
+			return true
 		} else {
 			c.option.Log.Error("error on dispatch message from kafka: ", err.Error())
 			message.Err = err
 		}
 	}
 
+	if topic, ok := c.nextRetry(msg); ok {
+		return c.forward(session, topic, msg, err)
+	}
+
 	errMessage := messaging.DispatchDTO{
 		Type:      messaging.Error,
 		Source:    fmt.Sprintf("Kafka - %s", topic),
+		Topic:     topic,
 		RequestID: requestID,
 		MsgType:   msgType,
 		Msg: messaging.Message{
@@ -116,9 +246,36 @@ func (c *consumer) processMessage(topic string, session sarama.ConsumerGroupSess
 			MsgData:       messageData.MsgData,
 			MsgAttributes: messageData.MsgAttributes,
 		},
-		Log: c.option.Log,
-		Err: err,
+		Log:     c.option.Log,
+		Err:     err,
+		Context: ctx,
+	}
+	if ctx.Transaction != nil {
+		ctx.Transaction.NoticeError(err)
 	}
 	_ = dispatcher.Dispatch(errMessage)
-	session.MarkMessage(msg, "")
+
+	if c.option.DeadLetterSuffix != "" {
+		return c.forward(session, originalTopic(msg)+c.option.DeadLetterSuffix, msg, err)
+	}
+	return true
+}
+
+// context rebuilds the request context of msg from its headers, its transaction continues the trace of the producer
+func (c *consumer) context(topic string, msg messaging.Message) *tntContext.Context {
+	ctx := tntContext.NewWithContext(context.Background())
+	ctx.SetMandatory(tntContext.NewMessagingSource(msg.MsgAttributes))
+
+	if c.apm != nil {
+		attributes := header.WithoutCredentials(msg.MsgAttributes)
+		trace := make(http.Header, len(attributes))
+		for key, attr := range attributes {
+			trace.Set(key, attr)
+		}
+
+		ctx.Transaction = apm.StartTransactionFromHeaders(c.apm, fmt.Sprintf("%s:%s", EventConsume, topic), trace)
+		ctx.Ctx = ctx.Transaction.NewContext(ctx.Ctx)
+	}
+
+	return ctx
 }
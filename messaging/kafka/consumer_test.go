@@ -0,0 +1,128 @@
+package kafka
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/apm"
+	"github.com/Dert12318/Utilities/apm/disabled"
+	"github.com/Dert12318/Utilities/common/constant/header"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs/logrus"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+type (
+	tracingAPM struct {
+		apm.APM
+		name   string
+		header http.Header
+
+		mu      sync.Mutex
+		started int
+		ended   int
+	}
+
+	// tracingTransaction counts its End and writes its name as trace header of its segments
+	tracingTransaction struct {
+		apm.Transaction
+		apm  *tracingAPM
+		name string
+	}
+
+	tracingSegment struct {
+		apm.Segment
+		name string
+	}
+)
+
+func (a *tracingAPM) StartTransactionFromHeaders(name string, header http.Header) apm.Transaction {
+	a.name, a.header = name, header
+	return apm.StartTransactionFromHeaders(a.APM, name, header)
+}
+
+func (a *tracingAPM) StartTransaction(name string) apm.Transaction {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.started++
+	return &tracingTransaction{Transaction: a.APM.StartTransaction(name), apm: a, name: name}
+}
+
+// transactions returns how many transactions were started and ended
+func (a *tracingAPM) transactions() (started, ended int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.started, a.ended
+}
+
+func (t *tracingTransaction) End() {
+	t.apm.mu.Lock()
+	defer t.apm.mu.Unlock()
+
+	t.apm.ended++
+	t.Transaction.End()
+}
+
+func (t *tracingTransaction) StartMessageProducerSegment(request apm.MessageProducerSegmentDTO) apm.Segment {
+	return &tracingSegment{Segment: t.Transaction.StartMessageProducerSegment(request), name: t.name}
+}
+
+func (s *tracingSegment) InsertDistributedTraceHeaders(header http.Header) {
+	header.Set("X-Trace-Transaction", s.name)
+}
+
+func TestProcessMessageContext(t *testing.T) {
+	disabledAPM, _ := disabled.New()
+	tracer := &tracingAPM{APM: disabledAPM}
+
+	var ctx *tntContext.Context
+	dispatcher := messaging.NewSingleEventDispatcher()
+	dispatcher.AddHandler(func(c *tntContext.Context, msg messaging.Message) error {
+		ctx = c
+		return nil
+	}, nil)
+
+	c := &consumer{option: option{Log: logrus.DefaultLog()}, apm: tracer}
+	msg := &sarama.ConsumerMessage{
+		Topic: "order",
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(header.MessagingRequestID), Value: []byte("request")},
+			{Key: []byte(header.MessagingAuthorization), Value: []byte("token")},
+			{Key: []byte("X-Datadog-Trace-Id"), Value: []byte("42")},
+		},
+	}
+	assert.True(t, c.processMessage(msg.Topic, &session{ctx: context.Background()}, msg, dispatcher))
+
+	assert.NotNil(t, ctx)
+	assert.Equal(t, "request", ctx.MandatoryRequest().RequestID())
+	assert.Equal(t, "token", ctx.MandatoryRequest().Token())
+	assert.NotNil(t, ctx.Transaction)
+	assert.Equal(t, "kafka_consume:order", tracer.name)
+	assert.Equal(t, "42", tracer.header.Get("X-Datadog-Trace-Id"))
+	assert.Empty(t, tracer.header.Get(header.MessagingAuthorization))
+}
+
+func TestGetMessagePartitionKey(t *testing.T) {
+	c := &consumer{option: option{Log: logrus.DefaultLog()}}
+
+	msg := c.getMessage(sarama.ConsumerMessage{Key: []byte("id")})
+	assert.Equal(t, "id", msg.MsgID)
+
+	msg = c.getMessage(sarama.ConsumerMessage{
+		Key: []byte("order-1"),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(messaging.PartitionKey), Value: []byte("order-1")},
+			{Key: []byte(header.MessagingMessageID), Value: []byte("id")},
+		},
+	})
+	assert.Equal(t, "id", msg.MsgID)
+	assert.Equal(t, "order-1", msg.MsgAttributes[messaging.PartitionKey])
+	assert.NotContains(t, msg.MsgAttributes, header.MessagingMessageID)
+}
@@ -2,12 +2,13 @@ package kafka
 
 import (
 	"context"
-	"fmt"
 	"sync"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/pkg/errors"
 
+	"github.com/Dert12318/Utilities/apm"
 	tntContext "github.com/Dert12318/Utilities/context"
 	"github.com/Dert12318/Utilities/encoding"
 	jsoniter "github.com/Dert12318/Utilities/encoding/jsontier"
@@ -15,15 +16,51 @@ import (
 	"github.com/Dert12318/Utilities/messaging"
 )
 
-type kafka struct {
-	Option        option
-	Client        sarama.Client
-	ConsumerGroup sarama.ConsumerGroup
-	consumer      *consumer
-	jsonEncoding  encoding.Encoding
-}
+type (
+	// Queue is the messaging.Queue returned by New with the publishing modes of kafka
+	Queue interface {
+		messaging.Queue
+		// PublishSync waits for the broker and returns where msg was written
+		PublishSync(ctx *tntContext.Context, topic string, msg messaging.Message) (Delivery, error)
+		// PublishBatch waits for every message, the error reports how many failed and the Err of their Delivery why
+		PublishBatch(ctx *tntContext.Context, topic string, msgs []messaging.Message) ([]Delivery, error)
+		// PublishAsync returns immediately, callback is called once the broker answered
+		PublishAsync(ctx *tntContext.Context, topic string, msg messaging.Message, callback DeliveryFunc) error
+		// Transactional publishes the messages of fn atomically, they are discarded when fn fails.
+		// It needs WithTransactionalID and runs one transaction at a time.
+		Transactional(ctx *tntContext.Context, fn func(tx Tx) error) error
+		// SubscribeTransform consumes topic exactly once with transform, see TransformFunc. errorHandler may be nil,
+		// it is called before a message that failed ConsumerRetryMax+1 times is skipped. It needs WithTransactionalID.
+		SubscribeTransform(topic string, transform TransformFunc, errorHandler messaging.ErrorHandlerFunc) error
+		// Pause stops fetching topics and their retry topics until Resume, also across rebalances.
+		// The messages already fetched are still processed.
+		Pause(topics ...string)
+		Resume(topics ...string)
+	}
+
+	kafka struct {
+		Option        option
+		Client        sarama.Client
+		ConsumerGroup sarama.ConsumerGroup
+		consumer      *consumer
+		producer      *producer
+		transactional *transactional
+		jsonEncoding  encoding.Encoding
+
+		// ctx is cancelled by Close to end the group session, done is closed once Listen stopped consuming
+		ctx     context.Context
+		cancel  context.CancelFunc
+		started bool
+		done    chan struct{}
+	}
+)
 
 func New(options ...Option) (messaging.Queue, error) {
+	return NewQueue(options...)
+}
+
+// NewQueue is New returning the kafka Queue, with the sync, batch, async and transactional publishing
+func NewQueue(options ...Option) (Queue, error) {
 	var err error
 
 	option := option{
@@ -39,6 +76,8 @@ func New(options ...Option) (messaging.Queue, error) {
 		ProducerMaxBytes:     DefaultProducerMaxBytes,
 		ProducerRetryMax:     DefaultProducerRetryMax,
 		ProducerRetryBackOff: DefaultProducerRetryBackoff,
+		ShutdownTimeout:      DefaultShutdownTimeout,
+		StartupTimeout:       DefaultStartupTimeout,
 		Log:                  logrus.DefaultLog(),
 		//Apm:                  defaultAPM,
 	}
@@ -55,6 +94,7 @@ func New(options ...Option) (messaging.Queue, error) {
 		Option:       option,
 		jsonEncoding: jsoniter.NewEncoding(),
 	}
+	l.ctx, l.cancel = context.WithCancel(context.Background())
 
 	version, err := sarama.ParseKafkaVersion(l.Option.KafkaVersion)
 	if err != nil {
@@ -84,19 +124,28 @@ func New(options ...Option) (messaging.Queue, error) {
 		cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
 		cfg.Consumer.Retry.Backoff = l.Option.ConsumerRetryBackoff
 		cfg.Consumer.Return.Errors = true
+		if l.Option.ReadCommitted {
+			cfg.Consumer.IsolationLevel = sarama.ReadCommitted
+		}
 		l.ConsumerGroup, err = sarama.NewConsumerGroup(l.Option.Host, l.Option.ConsumerGroup, cfg)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if !option.WithoutProducer {
+	forward := !option.WithoutConsumer && (len(option.RetryDelays) > 0 || option.DeadLetterSuffix != "")
+	if !option.WithoutProducer || forward {
 		// - producer
 		cfg.Producer.Return.Errors = true
 		cfg.Producer.Return.Successes = true
 		cfg.Producer.MaxMessageBytes = l.Option.ProducerMaxBytes
 		cfg.Producer.Retry.Max = l.Option.ProducerRetryMax
 		cfg.Producer.Retry.Backoff = l.Option.ProducerRetryBackOff
+		if l.Option.Idempotent {
+			cfg.Producer.Idempotent = true
+			cfg.Producer.RequiredAcks = sarama.WaitForAll
+			cfg.Net.MaxOpenRequests = 1
+		}
 	}
 
 	l.Client, err = sarama.NewClient(l.Option.Host, cfg)
@@ -104,13 +153,46 @@ func New(options ...Option) (messaging.Queue, error) {
 		return nil, err
 	}
 
+	if !option.WithoutProducer {
+		l.producer, err = newProducer(l.Client, option.Apm, option.Log)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if option.TransactionalID != "" {
+		publisher := l.producer
+		if publisher == nil {
+			publisher = &producer{apm: option.Apm, log: option.Log}
+		}
+
+		l.transactional, err = newTransactional(l.Option.Host, *cfg, option.TransactionalID, publisher)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if !option.WithoutConsumer {
 		l.consumer = &consumer{
-			mu:     &sync.Mutex{},
-			topics: make(map[string]messaging.Dispatcher),
-			ready:  make(chan bool),
-			option: l.Option,
-			apm:    option.Apm,
+			mu:            &sync.Mutex{},
+			topics:        make(map[string]messaging.Dispatcher),
+			ready:         make(chan bool, 1),
+			option:        l.Option,
+			apm:           option.Apm,
+			stages:        make(map[string]int),
+			transforms:    make(map[string]transform),
+			transactional: l.transactional,
+			group:         l.ConsumerGroup,
+			paused:        make(map[string]bool),
+		}
+
+		if forward && l.producer != nil {
+			l.consumer.forwarder = l.producer.sync
+		} else if forward {
+			l.consumer.forwarder, err = sarama.NewSyncProducerFromClient(l.Client)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to create the producer of the retry topics")
+			}
 		}
 	}
 
@@ -126,19 +208,46 @@ func (k *kafka) Subscribe(topic string, dispatcher messaging.Dispatcher) error {
 	defer k.consumer.mu.Unlock()
 
 	k.consumer.topics[topic] = dispatcher
+	for i, delay := range k.Option.RetryDelays {
+		retry := retryTopic(topic, delay)
+		k.consumer.topics[retry] = dispatcher
+		k.consumer.stages[retry] = i + 1
+	}
+	return nil
+}
+
+func (k *kafka) SubscribeTransform(topic string, fn TransformFunc, errorHandler messaging.ErrorHandlerFunc) error {
+	if k.Option.WithoutConsumer {
+		return errors.New("kafka is initialize without consumer")
+	}
+	if k.transactional == nil {
+		return errors.New("kafka is initialize without transactional id")
+	}
+
+	k.consumer.mu.Lock()
+	defer k.consumer.mu.Unlock()
+
+	k.consumer.transforms[topic] = transform{transform: fn, errorHandler: errorHandler}
 	return nil
 }
 
+// Listen joins the consumer group and returns once the first session started or the queue is closed,
+// the group is consumed again after every rebalance until Close.
 func (k *kafka) Listen() {
 	if k.Option.WithoutConsumer {
 		k.Option.Log.Error(errors.New("kafka is initialize without consumer"))
 		return
 	}
 
-	if k.consumer.listening {
+	k.consumer.mu.Lock()
+	if k.started {
+		k.consumer.mu.Unlock()
 		k.Option.Log.Info("already listening to kafka")
 		return
 	}
+	k.started = true
+	k.done = make(chan struct{})
+	done := k.done
 
 	var (
 		topics = make([]string, 0)
@@ -147,73 +256,200 @@ func (k *kafka) Listen() {
 	for key := range k.consumer.topics {
 		topics = append(topics, key)
 	}
+	for key := range k.consumer.transforms {
+		topics = append(topics, key)
+	}
+	k.consumer.mu.Unlock()
 
 	go func() {
-		for {
-			if err := k.ConsumerGroup.Consume(context.Background(), topics, k.consumer); err != nil {
-				k.Option.Log.Errorf("error from consumer: %s", err.Error())
+		for err := range k.ConsumerGroup.Errors() {
+			k.Option.Log.Errorf("error from consumer: %s", err.Error())
+		}
+	}()
+
+	// failed reports the first error of Consume, so Listen does not wait on brokers that are unreachable
+	failed := make(chan error, 1)
+	go func() {
+		defer close(done)
+
+		for k.ctx.Err() == nil {
+			err := k.ConsumerGroup.Consume(k.ctx, topics, k.consumer)
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return
 			}
 
-			k.consumer.ready <- true
+			if err != nil {
+				k.Option.Log.Errorf("error from consumer: %s", err.Error())
+				select {
+				case failed <- err:
+				default:
+				}
+
+				select {
+				case <-k.ctx.Done():
+				case <-time.After(k.Option.ConsumerRetryBackoff):
+				}
+			}
 		}
 	}()
 
-	// Await till the consumer has been set up
-	<-k.consumer.ready
+	// Await till the consumer has been set up, it keeps retrying in the background when it could not
+	select {
+	case <-k.consumer.ready:
+	case <-done:
+	case err := <-failed:
+		k.Option.Log.Error(errors.Wrapf(err, "kafka consumer failed to start"))
+	case <-time.After(k.Option.StartupTimeout):
+		k.Option.Log.Error(errors.Errorf("kafka consumer did not start within %s", k.Option.StartupTimeout))
+	}
 }
 
-func (k *kafka) Publish(topic string, msg messaging.Message) error {
-	if k.Option.WithoutProducer {
-		return errors.New("kafka is initialize without producer")
+func (k *kafka) Pause(topics ...string) {
+	if k.consumer != nil {
+		k.consumer.pause(k.withRetryTopics(topics))
 	}
-	ctx := tntContext.New()
-	ctx.Transaction = k.Option.Apm.StartTransaction(EventPublish)
+}
 
-	asyncProducer, err := sarama.NewAsyncProducerFromClient(k.Client)
-	if err != nil {
-		return errors.New(fmt.Sprintf("error create async client message: %s", err.Error()))
+func (k *kafka) Resume(topics ...string) {
+	if k.consumer != nil {
+		k.consumer.resume(k.withRetryTopics(topics))
 	}
+}
 
-	producer := &producer{asyncProducer: asyncProducer, apm: k.Option.Apm}
-	defer func() {
-		if err := producer.Close(); err != nil {
-			k.Option.Log.Error(errors.Wrapf(err, "Failed to Close producer"))
+func (k *kafka) withRetryTopics(topics []string) []string {
+	all := make([]string, 0, len(topics)*(len(k.Option.RetryDelays)+1))
+	for _, topic := range topics {
+		all = append(all, topic)
+		for _, delay := range k.Option.RetryDelays {
+			all = append(all, retryTopic(topic, delay))
 		}
-	}()
+	}
+	return all
+}
 
-	return producer.Publish(ctx, topic, msg)
+func (k *kafka) Publish(topic string, msg messaging.Message) error {
+	return k.PublishWithContext(tntContext.New(), topic, msg)
 }
 
 func (k *kafka) PublishWithContext(ctx *tntContext.Context, topic string, msg messaging.Message) error {
+	_, err := k.PublishSync(ctx, topic, msg)
+	return err
+}
+
+func (k *kafka) PublishSync(ctx *tntContext.Context, topic string, msg messaging.Message) (Delivery, error) {
+	if k.Option.WithoutProducer {
+		return Delivery{}, errors.New("kafka is initialize without producer")
+	}
+	if t := k.startTransaction(ctx); t != nil {
+		defer k.endTransaction(ctx, t)
+	}
+
+	return k.producer.Publish(ctx, topic, msg)
+}
+
+func (k *kafka) PublishBatch(ctx *tntContext.Context, topic string, msgs []messaging.Message) ([]Delivery, error) {
+	if k.Option.WithoutProducer {
+		return nil, errors.New("kafka is initialize without producer")
+	}
+	if t := k.startTransaction(ctx); t != nil {
+		defer k.endTransaction(ctx, t)
+	}
+
+	return k.producer.PublishBatch(ctx, topic, msgs)
+}
+
+func (k *kafka) PublishAsync(ctx *tntContext.Context, topic string, msg messaging.Message, callback DeliveryFunc) error {
 	if k.Option.WithoutProducer {
 		return errors.New("kafka is initialize without producer")
 	}
-	if ctx.Transaction == nil {
-		ctx.Transaction = k.Option.Apm.StartTransaction(EventPublish)
+
+	// the transaction started here ends once the message is delivered, ctx only carries it during the call
+	delivered := func() {}
+	if t := k.startTransaction(ctx); t != nil {
+		delivered = t.End
+		defer func() { ctx.Transaction = nil }()
 	}
 
-	asyncProducer, err := sarama.NewAsyncProducerFromClient(k.Client)
-	if err != nil {
-		return errors.New(fmt.Sprintf("error create async client message: %s", err.Error()))
+	if err := k.producer.PublishAsync(ctx, topic, msg, callback, delivered); err != nil {
+		delivered()
+		return err
 	}
+	return nil
+}
 
-	producer := &producer{asyncProducer: asyncProducer, apm: k.Option.Apm}
-	defer func() {
-		if err := producer.Close(); err != nil {
-			k.Option.Log.Error(errors.Wrapf(err, "Failed to Close producer"))
-		}
-	}()
+func (k *kafka) Transactional(ctx *tntContext.Context, fn func(tx Tx) error) error {
+	if k.transactional == nil {
+		return errors.New("kafka is initialize without transactional id")
+	}
+	if t := k.startTransaction(ctx); t != nil {
+		defer k.endTransaction(ctx, t)
+	}
 
-	return producer.Publish(ctx, topic, msg)
+	return k.transactional.run(ctx, fn, nil, "")
 }
 
+// startTransaction sets a publish transaction on ctx when it has none and returns it, nil when ctx already has
+// one. The transaction started here must be ended with endTransaction.
+func (k *kafka) startTransaction(ctx *tntContext.Context) apm.Transaction {
+	if ctx.Transaction != nil || k.Option.Apm == nil {
+		return nil
+	}
+
+	ctx.Transaction = k.Option.Apm.StartTransaction(EventPublish)
+	return ctx.Transaction
+}
+
+// endTransaction ends t and removes it from ctx, a ctx reused for another publish starts a new one
+func (k *kafka) endTransaction(ctx *tntContext.Context, t apm.Transaction) {
+	ctx.Transaction = nil
+	t.End()
+}
+
+// Close ends the group session and waits up to ShutdownTimeout for the messages being processed
+// before closing the connections.
 func (k *kafka) Close() error {
+	k.cancel()
+
+	// done is set by Listen under the lock of the consumer
+	var done chan struct{}
+	if k.consumer != nil {
+		k.consumer.mu.Lock()
+		done = k.done
+		k.consumer.mu.Unlock()
+	}
+	if done != nil {
+		select {
+		case <-done:
+		case <-time.After(k.Option.ShutdownTimeout):
+			k.Option.Log.Error(errors.Errorf("kafka consumer did not stop within %s", k.Option.ShutdownTimeout))
+		}
+	}
+
 	if k.ConsumerGroup != nil {
 		if err := k.ConsumerGroup.Close(); err != nil {
 			return errors.Wrapf(err, "Failed to Close Consumer")
 		}
 	}
 
+	if k.producer != nil {
+		if err := k.producer.Close(); err != nil {
+			return errors.Wrapf(err, "Failed to Close Producer")
+		}
+	}
+
+	if k.transactional != nil {
+		if err := k.transactional.Close(); err != nil {
+			return errors.Wrapf(err, "Failed to Close transactional producer")
+		}
+	}
+
+	// the retry producer is only owned by the consumer without the producer
+	if k.producer == nil && k.consumer != nil && k.consumer.forwarder != nil {
+		if err := k.consumer.forwarder.Close(); err != nil {
+			return errors.Wrapf(err, "Failed to Close retry producer")
+		}
+	}
+
 	if k.Client != nil {
 		if err := k.Client.Close(); err != nil {
 			return errors.Wrapf(err, "Failed to Close Producer")
@@ -223,8 +459,21 @@ func (k *kafka) Close() error {
 	return nil
 }
 
+// Ping fails once the queue is closed and, for a consumer, while no group session is running
+// e.g. during a rebalance or when the brokers are unreachable.
 func (k *kafka) Ping(ctx *tntContext.Context) error {
-	if k.consumer.listening {
+	if k.ctx.Err() != nil {
+		return errors.New("kafka is closed")
+	}
+
+	if k.consumer == nil {
+		if k.Client.Closed() {
+			return errors.New("kafka client is closed")
+		}
+		return nil
+	}
+
+	if k.consumer.active() {
 		return nil
 	}
 	return errors.New("kafka is not rebalanced")
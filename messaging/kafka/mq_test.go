@@ -0,0 +1,292 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/apm/disabled"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs/logrus"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+// group runs one session per Consume until ctx is cancelled, claiming partition 0 of every topic
+type group struct {
+	mu      sync.Mutex
+	paused  []map[string][]int32
+	resumed []map[string][]int32
+	errors  chan error
+	handled chan struct{}
+}
+
+func (g *group) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	claims := make(map[string][]int32)
+	for _, topic := range topics {
+		claims[topic] = []int32{0}
+	}
+
+	s := &claimSession{session: session{ctx: ctx}, claims: claims}
+	if err := handler.Setup(s); err != nil {
+		return err
+	}
+
+	cl := &claim{messages: make(chan *sarama.ConsumerMessage, 1)}
+	cl.messages <- &sarama.ConsumerMessage{Topic: "order", Key: []byte("1")}
+	go func() {
+		<-ctx.Done()
+		close(cl.messages)
+	}()
+
+	err := handler.ConsumeClaim(s, cl)
+	_ = handler.Cleanup(s)
+	return err
+}
+
+func (g *group) Errors() <-chan error { return g.errors }
+func (g *group) Close() error         { return nil }
+func (g *group) PauseAll()            {}
+func (g *group) ResumeAll()           {}
+
+func (g *group) Pause(partitions map[string][]int32) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.paused = append(g.paused, partitions)
+}
+
+func (g *group) Resume(partitions map[string][]int32) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.resumed = append(g.resumed, partitions)
+}
+
+type claimSession struct {
+	session
+	claims map[string][]int32
+}
+
+func (s *claimSession) Claims() map[string][]int32 { return s.claims }
+
+func newTestKafka(g *group, handler messaging.HandlerFunc, options ...Option) *kafka {
+	o := option{
+		ConsumerWorker:  1,
+		ShutdownTimeout: time.Second,
+		StartupTimeout:  time.Second,
+		RetryDelays:     []time.Duration{time.Minute},
+		Log:             logrus.DefaultLog(),
+	}
+	for _, opt := range options {
+		opt.Apply(&o)
+	}
+
+	dispatcher := messaging.NewSingleEventDispatcher()
+	dispatcher.AddHandler(handler, nil)
+
+	k := &kafka{
+		Option:        o,
+		ConsumerGroup: g,
+		consumer: &consumer{
+			mu:     &sync.Mutex{},
+			topics: map[string]messaging.Dispatcher{"order": dispatcher},
+			ready:  make(chan bool, 1),
+			option: o,
+			group:  g,
+			paused: make(map[string]bool),
+		},
+	}
+	k.ctx, k.cancel = context.WithCancel(context.Background())
+	return k
+}
+
+func TestListenAndCloseDrainsHandlers(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		assigned map[string][]int32
+		revoked  map[string][]int32
+		finished bool
+	)
+	started := make(chan struct{})
+
+	g := &group{errors: make(chan error)}
+	k := newTestKafka(g, func(ctx *tntContext.Context, msg messaging.Message) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		finished = true
+		return nil
+	}, WithOnAssign(func(claims map[string][]int32) {
+		mu.Lock()
+		defer mu.Unlock()
+		assigned = claims
+	}), WithOnRevoke(func(claims map[string][]int32) {
+		mu.Lock()
+		defer mu.Unlock()
+		revoked = claims
+	}))
+
+	k.Listen()
+	assert.NoError(t, k.Ping(tntContext.New()))
+
+	<-started
+	assert.NoError(t, k.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, finished)
+	assert.Equal(t, map[string][]int32{"order": {0}}, assigned)
+	assert.Equal(t, map[string][]int32{"order": {0}}, revoked)
+	assert.Error(t, k.Ping(tntContext.New()))
+}
+
+func TestCloseGivesUpAfterShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	g := &group{errors: make(chan error)}
+	k := newTestKafka(g, func(ctx *tntContext.Context, msg messaging.Message) error {
+		<-release
+		return nil
+	}, WithShutdownTimeout(20*time.Millisecond))
+
+	k.Listen()
+
+	start := time.Now()
+	assert.NoError(t, k.Close())
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+// unreachable fails every Consume, or blocks without a session when hang is set
+type unreachable struct {
+	group
+	hang bool
+}
+
+func (u *unreachable) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	if u.hang {
+		<-ctx.Done()
+	}
+	return sarama.ErrOutOfBrokers
+}
+
+func TestListenReturnsWhenBrokersAreUnreachable(t *testing.T) {
+	for _, hang := range []bool{false, true} {
+		g := &unreachable{group: group{errors: make(chan error)}, hang: hang}
+		k := newTestKafka(&g.group, nil, WithStartupTimeout(20*time.Millisecond))
+		k.ConsumerGroup = g
+
+		start := time.Now()
+		k.Listen()
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+		assert.Error(t, k.Ping(tntContext.New()))
+		assert.NoError(t, k.Close())
+	}
+}
+
+func TestPingReflectsSession(t *testing.T) {
+	g := &group{}
+	k := newTestKafka(g, nil)
+	assert.Error(t, k.Ping(tntContext.New()))
+
+	s := &claimSession{session: session{ctx: context.Background()}, claims: map[string][]int32{"order": {0}}}
+	assert.NoError(t, k.consumer.Setup(s))
+	assert.NoError(t, k.Ping(tntContext.New()))
+
+	assert.NoError(t, k.consumer.Cleanup(s))
+	assert.Error(t, k.Ping(tntContext.New()))
+}
+
+func TestPauseAndResume(t *testing.T) {
+	g := &group{}
+	k := newTestKafka(g, nil)
+
+	s := &claimSession{session: session{ctx: context.Background()}, claims: map[string][]int32{
+		"order":          {0, 1},
+		"order.retry.1m": {0},
+		"payment":        {2},
+	}}
+	assert.NoError(t, k.consumer.Setup(s))
+
+	k.Pause("order")
+	assert.Equal(t, []map[string][]int32{{"order": {0, 1}, "order.retry.1m": {0}}}, g.paused)
+
+	// the pause is applied again to the claims of the next sessions
+	k.consumer.pauseClaim(&claim{})
+	assert.Equal(t, map[string][]int32{"order": {0}}, g.paused[1])
+
+	k.Resume("order")
+	assert.Equal(t, []map[string][]int32{{"order": {0, 1}, "order.retry.1m": {0}}}, g.resumed)
+
+	k.consumer.pauseClaim(&claim{})
+	assert.Len(t, g.paused, 2)
+}
+
+func TestPublishEndsTransaction(t *testing.T) {
+	disabledAPM, _ := disabled.New()
+	tracer := &tracingAPM{APM: disabledAPM}
+
+	p, syncProducer, asyncProducer := newTestProducer(t)
+	defer p.Close()
+	k := &kafka{Option: option{Apm: tracer, Log: logrus.DefaultLog()}, producer: p}
+
+	syncProducer.ExpectSendMessageAndSucceed()
+	syncProducer.ExpectSendMessageAndSucceed()
+	ctx := tntContext.New()
+	assert.NoError(t, k.PublishWithContext(ctx, "order", messaging.Message{MsgID: "1"}))
+	_, err := k.PublishBatch(ctx, "order", []messaging.Message{{MsgID: "2"}})
+	assert.NoError(t, err)
+	assert.Nil(t, ctx.Transaction)
+
+	started, ended := tracer.transactions()
+	assert.Equal(t, 2, started)
+	assert.Equal(t, 2, ended)
+
+	// the transaction of ctx belongs to the caller, it is not ended by the publish
+	syncProducer.ExpectSendMessageAndSucceed()
+	ctx.Transaction = disabledAPM.StartTransaction("order")
+	assert.NoError(t, k.PublishWithContext(ctx, "order", messaging.Message{MsgID: "3"}))
+	started, ended = tracer.transactions()
+	assert.Equal(t, 2, started)
+	assert.Equal(t, 2, ended)
+
+	// the transaction of an asynchronous publish ends once the message is delivered
+	asyncProducer.ExpectInputAndSucceed()
+	delivered := make(chan Delivery, 1)
+	ctx = tntContext.New()
+	assert.NoError(t, k.PublishAsync(ctx, "order", messaging.Message{MsgID: "4"}, func(delivery Delivery) {
+		delivered <- delivery
+	}))
+	assert.Nil(t, ctx.Transaction)
+	assert.NoError(t, (<-delivered).Err)
+
+	started, ended = tracer.transactions()
+	assert.Equal(t, 3, started)
+	assert.Equal(t, 3, ended)
+}
+
+func TestPublishTraceFromEndedTransaction(t *testing.T) {
+	disabledAPM, _ := disabled.New()
+	tracer := &tracingAPM{APM: disabledAPM}
+
+	p, syncProducer, _ := newTestProducer(t)
+	defer p.Close()
+	k := &kafka{Option: option{Apm: tracer, Log: logrus.DefaultLog()}, producer: p}
+
+	var sent *sarama.ProducerMessage
+	syncProducer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		sent = msg
+		return nil
+	})
+	assert.NoError(t, k.Publish("order", messaging.Message{MsgID: "1"}))
+
+	// the consumer continues the trace of the publish transaction, which is not left open
+	assert.Equal(t, EventPublish, headers(sent)["X-Trace-Transaction"])
+	started, ended := tracer.transactions()
+	assert.Equal(t, 1, started)
+	assert.Equal(t, 1, ended)
+}
@@ -23,8 +23,14 @@ const (
 	BalanceStrategySticky       = "BalanceStrategySticky"
 	BalanceStrategyRoundRobin   = "BalanceStrategyRoundRobin"
 	BalanceStrategyRange        = "BalanceStrategyRange"
+	DefaultDeadLetterSuffix     = ".dlq"
+	DefaultShutdownTimeout      = 30 * time.Second
+	DefaultStartupTimeout       = 30 * time.Second
 )
 
+// RebalanceFunc receives the partitions of every topic assigned to or revoked from this consumer
+type RebalanceFunc func(claims map[string][]int32)
+
 type (
 	Option interface {
 		Apply(o *option)
@@ -51,6 +57,22 @@ type (
 		SASLMechanism        string
 		Username             string
 		Password             string
+		// RetryDelays are the delays of the retry topics a failed message goes through, in order
+		RetryDelays []time.Duration
+		// DeadLetterSuffix names the topic a message is published to once every retry failed
+		DeadLetterSuffix string
+		// Idempotent producers write every message exactly once per partition despite their retries
+		Idempotent bool
+		// TransactionalID enables Queue.Transactional and Queue.SubscribeTransform
+		TransactionalID string
+		// ReadCommitted consumers skip the messages of aborted or open transactions
+		ReadCommitted bool
+		// ShutdownTimeout bounds how long Close waits for the messages being processed
+		ShutdownTimeout time.Duration
+		// StartupTimeout bounds how long Listen waits for the first group session
+		StartupTimeout time.Duration
+		OnAssign       RebalanceFunc
+		OnRevoke       RebalanceFunc
 	}
 )
 
@@ -64,6 +86,17 @@ func validate(option option) error {
 	if option.KafkaVersion == "" {
 		return errors.New("invalid kafka version")
 	}
+	if option.ShutdownTimeout <= 0 {
+		return errors.New("invalid kafka shutdown timeout")
+	}
+	if option.StartupTimeout <= 0 {
+		return errors.New("invalid kafka startup timeout")
+	}
+	for _, delay := range option.RetryDelays {
+		if delay <= 0 {
+			return errors.New("invalid kafka retry topic delay")
+		}
+	}
 	return nil
 }
 
@@ -299,3 +332,113 @@ func Password(pass string) Option {
 func (p password) Apply(o *option) {
 	o.Password = string(p)
 }
+
+type withRetryTopics []time.Duration
+
+// WithRetryTopics forwards a message that failed ConsumerRetryMax times to a retry topic per delay,
+// named after the topic and the delay e.g. "order.retry.5s" and "order.retry.1m". A retry topic is
+// consumed once the delay elapsed since the message was forwarded, the topics must exist unless the
+// brokers create them automatically.
+func WithRetryTopics(delays ...time.Duration) Option {
+	return withRetryTopics(delays)
+}
+
+func (w withRetryTopics) Apply(o *option) {
+	o.RetryDelays = w
+}
+
+type withDeadLetterSuffix string
+
+// WithDeadLetterSuffix publishes a message to the topic named after its original topic and suffix
+// once every retry failed and the error handler ran, see DefaultDeadLetterSuffix.
+func WithDeadLetterSuffix(suffix string) Option {
+	return withDeadLetterSuffix(suffix)
+}
+
+func (w withDeadLetterSuffix) Apply(o *option) {
+	o.DeadLetterSuffix = string(w)
+}
+
+type withIdempotentProducer bool
+
+// WithIdempotentProducer makes the producers idempotent, which needs kafka 0.11, acks from every in-sync replica
+// and a single in-flight request per broker connection.
+func WithIdempotentProducer() Option {
+	return withIdempotentProducer(true)
+}
+
+func (w withIdempotentProducer) Apply(o *option) {
+	o.Idempotent = bool(w)
+}
+
+type withTransactionalID string
+
+// WithTransactionalID connects an idempotent transactional producer, used by Queue.Transactional and
+// Queue.SubscribeTransform. The id must be stable across restarts and unique per instance, a new producer
+// with the same id fences the previous one.
+func WithTransactionalID(id string) Option {
+	return withTransactionalID(id)
+}
+
+func (w withTransactionalID) Apply(o *option) {
+	o.TransactionalID = string(w)
+}
+
+type withReadCommitted bool
+
+// WithReadCommitted only consumes the messages of committed transactions, set it on the consumers of the
+// topics written with Queue.Transactional or Queue.SubscribeTransform.
+func WithReadCommitted() Option {
+	return withReadCommitted(true)
+}
+
+func (w withReadCommitted) Apply(o *option) {
+	o.ReadCommitted = bool(w)
+}
+
+type withShutdownTimeout time.Duration
+
+// WithShutdownTimeout bounds how long Close waits for the handlers of the messages being processed,
+// see DefaultShutdownTimeout. The messages whose handler did not finish in time are consumed again.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return withShutdownTimeout(timeout)
+}
+
+func (w withShutdownTimeout) Apply(o *option) {
+	o.ShutdownTimeout = time.Duration(w)
+}
+
+type withStartupTimeout time.Duration
+
+// WithStartupTimeout bounds how long Listen waits for the consumer to join its group, see DefaultStartupTimeout.
+// The consumer keeps retrying in the background after Listen returned.
+func WithStartupTimeout(timeout time.Duration) Option {
+	return withStartupTimeout(timeout)
+}
+
+func (w withStartupTimeout) Apply(o *option) {
+	o.StartupTimeout = time.Duration(w)
+}
+
+type withOnAssign RebalanceFunc
+
+// WithOnAssign calls fn with the partitions of every new group session, before any message is consumed.
+func WithOnAssign(fn RebalanceFunc) Option {
+	return withOnAssign(fn)
+}
+
+func (w withOnAssign) Apply(o *option) {
+	o.OnAssign = RebalanceFunc(w)
+}
+
+type withOnRevoke RebalanceFunc
+
+// WithOnRevoke calls fn with the partitions of a group session once it ends, after its messages were
+// processed and before their offsets are committed.
+func WithOnRevoke(fn RebalanceFunc) Option {
+	return withOnRevoke(fn)
+}
+
+func (w withOnRevoke) Apply(o *option) {
+	o.OnRevoke = RebalanceFunc(w)
+}
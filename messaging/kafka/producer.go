@@ -2,51 +2,262 @@ package kafka
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/google/uuid"
+	"github.com/pkg/errors"
 
 	"github.com/Dert12318/Utilities/apm"
+	"github.com/Dert12318/Utilities/apm/disabled"
+	"github.com/Dert12318/Utilities/common/constant/header"
 	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs"
 	"github.com/Dert12318/Utilities/messaging"
 )
 
 const (
 	EventPublish = "kafka_publish"
+	EventConsume = "kafka_consume"
 )
 
 type (
+	// Delivery is where a message was written, Err is set when the broker rejected it
+	Delivery struct {
+		MsgID     string
+		Topic     string
+		Partition int32
+		Offset    int64
+		Err       error
+	}
+
+	// DeliveryFunc is called once the broker acknowledged or rejected a message published with PublishAsync
+	DeliveryFunc func(delivery Delivery)
+
+	// producer is shared by every publish of a kafka queue, PublishAsync messages go through an
+	// AsyncProducer whose results are read on a goroutine until Close
 	producer struct {
-		asyncProducer sarama.AsyncProducer
-		apm           apm.APM
+		sync  sarama.SyncProducer
+		async sarama.AsyncProducer
+		apm   apm.APM
+		log   logs.Logger
+		wg    sync.WaitGroup
+
+		// mu guards closed, PublishAsync holds it while sending so Close does not close the input under it
+		mu     sync.RWMutex
+		closed bool
 	}
 )
 
-func (p *producer) Publish(ctx *tntContext.Context, topic string, msg messaging.Message) error {
-	//s := ctx.Transaction.StartMessageProducerSegment(apm.MessageProducerSegmentDTO{
-	//	Library:              "Kafka",
-	//	DestinationType:      apm.MessageTopic,
-	//	DestinationName:      topic,
-	//	DestinationTemporary: false,
-	//})
-	//defer s.End()
+// ErrProducerClosed is returned by PublishAsync once the producer is closed
+var ErrProducerClosed = errors.New("kafka producer is closed")
+
+func newProducer(client sarama.Client, apm apm.APM, log logs.Logger) (*producer, error) {
+	syncProducer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		return nil, errors.Wrap(err, "error create sync producer")
+	}
+
+	asyncProducer, err := sarama.NewAsyncProducerFromClient(client)
+	if err != nil {
+		_ = syncProducer.Close()
+		return nil, errors.Wrap(err, "error create async producer")
+	}
+
+	p := &producer{sync: syncProducer, async: asyncProducer, apm: apm, log: log}
+	p.listen()
+
+	return p, nil
+}
+
+// listen reads the results of the async producer until Close
+func (p *producer) listen() {
+	p.wg.Add(2)
+	go p.successes()
+	go p.failures()
+}
+
+func (p *producer) Publish(ctx *tntContext.Context, topic string, msg messaging.Message) (Delivery, error) {
+	message, segment := p.message(ctx, topic, &msg)
+	defer segment.End()
+
+	partition, offset, err := p.sync.SendMessage(message)
+	delivery := Delivery{MsgID: msg.MsgID, Topic: topic, Partition: partition, Offset: offset}
+	if err != nil {
+		segment.AddAttribute("error", err.Error())
+		delivery.Err = errors.Wrapf(err, "failed to publish message %s to %s", msg.MsgID, topic)
+		return delivery, delivery.Err
+	}
+
+	p.record(topic, msg)
+	return delivery, nil
+}
+
+// PublishBatch sends msgs in as few requests as possible, the deliveries are in the order of msgs
+func (p *producer) PublishBatch(ctx *tntContext.Context, topic string, msgs []messaging.Message) ([]Delivery, error) {
+	messages := make([]*sarama.ProducerMessage, len(msgs))
+	for i := range msgs {
+		var segment apm.Segment
+		messages[i], segment = p.message(ctx, topic, &msgs[i])
+		defer segment.End()
+	}
+
+	err := p.sync.SendMessages(messages)
+
+	failed := make(map[*sarama.ProducerMessage]error)
+	if errs, ok := err.(sarama.ProducerErrors); ok {
+		for _, e := range errs {
+			failed[e.Msg] = e.Err
+		}
+	} else if err != nil {
+		for _, message := range messages {
+			failed[message] = err
+		}
+	}
+
+	deliveries := make([]Delivery, len(msgs))
+	for i, message := range messages {
+		deliveries[i] = Delivery{MsgID: msgs[i].MsgID, Topic: topic, Partition: message.Partition, Offset: message.Offset}
+		if e, ok := failed[message]; ok {
+			deliveries[i].Err = errors.Wrapf(e, "failed to publish message %s to %s", msgs[i].MsgID, topic)
+			continue
+		}
+
+		p.record(topic, msgs[i])
+	}
+
+	if len(failed) > 0 {
+		return deliveries, errors.Errorf("failed to publish %d of %d messages to %s", len(failed), len(msgs), topic)
+	}
+
+	return deliveries, nil
+}
+
+// PublishAsync queues msg and returns, callback may be nil in which case a failure is only logged.
+// delivered is called once the message is delivered or failed, before callback.
+func (p *producer) PublishAsync(ctx *tntContext.Context, topic string, msg messaging.Message, callback DeliveryFunc, delivered func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return errors.Wrapf(ErrProducerClosed, "failed to publish message %s to %s", msg.MsgID, topic)
+	}
+
+	message, segment := p.message(ctx, topic, &msg)
+	message.Metadata = asyncMetadata{msg: msg, callback: callback, segment: segment, delivered: delivered}
+	p.async.Input() <- message
+	return nil
+}
+
+func (p *producer) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	// AsyncClose lets the goroutines drain the results until sarama closes the channels
+	p.async.AsyncClose()
+	p.wg.Wait()
+
+	return p.sync.Close()
+}
+
+type asyncMetadata struct {
+	msg       messaging.Message
+	callback  DeliveryFunc
+	segment   apm.Segment
+	delivered func()
+}
+
+func (p *producer) successes() {
+	defer p.wg.Done()
+
+	for message := range p.async.Successes() {
+		metadata := message.Metadata.(asyncMetadata)
+		metadata.segment.End()
+		metadata.delivered()
+		p.record(message.Topic, metadata.msg)
+
+		if metadata.callback != nil {
+			metadata.callback(Delivery{
+				MsgID:     metadata.msg.MsgID,
+				Topic:     message.Topic,
+				Partition: message.Partition,
+				Offset:    message.Offset,
+			})
+		}
+	}
+}
+
+func (p *producer) failures() {
+	defer p.wg.Done()
+
+	for e := range p.async.Errors() {
+		metadata := e.Msg.Metadata.(asyncMetadata)
+		metadata.segment.AddAttribute("error", e.Err.Error())
+		metadata.segment.End()
+		metadata.delivered()
+		err := errors.Wrapf(e.Err, "failed to publish message %s to %s", metadata.msg.MsgID, e.Msg.Topic)
+
+		if metadata.callback == nil {
+			p.log.Error(err)
+			continue
+		}
+
+		metadata.callback(Delivery{
+			MsgID:     metadata.msg.MsgID,
+			Topic:     e.Msg.Topic,
+			Partition: e.Msg.Partition,
+			Offset:    e.Msg.Offset,
+			Err:       err,
+		})
+	}
+}
+
+// message converts msg, generating its MsgID when empty. The mandatory request of ctx and the trace of the
+// returned segment are carried as headers, the segment must be ended once the message is delivered and before
+// the transaction of ctx.
+func (p *producer) message(ctx *tntContext.Context, topic string, msg *messaging.Message) (*sarama.ProducerMessage, apm.Segment) {
+	segment := disabled.NewSegment()
+	if ctx != nil && ctx.Transaction != nil {
+		segment = ctx.Transaction.StartMessageProducerSegment(apm.MessageProducerSegmentDTO{
+			Library:              "Kafka",
+			DestinationType:      apm.MessageTopic,
+			DestinationName:      topic,
+			DestinationTemporary: false,
+		})
+	}
 
-	headers := make([]sarama.RecordHeader, 0)
+	attributes := make(map[string]string, len(msg.MsgAttributes)+2)
+	if ctx != nil {
+		mandatory := ctx.MandatoryRequest()
+		if mandatory.RequestID() != "" {
+			attributes[header.MessagingRequestID] = mandatory.RequestID()
+		}
+		if mandatory.Token() != "" {
+			attributes[header.MessagingAuthorization] = mandatory.Token()
+		}
+	}
 
-	if msg.MsgAttributes == nil {
-		msg.MsgAttributes = make(map[string]string)
+	trace := make(http.Header)
+	apm.InsertDistributedTraceHeaders(segment, trace)
+	for key := range trace {
+		attributes[key] = trace.Get(key)
 	}
 
-	//msg.MsgAttributes[header.MessagingApiKey] = ctx.MandatoryRequest().APIKey()
-	//msg.MsgAttributes[header.MessagingRequestID] = ctx.MandatoryRequest().RequestID()
-	//msg.MsgAttributes[header.MessagingServiceID] = ctx.MandatoryRequest().ServiceID()
-	//msg.MsgAttributes[header.MessagingServiceSecret] = ctx.MandatoryRequest().ServiceSecret()
-	//msg.MsgAttributes[header.MessagingAuthorization] = ctx.MandatoryRequest().Token()
-	//msg.MsgAttributes[header.MessagingDeviceID] = ctx.MandatoryRequest().DeviceID()
-	//msg.MsgAttributes[header.MessagingUserAgent] = ctx.MandatoryRequest().UserAgent()
+	// the attributes set by the caller win over the ones of ctx
+	for key, attr := range msg.MsgAttributes {
+		attributes[key] = attr
+	}
+	msg.MsgAttributes = attributes
 
+	headers := make([]sarama.RecordHeader, 0, len(attributes)+1)
 	for key, attr := range msg.MsgAttributes {
 		headers = append(headers, sarama.RecordHeader{
 			Key:   []byte(key),
@@ -62,26 +273,32 @@ func (p *producer) Publish(ctx *tntContext.Context, topic string, msg messaging.
 		msg.MsgID = uuid.New().String()
 	}
 
-	p.asyncProducer.Input() <- &sarama.ProducerMessage{
+	key := msg.MsgID
+	if partitionKey := msg.MsgAttributes[messaging.PartitionKey]; partitionKey != "" {
+		key = partitionKey
+		headers = append(headers, sarama.RecordHeader{
+			Key:   []byte(header.MessagingMessageID),
+			Value: []byte(msg.MsgID),
+		})
+	}
+
+	return &sarama.ProducerMessage{
 		Topic:     topic,
-		Key:       sarama.StringEncoder(msg.MsgID),
+		Key:       sarama.StringEncoder(key),
 		Value:     sarama.StringEncoder(string(msg.MsgData)),
 		Headers:   headers,
 		Timestamp: time.Now(),
-	}
+	}, segment
+}
 
+func (p *producer) record(topic string, msg messaging.Message) {
 	if p.apm != nil {
 		key := fmt.Sprintf("%s:%s", EventPublish, strings.ReplaceAll(topic, ".", "_"))
 		p.apm.RecordCustomEvent(key, map[string]interface{}{
 			"topic":        topic,
 			"message_id":   msg.MsgID,
-			"message_attr": msg.MsgAttributes,
+			"message_attr": header.WithoutCredentials(msg.MsgAttributes),
 			"message_data": string(msg.MsgData),
 		})
 	}
-	return nil
-}
-
-func (p *producer) Close() error {
-	return p.asyncProducer.Close()
 }
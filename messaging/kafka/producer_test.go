@@ -0,0 +1,219 @@
+package kafka
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/apm"
+	"github.com/Dert12318/Utilities/apm/disabled"
+	"github.com/Dert12318/Utilities/common/constant/header"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs/logrus"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+func newTestProducer(t *testing.T) (*producer, *mocks.SyncProducer, *mocks.AsyncProducer) {
+	cfg := mocks.NewTestConfig()
+	cfg.Producer.Return.Successes = true
+
+	syncProducer := mocks.NewSyncProducer(t, cfg)
+	asyncProducer := mocks.NewAsyncProducer(t, cfg)
+
+	p := &producer{sync: syncProducer, async: asyncProducer, log: logrus.DefaultLog()}
+	p.listen()
+	return p, syncProducer, asyncProducer
+}
+
+func TestProducerPublish(t *testing.T) {
+	p, syncProducer, _ := newTestProducer(t)
+	defer p.Close()
+
+	syncProducer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		key, _ := msg.Key.Encode()
+		assert.Equal(t, "id", string(key))
+		return nil
+	})
+	syncProducer.ExpectSendMessageAndFail(sarama.ErrNotLeaderForPartition)
+
+	delivery, err := p.Publish(tntContext.New(), "order", messaging.Message{MsgID: "id"})
+	assert.NoError(t, err)
+	assert.Equal(t, "order", delivery.Topic)
+	assert.Equal(t, "id", delivery.MsgID)
+	assert.Equal(t, int64(1), delivery.Offset)
+
+	delivery, err = p.Publish(tntContext.New(), "order", messaging.Message{})
+	assert.True(t, errors.Is(err, sarama.ErrNotLeaderForPartition))
+	assert.NotEmpty(t, delivery.MsgID)
+	assert.Equal(t, err, delivery.Err)
+}
+
+func TestProducerPartitionKey(t *testing.T) {
+	p, syncProducer, _ := newTestProducer(t)
+	defer p.Close()
+
+	var sent *sarama.ProducerMessage
+	syncProducer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		sent = msg
+		return nil
+	})
+
+	delivery, err := p.Publish(tntContext.New(), "order", messaging.Message{
+		MsgID:         "id",
+		MsgAttributes: map[string]string{messaging.PartitionKey: "order-1"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "id", delivery.MsgID)
+
+	key, _ := sent.Key.Encode()
+	assert.Equal(t, "order-1", string(key))
+	assert.Equal(t, "id", headers(sent)[header.MessagingMessageID])
+}
+
+// batchProducer fails the messages of the batch listed in fail the way the sarama SyncProducer does
+type batchProducer struct {
+	*mocks.SyncProducer
+	fail map[int]error
+}
+
+func (b *batchProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	var errs sarama.ProducerErrors
+	for i, msg := range msgs {
+		if err, ok := b.fail[i]; ok {
+			errs = append(errs, &sarama.ProducerError{Msg: msg, Err: err})
+			continue
+		}
+		msg.Offset = int64(i + 10)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func TestProducerPublishBatch(t *testing.T) {
+	p, syncProducer, _ := newTestProducer(t)
+	defer p.Close()
+
+	p.sync = &batchProducer{SyncProducer: syncProducer, fail: map[int]error{1: sarama.ErrMessageSizeTooLarge}}
+
+	deliveries, err := p.PublishBatch(tntContext.New(), "order", []messaging.Message{{MsgID: "1"}, {MsgID: "2"}, {MsgID: "3"}})
+	assert.EqualError(t, err, "failed to publish 1 of 3 messages to order")
+	assert.Len(t, deliveries, 3)
+	assert.NoError(t, deliveries[0].Err)
+	assert.Equal(t, int64(10), deliveries[0].Offset)
+	assert.True(t, errors.Is(deliveries[1].Err, sarama.ErrMessageSizeTooLarge))
+	assert.NoError(t, deliveries[2].Err)
+	assert.Equal(t, "3", deliveries[2].MsgID)
+	assert.Equal(t, int64(12), deliveries[2].Offset)
+
+	// an error that is not per message fails the whole batch
+	syncProducer.ExpectSendMessageAndFail(sarama.ErrOutOfBrokers)
+	p.sync = syncProducer
+
+	deliveries, err = p.PublishBatch(tntContext.New(), "order", []messaging.Message{{MsgID: "4"}})
+	assert.EqualError(t, err, "failed to publish 1 of 1 messages to order")
+	assert.True(t, errors.Is(deliveries[0].Err, sarama.ErrOutOfBrokers))
+}
+
+func TestProducerPublishAsync(t *testing.T) {
+	p, _, asyncProducer := newTestProducer(t)
+
+	asyncProducer.ExpectInputAndSucceed()
+	asyncProducer.ExpectInputAndFail(sarama.ErrOutOfBrokers)
+
+	deliveries := make(chan Delivery, 2)
+	callback := func(delivery Delivery) { deliveries <- delivery }
+	var delivered int32
+	done := func() { atomic.AddInt32(&delivered, 1) }
+	assert.NoError(t, p.PublishAsync(tntContext.New(), "order", messaging.Message{MsgID: "1"}, callback, done))
+	assert.NoError(t, p.PublishAsync(tntContext.New(), "order", messaging.Message{MsgID: "2"}, callback, done))
+
+	assert.NoError(t, p.Close())
+	close(deliveries)
+
+	// publishing after Close fails instead of sending on the closed input
+	err := p.PublishAsync(tntContext.New(), "order", messaging.Message{MsgID: "3"}, callback, done)
+	assert.True(t, errors.Is(err, ErrProducerClosed))
+	assert.NoError(t, p.Close())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&delivered))
+
+	results := make(map[string]error)
+	for delivery := range deliveries {
+		results[delivery.MsgID] = delivery.Err
+	}
+	assert.NoError(t, results["1"])
+	assert.True(t, errors.Is(results["2"], sarama.ErrOutOfBrokers))
+}
+
+func TestProducerInjectsContext(t *testing.T) {
+	p, syncProducer, _ := newTestProducer(t)
+	defer p.Close()
+
+	var sent *sarama.ProducerMessage
+	syncProducer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		sent = msg
+		return nil
+	})
+
+	ctx := tntContext.New()
+	ctx.SetMandatory(tntContext.NewMessagingSource(map[string]string{
+		header.MessagingRequestID:     "request",
+		header.MessagingAuthorization: "token",
+	}))
+
+	attributes := map[string]string{"message": "created", header.MessagingAuthorization: "override"}
+	_, err := p.Publish(ctx, "order", messaging.Message{MsgAttributes: attributes})
+	assert.NoError(t, err)
+
+	h := headers(sent)
+	assert.Equal(t, "request", h[header.MessagingRequestID])
+	assert.Equal(t, "override", h[header.MessagingAuthorization])
+	assert.Equal(t, "created", h["message"])
+	assert.NotEmpty(t, h[messaging.PublishTime])
+	assert.Len(t, attributes, 2, "the attributes of the caller are not modified")
+}
+
+// eventAPM keeps the custom events recorded on publish
+type eventAPM struct {
+	apm.APM
+	events []map[string]interface{}
+}
+
+func (a *eventAPM) RecordCustomEvent(eventType string, params map[string]interface{}) {
+	a.events = append(a.events, params)
+}
+
+func TestProducerRecordsNoCredentials(t *testing.T) {
+	p, syncProducer, _ := newTestProducer(t)
+	defer p.Close()
+
+	disabledAPM, _ := disabled.New()
+	recorder := &eventAPM{APM: disabledAPM}
+	p.apm = recorder
+	syncProducer.ExpectSendMessageAndSucceed()
+
+	ctx := tntContext.New()
+	ctx.SetMandatory(tntContext.NewMessagingSource(map[string]string{
+		header.MessagingRequestID:     "request",
+		header.MessagingAuthorization: "Bearer token",
+	}))
+
+	_, err := p.Publish(ctx, "order", messaging.Message{MsgAttributes: map[string]string{header.HttpAuthorization: "Bearer other"}})
+	assert.NoError(t, err)
+
+	if assert.Len(t, recorder.events, 1) {
+		attributes := recorder.events[0]["message_attr"].(map[string]string)
+		assert.Equal(t, "request", attributes[header.MessagingRequestID])
+		for key, attr := range attributes {
+			assert.False(t, header.IsCredential(key), key)
+			assert.NotContains(t, attr, "token")
+			assert.NotContains(t, attr, "Bearer")
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/common/constant/header"
+)
+
+// retryTopic names the retry topic of topic for delay, e.g. "order.retry.1m" or "order.retry.1h30m"
+func retryTopic(topic string, delay time.Duration) string {
+	d := delay.String()
+	if strings.HasSuffix(d, "m0s") {
+		d = strings.TrimSuffix(d, "0s")
+	}
+	if strings.HasSuffix(d, "h0m") {
+		d = strings.TrimSuffix(d, "0m")
+	}
+
+	return fmt.Sprintf("%s.retry.%s", topic, d)
+}
+
+// stage is how many times msg already failed, 0 for a message of a subscribed topic
+func (c *consumer) stage(msg *sarama.ConsumerMessage) int {
+	if stage, ok := c.stages[msg.Topic]; ok {
+		return stage
+	}
+
+	return 0
+}
+
+// originalTopic is the subscribed topic msg was first published to
+func originalTopic(msg *sarama.ConsumerMessage) string {
+	for _, h := range msg.Headers {
+		if string(h.Key) == header.MessagingOriginalTopic {
+			return string(h.Value)
+		}
+	}
+
+	return msg.Topic
+}
+
+// nextRetry returns the retry topic a failed msg is forwarded to, false once every retry topic is used
+func (c *consumer) nextRetry(msg *sarama.ConsumerMessage) (string, bool) {
+	stage := c.stage(msg)
+	if stage >= len(c.option.RetryDelays) {
+		return "", false
+	}
+
+	return retryTopic(originalTopic(msg), c.option.RetryDelays[stage]), true
+}
+
+// awaitRetry waits until the delay of the retry topic of msg elapsed, it returns false when the session ended first
+func (c *consumer) awaitRetry(session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) bool {
+	stage := c.stage(msg)
+	if stage == 0 || msg.Timestamp.IsZero() {
+		return true
+	}
+
+	wait := time.Until(msg.Timestamp.Add(c.option.RetryDelays[stage-1]))
+	if wait <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-session.Context().Done():
+		return false
+	}
+}
+
+// forward publishes msg to topic with the failure headers, retrying until it succeeds.
+// It returns false when the session ended first, msg must not be marked then.
+func (c *consumer) forward(session sarama.ConsumerGroupSession, topic string, msg *sarama.ConsumerMessage, cause error) bool {
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+5)
+	original := map[string]string{
+		header.MessagingOriginalTopic:     msg.Topic,
+		header.MessagingOriginalPartition: strconv.FormatInt(int64(msg.Partition), 10),
+		header.MessagingOriginalOffset:    strconv.FormatInt(msg.Offset, 10),
+	}
+	for _, h := range msg.Headers {
+		key := string(h.Key)
+		if key == header.MessagingAttempt || key == header.MessagingFailureReason {
+			continue
+		}
+		// a retried message keeps pointing to the message first consumed
+		if _, ok := original[key]; ok {
+			original[key] = string(h.Value)
+			continue
+		}
+		headers = append(headers, *h)
+	}
+
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(header.MessagingOriginalTopic), Value: []byte(original[header.MessagingOriginalTopic])},
+		sarama.RecordHeader{Key: []byte(header.MessagingOriginalPartition), Value: []byte(original[header.MessagingOriginalPartition])},
+		sarama.RecordHeader{Key: []byte(header.MessagingOriginalOffset), Value: []byte(original[header.MessagingOriginalOffset])},
+		sarama.RecordHeader{Key: []byte(header.MessagingAttempt), Value: []byte(strconv.Itoa(c.stage(msg) + 1))},
+		sarama.RecordHeader{Key: []byte(header.MessagingFailureReason), Value: []byte(cause.Error())},
+	)
+
+	message := &sarama.ProducerMessage{
+		Topic:     topic,
+		Key:       sarama.ByteEncoder(msg.Key),
+		Value:     sarama.ByteEncoder(msg.Value),
+		Headers:   headers,
+		Timestamp: time.Now(),
+	}
+
+	for {
+		_, _, err := c.forwarder.SendMessage(message)
+		if err == nil {
+			return true
+		}
+
+		c.option.Log.Error(errors.Wrapf(err, "failed to forward message %s to %s", string(msg.Key), topic))
+
+		select {
+		case <-session.Context().Done():
+			return false
+		case <-time.After(c.option.ConsumerRetryBackoff):
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/common/constant/header"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs/logrus"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+type session struct {
+	ctx    context.Context
+	mu     sync.Mutex
+	marked []*sarama.ConsumerMessage
+}
+
+func (s *session) Claims() map[string][]int32               { return nil }
+func (s *session) MemberID() string                         { return "" }
+func (s *session) GenerationID() int32                      { return 0 }
+func (s *session) MarkOffset(string, int32, int64, string)  {}
+func (s *session) Commit()                                  {}
+func (s *session) ResetOffset(string, int32, int64, string) {}
+func (s *session) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, msg)
+}
+func (s *session) Context() context.Context { return s.ctx }
+
+func headers(msg *sarama.ProducerMessage) map[string]string {
+	h := make(map[string]string)
+	for _, record := range msg.Headers {
+		h[string(record.Key)] = string(record.Value)
+	}
+	return h
+}
+
+func TestRetryTopic(t *testing.T) {
+	assert.Equal(t, "order.retry.5s", retryTopic("order", 5*time.Second))
+	assert.Equal(t, "order.retry.1m", retryTopic("order", time.Minute))
+	assert.Equal(t, "order.retry.1m30s", retryTopic("order", 90*time.Second))
+	assert.Equal(t, "order.retry.2h", retryTopic("order", 2*time.Hour))
+	assert.Equal(t, "order.retry.1h30m", retryTopic("order", 90*time.Minute))
+}
+
+func TestProcessMessageRetryAndDeadLetter(t *testing.T) {
+	var handled, failed int
+	dispatcher := messaging.NewSingleEventDispatcher()
+	dispatcher.AddHandler(func(ctx *tntContext.Context, msg messaging.Message) error {
+		handled++
+		return errors.New("boom")
+	}, func(ctx *tntContext.Context, msg messaging.Message, err error) {
+		failed++
+	})
+
+	var forwarded []*sarama.ProducerMessage
+	producer := mocks.NewSyncProducer(t, nil)
+	capture := func(msg *sarama.ProducerMessage) error {
+		forwarded = append(forwarded, msg)
+		return nil
+	}
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndFail(capture, errors.New("unavailable"))
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(capture)
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(capture)
+	defer producer.Close()
+
+	c := &consumer{
+		option: option{
+			ConsumerRetryMax:     1,
+			ConsumerRetryBackoff: time.Millisecond,
+			RetryDelays:          []time.Duration{5 * time.Second},
+			DeadLetterSuffix:     DefaultDeadLetterSuffix,
+			Log:                  logrus.DefaultLog(),
+		},
+		stages:    map[string]int{"order.retry.5s": 1},
+		forwarder: producer,
+	}
+	s := &session{ctx: context.Background()}
+
+	msg := &sarama.ConsumerMessage{
+		Topic:     "order",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("id"),
+		Value:     []byte("data"),
+		Headers:   []*sarama.RecordHeader{{Key: []byte(header.MessagingRequestID), Value: []byte("request")}},
+	}
+	assert.True(t, c.processMessage(msg.Topic, s, msg, dispatcher))
+
+	assert.Equal(t, 2, handled)
+	assert.Equal(t, 0, failed)
+	assert.Len(t, forwarded, 2)
+
+	retry := forwarded[1]
+	assert.Equal(t, "order.retry.5s", retry.Topic)
+	assert.Equal(t, map[string]string{
+		header.MessagingRequestID:         "request",
+		header.MessagingOriginalTopic:     "order",
+		header.MessagingOriginalPartition: "2",
+		header.MessagingOriginalOffset:    "42",
+		header.MessagingAttempt:           "1",
+		header.MessagingFailureReason:     "boom",
+	}, headers(retry))
+
+	// the retried message fails again and goes to the dead-letter topic
+	value, _ := retry.Value.Encode()
+	msg = &sarama.ConsumerMessage{Topic: retry.Topic, Partition: 0, Offset: 7, Key: []byte("id"), Value: value}
+	for _, h := range retry.Headers {
+		msg.Headers = append(msg.Headers, &sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+	assert.True(t, c.processMessage(msg.Topic, s, msg, dispatcher))
+
+	assert.Equal(t, 4, handled)
+	assert.Equal(t, 1, failed)
+	assert.Len(t, forwarded, 3)
+
+	dead := forwarded[2]
+	assert.Equal(t, "order.dlq", dead.Topic)
+	assert.Equal(t, map[string]string{
+		header.MessagingRequestID:         "request",
+		header.MessagingOriginalTopic:     "order",
+		header.MessagingOriginalPartition: "2",
+		header.MessagingOriginalOffset:    "42",
+		header.MessagingAttempt:           "2",
+		header.MessagingFailureReason:     "boom",
+	}, headers(dead))
+}
+
+func TestAwaitRetry(t *testing.T) {
+	c := &consumer{
+		option: option{RetryDelays: []time.Duration{time.Hour}},
+		stages: map[string]int{"order.retry.1h": 1},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := &session{ctx: ctx}
+
+	assert.True(t, c.awaitRetry(s, &sarama.ConsumerMessage{Topic: "order", Timestamp: time.Now()}))
+	assert.True(t, c.awaitRetry(s, &sarama.ConsumerMessage{Topic: "order.retry.1h", Timestamp: time.Now().Add(-2 * time.Hour)}))
+	assert.False(t, c.awaitRetry(s, &sarama.ConsumerMessage{Topic: "order.retry.1h", Timestamp: time.Now()}))
+}
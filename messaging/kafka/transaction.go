@@ -0,0 +1,180 @@
+package kafka
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+type (
+	// Tx publishes messages inside a kafka transaction, they are only visible to read_committed consumers
+	// once the transaction is committed
+	Tx interface {
+		Publish(topic string, msg messaging.Message) error
+	}
+
+	// TransformFunc turns a consumed message into the messages published by tx, the offset of msg is committed
+	// in the same transaction so that a message is transformed exactly once
+	TransformFunc func(ctx *tntContext.Context, msg messaging.Message, tx Tx) error
+
+	// transactional owns the transactional producer, a transactional id has one transaction open at a time
+	transactional struct {
+		mu       sync.Mutex
+		producer sarama.SyncProducer
+		client   sarama.Client
+		// publisher converts the messages and records them once committed
+		publisher *producer
+	}
+
+	transaction struct {
+		ctx       *tntContext.Context
+		producer  sarama.SyncProducer
+		publisher *producer
+		published []messaging.Message
+		topics    []string
+	}
+
+	transform struct {
+		transform    TransformFunc
+		errorHandler messaging.ErrorHandlerFunc
+	}
+)
+
+// newTransactional connects a transactional producer on its own client, the producers sharing the client
+// of the queue would otherwise share the transactional id and fence each other
+func newTransactional(host []string, cfg sarama.Config, id string, publisher *producer) (*transactional, error) {
+	cfg.Producer.Idempotent = true
+	cfg.Producer.RequiredAcks = sarama.WaitForAll
+	cfg.Producer.Return.Successes = true
+	cfg.Producer.Return.Errors = true
+	cfg.Producer.Transaction.ID = id
+	cfg.Net.MaxOpenRequests = 1
+	if cfg.Producer.Retry.Max < 1 {
+		cfg.Producer.Retry.Max = 1
+	}
+
+	client, err := sarama.NewClient(host, &cfg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect the transactional producer %s", id)
+	}
+
+	syncProducer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, errors.Wrapf(err, "failed to create the transactional producer %s", id)
+	}
+
+	return &transactional{producer: syncProducer, client: client, publisher: publisher}, nil
+}
+
+// run publishes the messages of fn in a transaction, committing the offset of consumed for group when it is
+// not nil. The transaction is aborted when fn or the commit fails.
+func (t *transactional) run(ctx *tntContext.Context, fn func(tx Tx) error, consumed *sarama.ConsumerMessage, group string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.producer.BeginTxn(); err != nil {
+		return errors.Wrap(err, "failed to begin kafka transaction")
+	}
+
+	tx := &transaction{ctx: ctx, producer: t.producer, publisher: t.publisher}
+	if err := fn(tx); err != nil {
+		return t.abort(err)
+	}
+
+	if consumed != nil {
+		if err := t.producer.AddMessageToTxn(consumed, group, nil); err != nil {
+			return t.abort(errors.Wrapf(err, "failed to add the offset %d of %s/%d to kafka transaction",
+				consumed.Offset, consumed.Topic, consumed.Partition))
+		}
+	}
+
+	if err := t.producer.CommitTxn(); err != nil {
+		return t.abort(errors.Wrap(err, "failed to commit kafka transaction"))
+	}
+
+	for i, msg := range tx.published {
+		t.publisher.record(tx.topics[i], msg)
+	}
+	return nil
+}
+
+func (t *transactional) abort(err error) error {
+	if abortErr := t.producer.AbortTxn(); abortErr != nil {
+		t.publisher.log.Error(errors.Wrap(abortErr, "failed to abort kafka transaction"))
+	}
+	return err
+}
+
+func (t *transactional) Close() error {
+	if err := t.producer.Close(); err != nil {
+		return err
+	}
+	return t.client.Close()
+}
+
+func (tx *transaction) Publish(topic string, msg messaging.Message) error {
+	message, segment := tx.publisher.message(tx.ctx, topic, &msg)
+	defer segment.End()
+
+	if _, _, err := tx.producer.SendMessage(message); err != nil {
+		segment.AddAttribute("error", err.Error())
+		return errors.Wrapf(err, "failed to publish message %s to %s", msg.MsgID, topic)
+	}
+
+	tx.published = append(tx.published, msg)
+	tx.topics = append(tx.topics, topic)
+	return nil
+}
+
+// transform consumes msg exactly once: the messages published by the transform and the offset of msg are
+// committed in one transaction. A message that failed ConsumerRetryMax+1 times is passed to the error handler
+// and skipped, committing its offset alone. It reports false when the session ended first.
+func (c *consumer) transform(session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, t transform) bool {
+	messageData := c.getMessage(*msg)
+	ctx := c.context(msg.Topic, messageData)
+	if ctx.Transaction != nil {
+		defer ctx.Transaction.End()
+	}
+
+	var err error
+	for i := 0; i <= c.option.ConsumerRetryMax; i++ {
+		if session.Context().Err() != nil {
+			return false
+		}
+
+		err = c.transactional.run(ctx, func(tx Tx) error {
+			return t.transform(ctx, messageData, tx)
+		}, msg, c.option.ConsumerGroup)
+		if err == nil {
+			return true
+		}
+		c.option.Log.Error("error on transform message from kafka: ", err.Error())
+	}
+
+	if ctx.Transaction != nil {
+		ctx.Transaction.NoticeError(err)
+	}
+	if t.errorHandler != nil {
+		t.errorHandler(ctx, messageData, err)
+	}
+
+	for session.Context().Err() == nil {
+		skip := c.transactional.run(ctx, func(tx Tx) error { return nil }, msg, c.option.ConsumerGroup)
+		if skip == nil {
+			return true
+		}
+
+		c.option.Log.Error(errors.Wrapf(skip, "failed to skip message %s of %s", messageData.MsgID, msg.Topic))
+		select {
+		case <-session.Context().Done():
+		case <-time.After(c.option.ConsumerRetryBackoff):
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs/logrus"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+// txProducer records the transactions of the mock producer
+type txProducer struct {
+	*mocks.SyncProducer
+	events    []string
+	offsets   []int64
+	commitErr error
+}
+
+func (p *txProducer) BeginTxn() error {
+	p.events = append(p.events, "begin")
+	return p.SyncProducer.BeginTxn()
+}
+
+func (p *txProducer) CommitTxn() error {
+	if p.commitErr != nil {
+		err := p.commitErr
+		p.commitErr = nil
+		return err
+	}
+	p.events = append(p.events, "commit")
+	return p.SyncProducer.CommitTxn()
+}
+
+func (p *txProducer) AbortTxn() error {
+	p.events = append(p.events, "abort")
+	return p.SyncProducer.AbortTxn()
+}
+
+func (p *txProducer) AddMessageToTxn(msg *sarama.ConsumerMessage, group string, metadata *string) error {
+	p.offsets = append(p.offsets, msg.Offset)
+	return p.SyncProducer.AddMessageToTxn(msg, group, metadata)
+}
+
+func newTestTransactional(t *testing.T) (*transactional, *txProducer) {
+	cfg := mocks.NewTestConfig()
+	cfg.Producer.Return.Successes = true
+	cfg.Version = sarama.V0_11_0_0
+	cfg.Producer.Idempotent = true
+	cfg.Producer.RequiredAcks = sarama.WaitForAll
+	cfg.Net.MaxOpenRequests = 1
+	cfg.Producer.Transaction.ID = "ledger"
+
+	p := &txProducer{SyncProducer: mocks.NewSyncProducer(t, cfg)}
+	return &transactional{producer: p, publisher: &producer{log: logrus.DefaultLog()}}, p
+}
+
+func TestTransactionalCommit(t *testing.T) {
+	tr, p := newTestTransactional(t)
+	p.ExpectSendMessageAndSucceed()
+	p.ExpectSendMessageAndSucceed()
+
+	err := tr.run(tntContext.New(), func(tx Tx) error {
+		assert.NoError(t, tx.Publish("ledger", messaging.Message{MsgID: "1"}))
+		return tx.Publish("ledger", messaging.Message{MsgID: "2"})
+	}, nil, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"begin", "commit"}, p.events)
+	assert.Empty(t, p.offsets)
+}
+
+func TestTransactionalAbort(t *testing.T) {
+	tr, p := newTestTransactional(t)
+	p.ExpectSendMessageAndSucceed()
+
+	err := tr.run(tntContext.New(), func(tx Tx) error {
+		assert.NoError(t, tx.Publish("ledger", messaging.Message{MsgID: "1"}))
+		return errors.New("boom")
+	}, nil, "")
+	assert.EqualError(t, err, "boom")
+
+	p.commitErr = sarama.ErrOutOfOrderSequenceNumber
+	err = tr.run(tntContext.New(), func(tx Tx) error { return nil }, nil, "")
+	assert.True(t, errors.Is(err, sarama.ErrOutOfOrderSequenceNumber))
+
+	assert.Equal(t, []string{"begin", "abort", "begin", "abort"}, p.events)
+}
+
+func TestTransformCommitsOffsetInTransaction(t *testing.T) {
+	tr, p := newTestTransactional(t)
+	p.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		assert.Equal(t, "ledger", msg.Topic)
+		assert.Equal(t, "req-1", headers(msg)["requestId"])
+		return nil
+	})
+
+	c := &consumer{
+		option:        option{ConsumerGroup: "group", ConsumerRetryMax: 1, Log: logrus.DefaultLog()},
+		transactional: tr,
+		transforms: map[string]transform{"payment": {transform: func(ctx *tntContext.Context, msg messaging.Message, tx Tx) error {
+			return tx.Publish("ledger", messaging.Message{MsgID: msg.MsgID, MsgData: msg.MsgData})
+		}}},
+	}
+
+	cl := &claim{messages: make(chan *sarama.ConsumerMessage, 1)}
+	cl.messages <- &sarama.ConsumerMessage{
+		Topic:   "payment",
+		Offset:  7,
+		Key:     []byte("1"),
+		Headers: []*sarama.RecordHeader{{Key: []byte("requestId"), Value: []byte("req-1")}},
+	}
+	close(cl.messages)
+
+	s := &session{ctx: context.Background()}
+	assert.NoError(t, c.ConsumeClaim(s, cl))
+
+	assert.Equal(t, []string{"begin", "commit"}, p.events)
+	assert.Equal(t, []int64{7}, p.offsets)
+	// the offset is only committed by the transaction
+	assert.Empty(t, s.marked)
+}
+
+func TestTransformSkipsFailedMessage(t *testing.T) {
+	tr, p := newTestTransactional(t)
+
+	var failed error
+	c := &consumer{
+		option:        option{ConsumerGroup: "group", ConsumerRetryMax: 1, Log: logrus.DefaultLog()},
+		transactional: tr,
+	}
+	tf := transform{
+		transform: func(ctx *tntContext.Context, msg messaging.Message, tx Tx) error {
+			return errors.New("boom")
+		},
+		errorHandler: func(ctx *tntContext.Context, msg messaging.Message, err error) {
+			failed = err
+		},
+	}
+
+	s := &session{ctx: context.Background()}
+	assert.True(t, c.transform(s, &sarama.ConsumerMessage{Topic: "payment", Offset: 3}, tf))
+
+	assert.EqualError(t, failed, "boom")
+	assert.Equal(t, []string{"begin", "abort", "begin", "abort", "begin", "commit"}, p.events)
+	assert.Equal(t, []int64{3}, p.offsets)
+}
+
+func TestTransformStopsWhenSessionEnds(t *testing.T) {
+	tr, p := newTestTransactional(t)
+	c := &consumer{option: option{Log: logrus.DefaultLog()}, transactional: tr}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := &session{ctx: ctx}
+	assert.False(t, c.transform(s, &sarama.ConsumerMessage{Topic: "payment"}, transform{}))
+	assert.Empty(t, p.events)
+}
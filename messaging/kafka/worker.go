@@ -0,0 +1,142 @@
+package kafka
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+// workerQueueSize is how many messages wait for a worker before the claim stops reading
+const workerQueueSize = 16
+
+type (
+	// workers process the messages of a claim on ConsumerWorker goroutines. The messages of a key always
+	// go to the same worker so they are handled in order, messages without a key are spread evenly.
+	workers struct {
+		consumer *consumer
+		session  sarama.ConsumerGroupSession
+		queues   []chan job
+		offsets  *offsets
+		next     int
+		wg       sync.WaitGroup
+	}
+
+	job struct {
+		msg        *sarama.ConsumerMessage
+		dispatcher messaging.Dispatcher
+		offset     *offset
+	}
+
+	// offsets marks a message only once every earlier message of the partition is done, so a crash
+	// never skips a message still being processed
+	offsets struct {
+		mu      sync.Mutex
+		session sarama.ConsumerGroupSession
+		pending []*offset
+	}
+
+	offset struct {
+		msg  *sarama.ConsumerMessage
+		done bool
+		ok   bool
+	}
+)
+
+func newWorkers(c *consumer, session sarama.ConsumerGroupSession) *workers {
+	size := c.option.ConsumerWorker
+	if size < 1 {
+		size = 1
+	}
+
+	w := &workers{
+		consumer: c,
+		session:  session,
+		queues:   make([]chan job, size),
+		offsets:  &offsets{session: session},
+	}
+
+	w.wg.Add(size)
+	for i := range w.queues {
+		w.queues[i] = make(chan job, workerQueueSize)
+		go w.run(w.queues[i])
+	}
+
+	return w
+}
+
+// submit queues msg on the worker of its key, it blocks while that worker is saturated and
+// returns false when the session ended first
+func (w *workers) submit(msg *sarama.ConsumerMessage, dispatcher messaging.Dispatcher) bool {
+	j := job{msg: msg, dispatcher: dispatcher, offset: w.offsets.add(msg)}
+
+	select {
+	case w.queues[w.route(msg)] <- j:
+		return true
+	case <-w.session.Context().Done():
+		return false
+	}
+}
+
+// stop waits for the messages being processed, the queued ones are dropped once the session ended
+func (w *workers) stop() {
+	for _, queue := range w.queues {
+		close(queue)
+	}
+	w.wg.Wait()
+}
+
+func (w *workers) route(msg *sarama.ConsumerMessage) int {
+	if len(msg.Key) == 0 {
+		w.next = (w.next + 1) % len(w.queues)
+		return w.next
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write(msg.Key)
+	return int(h.Sum32() % uint32(len(w.queues)))
+}
+
+func (w *workers) run(queue <-chan job) {
+	defer w.wg.Done()
+
+	for j := range queue {
+		if w.session.Context().Err() != nil {
+			continue
+		}
+
+		ok := w.consumer.processMessage(j.msg.Topic, w.session, j.msg, j.dispatcher)
+		w.offsets.complete(j.offset, ok)
+	}
+}
+
+func (o *offsets) add(msg *sarama.ConsumerMessage) *offset {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	off := &offset{msg: msg}
+	o.pending = append(o.pending, off)
+	return off
+}
+
+// complete marks the longest run of done messages at the head of the partition, a message that
+// is not ok blocks the marking so it is consumed again by the next session
+func (o *offsets) complete(off *offset, ok bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	off.done, off.ok = true, ok
+
+	var last *sarama.ConsumerMessage
+	for len(o.pending) > 0 && o.pending[0].done && o.pending[0].ok {
+		last = o.pending[0].msg
+		o.pending[0] = nil
+		o.pending = o.pending[1:]
+	}
+
+	if last != nil {
+		o.session.MarkMessage(last, "")
+	}
+}
@@ -0,0 +1,157 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs/logrus"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+type claim struct {
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *claim) Topic() string                            { return "order" }
+func (c *claim) Partition() int32                         { return 0 }
+func (c *claim) InitialOffset() int64                     { return 0 }
+func (c *claim) HighWaterMarkOffset() int64               { return 0 }
+func (c *claim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func TestConsumeClaimOrderedPerKey(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		handled   = make(map[string][]int64)
+		active    int
+		maxActive int
+	)
+
+	dispatcher := messaging.NewSingleEventDispatcher()
+	dispatcher.AddHandler(func(ctx *tntContext.Context, msg messaging.Message) error {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		active--
+		offset, _ := strconv.ParseInt(string(msg.MsgData), 10, 64)
+		handled[msg.MsgID] = append(handled[msg.MsgID], offset)
+		return nil
+	}, nil)
+
+	c := &consumer{
+		topics: map[string]messaging.Dispatcher{"order": dispatcher},
+		option: option{ConsumerWorker: 4, Log: logrus.DefaultLog()},
+	}
+
+	cl := &claim{messages: make(chan *sarama.ConsumerMessage, 100)}
+	for i := int64(0); i < 100; i++ {
+		cl.messages <- &sarama.ConsumerMessage{
+			Topic:  "order",
+			Offset: i,
+			Key:    []byte(strconv.FormatInt(i%5, 10)),
+			Value:  []byte(strconv.FormatInt(i, 10)),
+		}
+	}
+	close(cl.messages)
+
+	s := &session{ctx: context.Background()}
+	assert.NoError(t, c.ConsumeClaim(s, cl))
+
+	assert.Len(t, handled, 5)
+	for _, offsets := range handled {
+		assert.Len(t, offsets, 20)
+		for i := 1; i < len(offsets); i++ {
+			assert.Less(t, offsets[i-1], offsets[i])
+		}
+	}
+	assert.Greater(t, maxActive, 1)
+	assert.LessOrEqual(t, maxActive, 4)
+
+	// offsets are only marked forward
+	assert.NotEmpty(t, s.marked)
+	for i := 1; i < len(s.marked); i++ {
+		assert.Less(t, s.marked[i-1].Offset, s.marked[i].Offset)
+	}
+	assert.Equal(t, int64(99), s.marked[len(s.marked)-1].Offset)
+}
+
+func TestOffsetsWaitForEarlierMessages(t *testing.T) {
+	s := &session{ctx: context.Background()}
+	o := &offsets{session: s}
+
+	first := o.add(&sarama.ConsumerMessage{Offset: 1})
+	second := o.add(&sarama.ConsumerMessage{Offset: 2})
+	third := o.add(&sarama.ConsumerMessage{Offset: 3})
+
+	o.complete(second, true)
+	assert.Empty(t, s.marked)
+
+	o.complete(first, true)
+	assert.Len(t, s.marked, 1)
+	assert.Equal(t, int64(2), s.marked[0].Offset)
+
+	o.complete(third, true)
+	assert.Equal(t, int64(3), s.marked[1].Offset)
+}
+
+func TestOffsetsStopAtUnfinishedMessage(t *testing.T) {
+	s := &session{ctx: context.Background()}
+	o := &offsets{session: s}
+
+	first := o.add(&sarama.ConsumerMessage{Offset: 1})
+	second := o.add(&sarama.ConsumerMessage{Offset: 2})
+
+	o.complete(first, false)
+	o.complete(second, true)
+	assert.Empty(t, s.marked)
+}
+
+func TestConsumeClaimStopsWhenSessionEnds(t *testing.T) {
+	release := make(chan struct{})
+	dispatcher := messaging.NewSingleEventDispatcher()
+	dispatcher.AddHandler(func(ctx *tntContext.Context, msg messaging.Message) error {
+		<-release
+		return errors.New("unused")
+	}, nil)
+
+	c := &consumer{
+		topics: map[string]messaging.Dispatcher{"order": dispatcher},
+		option: option{ConsumerWorker: 1, Log: logrus.DefaultLog()},
+	}
+
+	// the claim never closes, the saturated worker must not block the end of the session
+	cl := &claim{messages: make(chan *sarama.ConsumerMessage, workerQueueSize+2)}
+	for i := int64(0); i < workerQueueSize+2; i++ {
+		cl.messages <- &sarama.ConsumerMessage{Topic: "order", Offset: i, Key: []byte("a")}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.ConsumeClaim(&session{ctx: ctx}, cl) }()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	close(release)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("ConsumeClaim did not return")
+	}
+}
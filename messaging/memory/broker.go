@@ -0,0 +1,302 @@
+package memory
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+// publishedRetention is how many messages of a topic Published returns at most, the oldest are dropped first
+const publishedRetention = 10000
+
+type (
+	// Broker keeps the topics in memory, a message is removed once every consumer group of its topic
+	// committed past it. A group joining later starts from the oldest message left, like a kafka
+	// consumer group without committed offsets. A message published while no group listens to its
+	// topic is only kept for Published.
+	Broker struct {
+		partitions int
+
+		mu     sync.Mutex
+		topics map[string]*topic
+		groups map[string]*group
+		// changed is closed and replaced every time a message is published or consumed
+		changed chan struct{}
+	}
+	topic struct {
+		partitions [][]record
+		// base is the offset of the first record left in every partition
+		base      []int
+		published []messaging.Message
+	}
+	record struct {
+		msg messaging.Message
+		due time.Time
+	}
+	group struct {
+		// members are the queues listening to a topic, partition p is consumed by members[p%len(members)]
+		members  map[string][]*queue
+		offsets  map[string][]int
+		running  map[string][]bool
+		inflight int
+	}
+)
+
+// NewBroker returns an empty Broker whose topics have the given number of partitions,
+// DefaultPartitions when it is lower than 1. Messages are partitioned by
+// messaging.PartitionKey, or MsgID without one.
+func NewBroker(partitions int) *Broker {
+	if partitions < 1 {
+		partitions = DefaultPartitions
+	}
+
+	return &Broker{
+		partitions: partitions,
+		topics:     make(map[string]*topic),
+		groups:     make(map[string]*group),
+		changed:    make(chan struct{}),
+	}
+}
+
+// topic must be called with mu held
+func (b *Broker) topic(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{partitions: make([][]record, b.partitions), base: make([]int, b.partitions)}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// group must be called with mu held
+func (b *Broker) group(name string) *group {
+	g, ok := b.groups[name]
+	if !ok {
+		g = &group{
+			members: make(map[string][]*queue),
+			offsets: make(map[string][]int),
+			running: make(map[string][]bool),
+		}
+		b.groups[name] = g
+	}
+	return g
+}
+
+// notify wakes everyone waiting on changed, it must be called with mu held
+func (b *Broker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// partition hashes the messaging.PartitionKey of msg, or its MsgID without one
+func (b *Broker) partition(msg messaging.Message) int {
+	key := msg.MsgID
+	if partitionKey := msg.MsgAttributes[messaging.PartitionKey]; partitionKey != "" {
+		key = partitionKey
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(b.partitions))
+}
+
+func (b *Broker) append(name string, msg messaging.Message, due time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(name)
+	p := b.partition(msg)
+	if b.listened(name) {
+		t.partitions[p] = append(t.partitions[p], record{msg: msg, due: due})
+	} else {
+		// nobody would commit past the record, the partition moves on without it
+		t.base[p]++
+	}
+	t.published = append(t.published, msg)
+	if n := len(t.published) - publishedRetention; n > 0 {
+		t.published = append(t.published[:0:0], t.published[n:]...)
+	}
+	b.notify()
+}
+
+func (b *Broker) published(name string) []messaging.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[name]
+	if !ok {
+		return nil
+	}
+
+	published := make([]messaging.Message, len(t.published))
+	copy(published, t.published)
+	return published
+}
+
+// join adds q to the members of its group for topic and starts consuming the partitions nobody consumes yet
+func (b *Broker) join(q *queue, name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(name)
+	g := b.group(q.option.ConsumerGroup)
+	g.members[name] = append(g.members[name], q)
+	if g.offsets[name] == nil {
+		g.offsets[name] = append([]int(nil), t.base...)
+		g.running[name] = make([]bool, b.partitions)
+	}
+
+	for p, running := range g.running[name] {
+		if !running {
+			g.running[name][p] = true
+			go b.consume(g, name, p)
+		}
+	}
+	b.notify()
+}
+
+// leave removes q from every topic of its group, the partitions it consumed move to the remaining members
+func (b *Broker) leave(q *queue) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g, ok := b.groups[q.option.ConsumerGroup]
+	if !ok {
+		return
+	}
+
+	for name, members := range g.members {
+		remaining := members[:0]
+		for _, member := range members {
+			if member != q {
+				remaining = append(remaining, member)
+			}
+		}
+		g.members[name] = remaining
+	}
+	b.notify()
+}
+
+// consume delivers the messages of a partition in order to the member of g it is assigned to,
+// it returns once g has no member left for the topic and resumes from the same offset on the next join
+func (b *Broker) consume(g *group, name string, p int) {
+	for {
+		b.mu.Lock()
+		members := g.members[name]
+		if len(members) == 0 {
+			g.running[name][p] = false
+			b.mu.Unlock()
+			return
+		}
+
+		t := b.topics[name]
+		offset := g.offsets[name][p] - t.base[p]
+		if offset >= len(t.partitions[p]) {
+			changed := b.changed
+			b.mu.Unlock()
+			<-changed
+			continue
+		}
+
+		rec := t.partitions[p][offset]
+		q := members[p%len(members)]
+		q.wg.Add(1)
+		g.inflight++
+		b.mu.Unlock()
+
+		delivered := q.deliver(name, rec)
+
+		b.mu.Lock()
+		if delivered {
+			g.offsets[name][p]++
+			b.trim(name, p)
+		}
+		g.inflight--
+		b.notify()
+		b.mu.Unlock()
+		q.wg.Done()
+	}
+}
+
+// listened reports whether a group has offsets for topic name, it must be called with mu held
+func (b *Broker) listened(name string) bool {
+	for _, g := range b.groups {
+		if _, ok := g.offsets[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// trim removes the records of partition p every group listening to topic name committed,
+// it must be called with mu held
+func (b *Broker) trim(name string, p int) {
+	committed := -1
+	for _, g := range b.groups {
+		if offsets, ok := g.offsets[name]; ok && (committed < 0 || offsets[p] < committed) {
+			committed = offsets[p]
+		}
+	}
+
+	t := b.topics[name]
+	n := committed - t.base[p]
+	if n <= 0 {
+		return
+	}
+
+	records := t.partitions[p]
+	for i := range records[:n] {
+		records[i] = record{}
+	}
+	t.partitions[p] = records[n:]
+	t.base[p] = committed
+}
+
+// idle reports whether the group caught up with every topic it listens to, it must be called with mu held
+func (b *Broker) idle(name string) bool {
+	g, ok := b.groups[name]
+	if !ok {
+		return true
+	}
+	if g.inflight > 0 {
+		return false
+	}
+
+	for topic, members := range g.members {
+		if len(members) == 0 {
+			continue
+		}
+		t := b.topics[topic]
+		for p, records := range t.partitions {
+			if g.offsets[topic][p] < t.base[p]+len(records) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// wait blocks until done returns true, done is called with mu held
+func (b *Broker) wait(done func() bool, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		b.mu.Lock()
+		ok := done()
+		changed := b.changed
+		b.mu.Unlock()
+
+		if ok {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return false
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/common/constant/header"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs/logrus"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+var (
+	ErrClosed = errors.New("memory queue is closed")
+	// ErrWaitTimeout is returned by the assertion helpers when the condition is not met in time
+	ErrWaitTimeout = errors.New("timed out waiting on memory queue")
+)
+
+type (
+	// Queue is a messaging.Queue kept in the memory of the process, it is meant for tests and single process
+	// deployments. The messages of a partition are delivered in order, one at a time, and are lost on exit.
+	Queue interface {
+		messaging.Queue
+		// Published returns the last 10000 messages successfully published to topic, in publish order
+		Published(topic string) []messaging.Message
+		// WaitForPublished waits until at least count messages are published to topic and returns them
+		WaitForPublished(topic string, count int, timeout time.Duration) ([]messaging.Message, error)
+		// WaitForIdle waits until the consumer group processed every message of the topics it listens to
+		WaitForIdle(timeout time.Duration) error
+		// FailPublish makes the next times publishes to topic fail with err
+		FailPublish(topic string, err error, times int)
+	}
+
+	queue struct {
+		option option
+		broker *Broker
+
+		mu        sync.Mutex
+		topics    map[string]messaging.Dispatcher
+		failures  map[string][]error
+		listening bool
+		closed    bool
+		stop      chan struct{}
+		wg        sync.WaitGroup
+	}
+)
+
+// New returns an in-memory Queue. The messages are dispatched like the kafka consumer does: every message
+// is retried up to ConsumerRetryMax times before being dispatched as a messaging.Error.
+func New(options ...Option) (Queue, error) {
+	o := option{
+		Partitions:       DefaultPartitions,
+		ConsumerGroup:    DefaultConsumerGroup,
+		ConsumerRetryMax: DefaultConsumerRetryMax,
+		Log:              logrus.DefaultLog(),
+	}
+
+	for _, opt := range options {
+		opt.Apply(&o)
+	}
+
+	if err := validate(o); err != nil {
+		return nil, err
+	}
+
+	broker := o.Broker
+	if broker == nil {
+		broker = NewBroker(o.Partitions)
+	}
+
+	return &queue{
+		option:   o,
+		broker:   broker,
+		topics:   make(map[string]messaging.Dispatcher),
+		failures: make(map[string][]error),
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+func (q *queue) Subscribe(topic string, dispatcher messaging.Dispatcher) error {
+	if q.option.WithoutConsumer {
+		return errors.New("memory queue is initialize without consumer")
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.listening {
+		return errors.Errorf("failed to subscribe to topic %s, memory queue is already listening", topic)
+	}
+
+	q.topics[topic] = dispatcher
+	return nil
+}
+
+func (q *queue) Listen() {
+	if q.option.WithoutConsumer {
+		q.option.Log.Error(errors.New("memory queue is initialize without consumer"))
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		q.option.Log.Error(ErrClosed)
+		return
+	}
+
+	if q.listening {
+		q.option.Log.Info("already listening to memory queue")
+		return
+	}
+
+	for topic := range q.topics {
+		q.broker.join(q, topic)
+	}
+
+	q.option.Log.Info("Start Listening")
+	q.listening = true
+}
+
+func (q *queue) Publish(topic string, msg messaging.Message) error {
+	return q.PublishWithContext(tntContext.New(), topic, msg)
+}
+
+func (q *queue) PublishWithContext(ctx *tntContext.Context, topic string, msg messaging.Message) error {
+	if msg.MsgID == "" {
+		msg.MsgID = uuid.New().String()
+	}
+
+	attributes := make(map[string]string, len(msg.MsgAttributes)+3)
+	if ctx != nil {
+		mandatory := ctx.MandatoryRequest()
+		if mandatory.RequestID() != "" {
+			attributes[header.MessagingRequestID] = mandatory.RequestID()
+		}
+		if mandatory.Token() != "" {
+			attributes[header.MessagingAuthorization] = mandatory.Token()
+		}
+	}
+	// the attributes set by the caller win over the ones of ctx
+	for key, attr := range msg.MsgAttributes {
+		attributes[key] = attr
+	}
+	attributes[messaging.PublishTime] = time.Now().Format(time.RFC3339)
+	msg.MsgAttributes = attributes
+
+	if err := q.failure(topic, msg); err != nil {
+		return errors.Wrapf(err, "failed to publish message %s to topic %s", msg.MsgID, topic)
+	}
+
+	q.broker.append(topic, msg, time.Now().Add(q.option.DeliveryDelay))
+	return nil
+}
+
+func (q *queue) failure(topic string, msg messaging.Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+
+	if failures := q.failures[topic]; len(failures) > 0 {
+		q.failures[topic] = failures[1:]
+		return failures[0]
+	}
+
+	if q.option.Failure != nil {
+		return q.option.Failure(topic, msg)
+	}
+	return nil
+}
+
+func (q *queue) Ping(ctx *tntContext.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Close stops consuming, waiting for the messages being processed, the partitions of this queue move to the
+// other queues of its consumer group.
+func (q *queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.stop)
+	q.mu.Unlock()
+
+	q.broker.leave(q)
+	q.wg.Wait()
+	return nil
+}
+
+func (q *queue) Published(topic string) []messaging.Message {
+	return q.broker.published(topic)
+}
+
+func (q *queue) WaitForPublished(topic string, count int, timeout time.Duration) ([]messaging.Message, error) {
+	ok := q.broker.wait(func() bool {
+		t, ok := q.broker.topics[topic]
+		return ok && len(t.published) >= count
+	}, timeout)
+
+	published := q.broker.published(topic)
+	if !ok {
+		return published, errors.Wrapf(ErrWaitTimeout, "%d of %d messages published to topic %s", len(published), count, topic)
+	}
+	return published, nil
+}
+
+func (q *queue) WaitForIdle(timeout time.Duration) error {
+	ok := q.broker.wait(func() bool {
+		return q.broker.idle(q.option.ConsumerGroup)
+	}, timeout)
+
+	if !ok {
+		return errors.Wrapf(ErrWaitTimeout, "consumer group %s is not idle", q.option.ConsumerGroup)
+	}
+	return nil
+}
+
+func (q *queue) FailPublish(topic string, err error, times int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := 0; i < times; i++ {
+		q.failures[topic] = append(q.failures[topic], err)
+	}
+}
+
+// deliver waits until rec is due and processes it, it reports false when the queue is closed first
+func (q *queue) deliver(topic string, rec record) bool {
+	if wait := time.Until(rec.due); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-q.stop:
+			return false
+		case <-timer.C:
+		}
+	}
+
+	q.mu.Lock()
+	dispatcher := q.topics[topic]
+	q.mu.Unlock()
+
+	q.process(topic, rec.msg, dispatcher)
+	return true
+}
+
+// process dispatches msg, retrying up to ConsumerRetryMax times before dispatching the error, like the kafka consumer
+func (q *queue) process(topic string, msg messaging.Message, dispatcher messaging.Dispatcher) {
+	requestID := msg.MsgAttributes[header.MessagingRequestID]
+	msgType := msg.MsgAttributes[messaging.MessageType]
+
+	ctx := tntContext.NewWithContext(context.Background())
+	ctx.SetMandatory(tntContext.NewMessagingSource(msg.MsgAttributes))
+
+	var err error
+	var message = messaging.DispatchDTO{
+		Type:      messaging.Handle,
+		Source:    fmt.Sprintf("Memory - %s", topic),
+		Topic:     topic,
+		RequestID: requestID,
+		MsgType:   msgType,
+		Msg:       msg,
+		Log:       q.option.Log,
+		Context:   ctx,
+	}
+	for i := 0; i <= q.option.ConsumerRetryMax; i++ {
+		message.Err = nil
+		if err = dispatcher.Dispatch(message); err == nil {
+			return
+		}
+
+		q.option.Log.Error("error on dispatch message from memory queue: ", err.Error())
+		message.Err = err
+	}
+
+	errMessage := message
+	errMessage.Type = messaging.Error
+	errMessage.Err = err
+	_ = dispatcher.Dispatch(errMessage)
+}
@@ -0,0 +1,336 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/common/constant/header"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	fail  int
+	delay time.Duration
+	dtos  []messaging.DispatchDTO
+}
+
+func (r *recorder) AddHandler(messaging.HandlerFunc, messaging.ErrorHandlerFunc, ...string) {}
+
+func (r *recorder) Use(...messaging.MiddlewareFunc) {}
+
+func (r *recorder) Dispatch(dto messaging.DispatchDTO) error {
+	time.Sleep(r.delay)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.dtos = append(r.dtos, dto)
+	if dto.Type == messaging.Handle && r.fail > 0 {
+		r.fail--
+		return errors.New("handler failed")
+	}
+	return nil
+}
+
+func (r *recorder) received() []messaging.DispatchDTO {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]messaging.DispatchDTO(nil), r.dtos...)
+}
+
+func newQueue(t *testing.T, options ...Option) Queue {
+	q, err := New(options...)
+	assert.NoError(t, err)
+	return q
+}
+
+func TestPublishAndConsume(t *testing.T) {
+	q := newQueue(t)
+	defer q.Close()
+
+	r := &recorder{}
+	assert.NoError(t, q.Subscribe("orders", r))
+	q.Listen()
+
+	ctx := tntContext.New()
+	ctx.SetMandatory(tntContext.NewMessagingSource(map[string]string{header.MessagingRequestID: "req-1"}))
+	assert.NoError(t, q.PublishWithContext(ctx, "orders", messaging.Message{
+		MsgID:         "1",
+		MsgData:       []byte(`{"id":1}`),
+		MsgAttributes: map[string]string{messaging.MessageType: "created"},
+	}))
+
+	assert.NoError(t, q.WaitForIdle(time.Second))
+
+	dtos := r.received()
+	if assert.Len(t, dtos, 1) {
+		assert.Equal(t, messaging.Handle, dtos[0].Type)
+		assert.Equal(t, "Memory - orders", dtos[0].Source)
+		assert.Equal(t, "orders", dtos[0].Topic)
+		assert.Equal(t, "created", dtos[0].MsgType)
+		assert.Equal(t, "req-1", dtos[0].RequestID)
+		assert.Equal(t, "req-1", dtos[0].Context.MandatoryRequest().RequestID())
+		assert.Equal(t, []byte(`{"id":1}`), dtos[0].Msg.MsgData)
+		assert.NotEmpty(t, dtos[0].Msg.MsgAttributes[messaging.PublishTime])
+	}
+}
+
+func TestRetryThenError(t *testing.T) {
+	q := newQueue(t, WithConsumerRetryMax(2))
+	defer q.Close()
+
+	r := &recorder{fail: 10}
+	assert.NoError(t, q.Subscribe("orders", r))
+	q.Listen()
+
+	assert.NoError(t, q.Publish("orders", messaging.Message{MsgID: "1"}))
+	assert.NoError(t, q.WaitForIdle(time.Second))
+
+	dtos := r.received()
+	if assert.Len(t, dtos, 4) {
+		for _, dto := range dtos[:3] {
+			assert.Equal(t, messaging.Handle, dto.Type)
+		}
+		assert.Equal(t, messaging.Error, dtos[3].Type)
+		assert.EqualError(t, dtos[3].Err, "handler failed")
+	}
+}
+
+func TestOrderPerPartition(t *testing.T) {
+	q := newQueue(t, WithPartitions(4))
+	defer q.Close()
+
+	r := &recorder{delay: time.Millisecond}
+	assert.NoError(t, q.Subscribe("orders", r))
+	q.Listen()
+
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, q.Publish("orders", messaging.Message{
+			MsgID:   "same-key",
+			MsgData: []byte{byte(i)},
+		}))
+	}
+	assert.NoError(t, q.WaitForIdle(time.Second))
+
+	dtos := r.received()
+	if assert.Len(t, dtos, 20) {
+		for i, dto := range dtos {
+			assert.Equal(t, []byte{byte(i)}, dto.Msg.MsgData)
+		}
+	}
+}
+
+func TestConsumerGroups(t *testing.T) {
+	broker := NewBroker(4)
+
+	var (
+		groupA = []*recorder{{}, {}}
+		groupB = &recorder{}
+		queues []Queue
+	)
+	for _, r := range groupA {
+		q := newQueue(t, WithBroker(broker), WithConsumerGroup("a"))
+		assert.NoError(t, q.Subscribe("orders", r))
+		queues = append(queues, q)
+	}
+	q := newQueue(t, WithBroker(broker), WithConsumerGroup("b"))
+	assert.NoError(t, q.Subscribe("orders", groupB))
+	queues = append(queues, q)
+
+	for _, q := range queues {
+		q.Listen()
+		defer q.Close()
+	}
+
+	for i := 0; i < 40; i++ {
+		assert.NoError(t, queues[0].Publish("orders", messaging.Message{MsgData: []byte{byte(i)}}))
+	}
+	for _, q := range queues {
+		assert.NoError(t, q.WaitForIdle(time.Second))
+	}
+
+	assert.Len(t, groupB.received(), 40)
+	assert.Equal(t, 40, len(groupA[0].received())+len(groupA[1].received()))
+	assert.NotEmpty(t, groupA[0].received())
+	assert.NotEmpty(t, groupA[1].received())
+}
+
+func TestCloseMovesPartitions(t *testing.T) {
+	broker := NewBroker(2)
+	first, second := &recorder{}, &recorder{}
+
+	q1 := newQueue(t, WithBroker(broker))
+	assert.NoError(t, q1.Subscribe("orders", first))
+	q1.Listen()
+
+	q2 := newQueue(t, WithBroker(broker))
+	assert.NoError(t, q2.Subscribe("orders", second))
+	q2.Listen()
+	defer q2.Close()
+
+	assert.NoError(t, q1.Close())
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, q2.Publish("orders", messaging.Message{}))
+	}
+	assert.NoError(t, q2.WaitForIdle(time.Second))
+
+	assert.Empty(t, first.received())
+	assert.Len(t, second.received(), 10)
+}
+
+// retained counts the records left in the partitions of topic name
+func retained(broker *Broker, name string) int {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	n := 0
+	for _, records := range broker.topics[name].partitions {
+		n += len(records)
+	}
+	return n
+}
+
+func TestRecordsRemovedOnceEveryGroupCommitted(t *testing.T) {
+	broker := NewBroker(2)
+
+	a := newQueue(t, WithBroker(broker), WithConsumerGroup("a"))
+	assert.NoError(t, a.Subscribe("orders", &recorder{}))
+	a.Listen()
+	defer a.Close()
+
+	// group b keeps its offsets while none of its members listens
+	b := newQueue(t, WithBroker(broker), WithConsumerGroup("b"))
+	assert.NoError(t, b.Subscribe("orders", &recorder{}))
+	b.Listen()
+	assert.NoError(t, b.Close())
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, a.Publish("orders", messaging.Message{MsgData: []byte{byte(i)}}))
+	}
+	assert.NoError(t, a.WaitForIdle(time.Second))
+	assert.Equal(t, 10, retained(broker, "orders"))
+
+	second := &recorder{}
+	b = newQueue(t, WithBroker(broker), WithConsumerGroup("b"))
+	assert.NoError(t, b.Subscribe("orders", second))
+	b.Listen()
+	defer b.Close()
+	assert.NoError(t, b.WaitForIdle(time.Second))
+	assert.Len(t, second.received(), 10)
+	assert.Equal(t, 0, retained(broker, "orders"))
+	assert.Len(t, a.Published("orders"), 10)
+
+	// a group joining later starts from the oldest message left
+	late := &recorder{}
+	c := newQueue(t, WithBroker(broker), WithConsumerGroup("c"))
+	assert.NoError(t, c.Subscribe("orders", late))
+	c.Listen()
+	defer c.Close()
+	assert.NoError(t, a.Publish("orders", messaging.Message{MsgData: []byte{10}}))
+	assert.NoError(t, c.WaitForIdle(time.Second))
+	assert.Len(t, late.received(), 1)
+	assert.Equal(t, []byte{10}, late.received()[0].Msg.MsgData)
+}
+
+func TestDeliveryDelay(t *testing.T) {
+	q := newQueue(t, WithDeliveryDelay(100*time.Millisecond))
+	defer q.Close()
+
+	r := &recorder{}
+	assert.NoError(t, q.Subscribe("orders", r))
+	q.Listen()
+
+	start := time.Now()
+	assert.NoError(t, q.Publish("orders", messaging.Message{}))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Empty(t, r.received())
+
+	assert.NoError(t, q.WaitForIdle(time.Second))
+	assert.Len(t, r.received(), 1)
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+}
+
+func TestFailureInjection(t *testing.T) {
+	injected := errors.New("broker down")
+	q := newQueue(t, WithoutConsumer(), WithFailure(func(topic string, msg messaging.Message) error {
+		if msg.MsgID == "poison" {
+			return injected
+		}
+		return nil
+	}))
+	defer q.Close()
+
+	q.FailPublish("orders", injected, 2)
+
+	assert.Error(t, q.Publish("orders", messaging.Message{MsgID: "1"}))
+	assert.Error(t, q.Publish("orders", messaging.Message{MsgID: "2"}))
+	assert.NoError(t, q.Publish("orders", messaging.Message{MsgID: "3"}))
+	assert.Error(t, q.Publish("orders", messaging.Message{MsgID: "poison"}))
+
+	published := q.Published("orders")
+	if assert.Len(t, published, 1) {
+		assert.Equal(t, "3", published[0].MsgID)
+	}
+}
+
+func TestWaitForPublished(t *testing.T) {
+	q := newQueue(t, WithoutConsumer())
+	defer q.Close()
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(10 * time.Millisecond)
+			_ = q.Publish("orders", messaging.Message{})
+		}
+	}()
+
+	published, err := q.WaitForPublished("orders", 3, time.Second)
+	assert.NoError(t, err)
+	assert.Len(t, published, 3)
+
+	_, err = q.WaitForPublished("orders", 4, 20*time.Millisecond)
+	assert.True(t, errors.Is(err, ErrWaitTimeout))
+}
+
+func TestClosed(t *testing.T) {
+	q := newQueue(t)
+	assert.NoError(t, q.Close())
+
+	assert.Equal(t, ErrClosed, q.Ping(tntContext.New()))
+	assert.Error(t, q.Publish("orders", messaging.Message{}))
+}
+
+func TestRecordsNotKeptWithoutGroup(t *testing.T) {
+	broker := NewBroker(2)
+
+	producer := newQueue(t, WithBroker(broker), WithoutConsumer())
+	defer producer.Close()
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, producer.Publish("orders", messaging.Message{MsgData: []byte{byte(i)}}))
+	}
+	assert.Equal(t, 0, retained(broker, "orders"))
+	assert.Len(t, producer.Published("orders"), 10)
+
+	// a group joining afterwards only receives the messages published from then on
+	r := &recorder{}
+	q := newQueue(t, WithBroker(broker), WithConsumerGroup("a"))
+	assert.NoError(t, q.Subscribe("orders", r))
+	q.Listen()
+	defer q.Close()
+
+	assert.NoError(t, producer.Publish("orders", messaging.Message{MsgData: []byte{10}}))
+	assert.NoError(t, q.WaitForIdle(time.Second))
+	if assert.Len(t, r.received(), 1) {
+		assert.Equal(t, []byte{10}, r.received()[0].Msg.MsgData)
+	}
+	assert.Equal(t, 0, retained(broker, "orders"))
+}
@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/logs"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+const (
+	DefaultConsumerGroup    = "default"
+	DefaultConsumerRetryMax = 3
+	DefaultPartitions       = 3
+)
+
+// FailureFunc is called before a message is stored, a non nil error fails the publish with that error
+type FailureFunc func(topic string, msg messaging.Message) error
+
+type (
+	Option interface {
+		Apply(o *option)
+	}
+	option struct {
+		Broker           *Broker
+		Partitions       int
+		ConsumerGroup    string
+		ConsumerRetryMax int
+		DeliveryDelay    time.Duration
+		Failure          FailureFunc
+		Log              logs.Logger
+		WithoutConsumer  bool
+	}
+)
+
+func validate(option option) error {
+	if option.Partitions < 1 {
+		return errors.New("invalid memory queue partitions")
+	}
+	if !option.WithoutConsumer && option.ConsumerGroup == "" {
+		return errors.New("invalid memory queue consumer group")
+	}
+	if option.DeliveryDelay < 0 {
+		return errors.New("invalid memory queue delivery delay")
+	}
+	return nil
+}
+
+type withBroker struct{ *Broker }
+
+// WithBroker shares the topics of broker with the other queues using it, queues of the same consumer group
+// split the partitions between them while every group receives every message. Each queue gets its own
+// broker by default.
+func WithBroker(broker *Broker) Option {
+	return withBroker{broker}
+}
+
+func (w withBroker) Apply(o *option) {
+	o.Broker = w.Broker
+}
+
+type withPartitions int
+
+// WithPartitions is how many partitions the topics of the broker created by New have, it is ignored with WithBroker.
+func WithPartitions(partitions int) Option {
+	return withPartitions(partitions)
+}
+
+func (w withPartitions) Apply(o *option) {
+	o.Partitions = int(w)
+}
+
+type withConsumerGroup string
+
+func WithConsumerGroup(group string) Option {
+	return withConsumerGroup(group)
+}
+
+func (w withConsumerGroup) Apply(o *option) {
+	o.ConsumerGroup = string(w)
+}
+
+type withConsumerRetryMax int
+
+func WithConsumerRetryMax(maxRetry int) Option {
+	return withConsumerRetryMax(maxRetry)
+}
+
+func (w withConsumerRetryMax) Apply(o *option) {
+	o.ConsumerRetryMax = int(w)
+}
+
+type withDeliveryDelay time.Duration
+
+// WithDeliveryDelay is how long a message published by this queue waits before it is delivered.
+func WithDeliveryDelay(delay time.Duration) Option {
+	return withDeliveryDelay(delay)
+}
+
+func (w withDeliveryDelay) Apply(o *option) {
+	o.DeliveryDelay = time.Duration(w)
+}
+
+type withFailure FailureFunc
+
+// WithFailure injects publish failures, see FailureFunc and Queue.FailPublish.
+func WithFailure(failure FailureFunc) Option {
+	return withFailure(failure)
+}
+
+func (w withFailure) Apply(o *option) {
+	o.Failure = FailureFunc(w)
+}
+
+type withLog struct{ logs.Logger }
+
+func WithLog(logger logs.Logger) Option {
+	return withLog{logger}
+}
+
+func (w withLog) Apply(o *option) {
+	o.Log = w.Logger
+}
+
+type withoutConsumer bool
+
+func WithoutConsumer() Option {
+	return withoutConsumer(true)
+}
+
+func (w withoutConsumer) Apply(o *option) {
+	o.WithoutConsumer = bool(w)
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Dert12318/Utilities/apm"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+const EventConsume = "messaging_consume"
+
+// Apm runs every message in a transaction continuing the trace carried by its attributes, it keeps
+// the transaction already started by the queue, e.g. the kafka consumer created with WithApm.
+func Apm(a apm.APM) messaging.MiddlewareFunc {
+	return func(next messaging.MiddlewareHandlerFunc) messaging.MiddlewareHandlerFunc {
+		return func(ctx *tntContext.Context, dto messaging.DispatchDTO) error {
+			if ctx.Transaction != nil {
+				return next(ctx, dto)
+			}
+
+			trace := make(http.Header, len(dto.Msg.MsgAttributes))
+			for key, attr := range dto.Msg.MsgAttributes {
+				trace.Set(key, attr)
+			}
+
+			txn := apm.StartTransactionFromHeaders(a, fmt.Sprintf("%s:%s", EventConsume, dto.Topic), trace)
+			defer txn.End()
+
+			txn.AddAttribute("msg_id", dto.Msg.MsgID)
+			txn.AddAttribute("msg_type", dto.MsgType)
+			if dto.Err != nil {
+				txn.NoticeError(dto.Err)
+			}
+
+			parent := ctx.Ctx
+			ctx.Transaction = txn
+			ctx.Ctx = txn.NewContext(parent)
+			defer func() { ctx.Transaction, ctx.Ctx = nil, parent }()
+
+			err := next(ctx, dto)
+			if err != nil {
+				txn.NoticeError(err)
+			}
+
+			return err
+		}
+	}
+}
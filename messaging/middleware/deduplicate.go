@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/cache"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+const (
+	DefaultDeduplicatePrefix   = "messaging:dedup"
+	DefaultDeduplicateTTL      = 24 * time.Hour
+	DefaultDeduplicateClaimTTL = 5 * time.Minute
+
+	// the values of a key, a message is processing while its first delivery runs
+	processing = "processing"
+	handled    = "handled"
+)
+
+// ErrInProgress is returned for a duplicate that arrives while the first delivery is running,
+// it is retried like any other error since the first delivery may still fail
+var ErrInProgress = errors.New("message is already being handled")
+
+type DeduplicateOption struct {
+	// Prefix namespaces the keys, defaults to DefaultDeduplicatePrefix
+	Prefix string
+	// TTL is how long a MsgID is remembered once handled, defaults to DefaultDeduplicateTTL
+	TTL time.Duration
+	// ClaimTTL bounds how long a delivery holds the MsgID while it runs, so a consumer that crashes mid-delivery
+	// does not block the message until TTL. It should exceed the longest handler, defaults to DefaultDeduplicateClaimTTL
+	ClaimTTL time.Duration
+}
+
+// Deduplicate skips the messages whose MsgID was already handled successfully, it claims the MsgID with SetNX
+// before calling the handler, marks it handled once the handler succeeds and releases it when the handler fails
+// so the message can be retried. A duplicate of a message that is still being handled fails with ErrInProgress.
+// When the cache is unavailable the message is handled anyway.
+func Deduplicate(c cache.Cache, option *DeduplicateOption) messaging.MiddlewareFunc {
+	o := DeduplicateOption{Prefix: DefaultDeduplicatePrefix, TTL: DefaultDeduplicateTTL, ClaimTTL: DefaultDeduplicateClaimTTL}
+	if option != nil {
+		if option.Prefix != "" {
+			o.Prefix = option.Prefix
+		}
+		if option.TTL > 0 {
+			o.TTL = option.TTL
+		}
+		if option.ClaimTTL > 0 {
+			o.ClaimTTL = option.ClaimTTL
+		}
+	}
+
+	return func(next messaging.MiddlewareHandlerFunc) messaging.MiddlewareHandlerFunc {
+		return func(ctx *tntContext.Context, dto messaging.DispatchDTO) error {
+			if dto.Type != messaging.Handle || dto.Msg.MsgID == "" {
+				return next(ctx, dto)
+			}
+
+			cc := context.Background()
+			if ctx.Ctx != nil {
+				cc = ctx.Ctx
+			}
+
+			key := o.Prefix + ":" + dto.Topic + ":" + dto.Msg.MsgID
+			claimed, err := c.SetNX(&cc, key, processing, o.ClaimTTL)
+			if err != nil {
+				if dto.Log != nil {
+					dto.Log.Error("failed to deduplicate message ", dto.Msg.MsgID, ": ", err.Error())
+				}
+				return next(ctx, dto)
+			}
+
+			if !claimed {
+				// the key expiring in between means the first delivery failed or crashed, so retry as well
+				var state string
+				if err := c.Get(&cc, key, &state); err != nil || state != handled {
+					return errors.Wrapf(ErrInProgress, "message %s from %s", dto.Msg.MsgID, dto.Source)
+				}
+
+				if dto.Log != nil {
+					dto.Log.Infof("skip duplicate message %s from %s", dto.Msg.MsgID, dto.Source)
+				}
+				return nil
+			}
+
+			if err := next(ctx, dto); err != nil {
+				release := context.Background()
+				if rerr := c.Remove(&release, key); rerr != nil && dto.Log != nil {
+					dto.Log.Error("failed to release message ", dto.Msg.MsgID, ": ", rerr.Error())
+				}
+				return err
+			}
+
+			done := context.Background()
+			if err := c.SetWithExpiration(&done, key, handled, o.TTL); err != nil && dto.Log != nil {
+				dto.Log.Error("failed to mark message ", dto.Msg.MsgID, " as handled: ", err.Error())
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/labstack/gommon/log"
+
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+// Logger logs every message once it is handled with its duration and error. The fields of a JSON payload,
+// including the objects nested in its arrays, and the attributes are masked with masking. Any other payload,
+// such as msgpack or gzip, is only logged as its size.
+func Logger(masking logs.MaskedEncoder) messaging.MiddlewareFunc {
+	return func(next messaging.MiddlewareHandlerFunc) messaging.MiddlewareHandlerFunc {
+		return func(ctx *tntContext.Context, dto messaging.DispatchDTO) error {
+			start := time.Now()
+			err := next(ctx, dto)
+
+			if dto.Log == nil {
+				return err
+			}
+
+			entry := log.JSON{
+				"type":       dto.Type,
+				"source":     dto.Source,
+				"topic":      dto.Topic,
+				"request_id": dto.RequestID,
+				"msg_type":   dto.MsgType,
+				"msg_id":     dto.Msg.MsgID,
+				"attributes": mask(masking, dto.Msg.MsgAttributes),
+				"data":       maskData(masking, dto.Msg.MsgData),
+				"duration":   time.Since(start).String(),
+			}
+
+			if dto.Err != nil {
+				entry["cause"] = dto.Err.Error()
+			}
+
+			if err != nil {
+				entry["error"] = err.Error()
+				dto.Log.Errorj(entry)
+				return err
+			}
+
+			dto.Log.Infoj(entry)
+			return nil
+		}
+	}
+}
+
+func mask(masking logs.MaskedEncoder, attributes map[string]string) interface{} {
+	fields := make(map[string]interface{}, len(attributes))
+	for key, value := range attributes {
+		fields[key] = value
+	}
+
+	return masking.Encode("", fields)
+}
+
+func maskData(masking logs.MaskedEncoder, data []byte) interface{} {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Sprintf("<%d bytes>", len(data))
+	}
+
+	return maskValue(masking, "", value)
+}
+
+// maskValue masks the fields of every object nested in value, MaskedEncoder.Encode does not walk arrays
+func maskValue(masking logs.MaskedEncoder, key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			v[k] = maskValue(masking, k, field)
+		}
+		return v
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = maskValue(masking, key, elem)
+		}
+		return v
+	default:
+		return masking.Encode(key, v)
+	}
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/helper/prometheus"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+const (
+	DefaultNamespace = "messaging"
+
+	Success Result = "success"
+	Failure Result = "failure"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the processing time histogram
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type (
+	Result string
+
+	MetricsOption struct {
+		// Namespace prefixes every metric name, defaults to DefaultNamespace
+		Namespace string
+		// Buckets are the upper bounds in seconds of the processing time histogram, defaults to DefaultBuckets
+		Buckets []float64
+	}
+
+	// Recorder receives the processing metrics of the messages, see Registry
+	Recorder interface {
+		ObserveMessage(topic, msgType string, result Result, duration time.Duration)
+	}
+
+	// Registry keeps the metrics per topic in memory and writes them in the Prometheus text format,
+	// it is an http.Handler so it can be mounted as the metrics endpoint.
+	Registry struct {
+		namespace string
+		buckets   []float64
+
+		mu     sync.Mutex
+		topics map[string]*topicMetrics
+	}
+
+	topicMetrics struct {
+		results  map[Result]uint64
+		duration *prometheus.Histogram
+	}
+)
+
+// Metrics records how long the handler of every message took and whether it failed, the error dispatches are not recorded
+func Metrics(recorder Recorder) messaging.MiddlewareFunc {
+	return func(next messaging.MiddlewareHandlerFunc) messaging.MiddlewareHandlerFunc {
+		return func(ctx *tntContext.Context, dto messaging.DispatchDTO) error {
+			if dto.Type != messaging.Handle {
+				return next(ctx, dto)
+			}
+
+			start := time.Now()
+			err := next(ctx, dto)
+
+			result := Success
+			if err != nil {
+				result = Failure
+			}
+			recorder.ObserveMessage(dto.Topic, dto.MsgType, result, time.Since(start))
+
+			return err
+		}
+	}
+}
+
+func NewRegistry(option *MetricsOption) *Registry {
+	r := &Registry{
+		namespace: DefaultNamespace,
+		buckets:   DefaultBuckets,
+		topics:    make(map[string]*topicMetrics),
+	}
+
+	if option != nil {
+		if option.Namespace != "" {
+			r.namespace = option.Namespace
+		}
+		r.buckets = prometheus.Buckets(option.Buckets, DefaultBuckets)
+	}
+
+	return r
+}
+
+// ObserveMessage aggregates per topic, msgType is not used as a label to bound the number of series
+func (r *Registry) ObserveMessage(topic, msgType string, result Result, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.topics[topic]
+	if !ok {
+		m = &topicMetrics{results: make(map[Result]uint64), duration: prometheus.NewHistogram(r.buckets)}
+		r.topics[topic] = m
+	}
+
+	m.results[result]++
+	m.duration.Observe(duration.Seconds())
+}
+
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	topics := make([]string, 0, len(r.topics))
+	for topic := range r.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	b := prometheus.NewWriter(w, r.namespace)
+
+	b.Header("messages_total", "counter", "Messages handled by result.")
+	for _, topic := range topics {
+		for _, result := range []Result{Success, Failure} {
+			if n, ok := r.topics[topic].results[result]; ok {
+				b.Value("messages_total", n, prometheus.Label{Name: "topic", Value: topic}, prometheus.Label{Name: "result", Value: string(result)})
+			}
+		}
+	}
+
+	b.Header("message_duration_seconds", "histogram", "Processing time of the messages.")
+	for _, topic := range topics {
+		b.Histogram("message_duration_seconds", r.topics[topic].duration, prometheus.Label{Name: "topic", Value: topic})
+	}
+
+	return b.Flush()
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	prometheus.Serve(w, r.WritePrometheus)
+}
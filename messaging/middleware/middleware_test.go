@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/apm"
+	"github.com/Dert12318/Utilities/apm/disabled"
+	"github.com/Dert12318/Utilities/cache/memory"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs"
+	"github.com/Dert12318/Utilities/logs/logrus"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+func dispatcher(handler messaging.HandlerFunc, errorHandler messaging.ErrorHandlerFunc, middleware ...messaging.MiddlewareFunc) messaging.Dispatcher {
+	d := messaging.NewSingleEventDispatcher()
+	d.AddHandler(handler, errorHandler)
+	d.Use(middleware...)
+	return d
+}
+
+func dto(id string, data string) messaging.DispatchDTO {
+	return messaging.DispatchDTO{
+		Type:  messaging.Handle,
+		Topic: "order",
+		Msg:   messaging.Message{MsgID: id, MsgData: []byte(data)},
+		Log:   logrus.DefaultLog(),
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var handled error
+	d := dispatcher(func(ctx *tntContext.Context, msg messaging.Message) error {
+		panic("boom")
+	}, func(ctx *tntContext.Context, msg messaging.Message, err error) {
+		handled = err
+	}, Recover())
+
+	assert.NoError(t, d.Dispatch(dto("1", "")))
+	assert.True(t, errors.Is(handled, ErrPanic))
+	assert.Contains(t, handled.Error(), "boom")
+
+	d = dispatcher(nil, func(ctx *tntContext.Context, msg messaging.Message, err error) {
+		panic("again")
+	}, Recover())
+
+	failure := dto("2", "")
+	failure.Type, failure.Err = messaging.Error, errors.New("failed")
+	assert.True(t, errors.Is(d.Dispatch(failure), ErrPanic))
+}
+
+func TestLogger(t *testing.T) {
+	var out bytes.Buffer
+	log := logrus.DefaultLog()
+	log.SetOutput(&out)
+
+	d := dispatcher(func(ctx *tntContext.Context, msg messaging.Message) error {
+		return nil
+	}, nil, Logger(logs.MaskedEncoder{
+		"password":      {Key: "password", Aliasing: "***"},
+		"authorization": {Key: "authorization", Pattern: "*", Skipper: logs.Skipper{First: 2}},
+	}))
+
+	message := dto("1", `{"user":"john","password":"secret"}`)
+	message.Log = log
+	message.Msg.MsgAttributes = map[string]string{"authorization": "Bearer token"}
+	assert.NoError(t, d.Dispatch(message))
+
+	logged := out.String()
+	assert.Contains(t, logged, "john")
+	assert.NotContains(t, logged, "secret")
+	assert.Contains(t, logged, "***")
+	assert.NotContains(t, logged, "Bearer token")
+	assert.Contains(t, logged, "Be**********")
+}
+
+func TestLoggerMasksArraysAndHidesOtherPayloads(t *testing.T) {
+	var out bytes.Buffer
+	log := logrus.DefaultLog()
+	log.SetOutput(&out)
+
+	d := dispatcher(func(ctx *tntContext.Context, msg messaging.Message) error {
+		return nil
+	}, nil, Logger(logs.MaskedEncoder{"password": {Key: "password", Aliasing: "***"}}))
+
+	message := dto("1", `[{"user":"john","password":"secret"},{"user":"jane","credentials":{"password":"hidden"}}]`)
+	message.Log = log
+	assert.NoError(t, d.Dispatch(message))
+
+	// a payload that is not JSON may hold secrets the masking can not find
+	binary := dto("2", "\x1f\x8bpassword=binary-secret")
+	binary.Log = log
+	assert.NoError(t, d.Dispatch(binary))
+
+	logged := out.String()
+	assert.Contains(t, logged, "jane")
+	assert.NotContains(t, logged, "secret")
+	assert.NotContains(t, logged, "hidden")
+	assert.Contains(t, logged, "<24 bytes>")
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	d := dispatcher(func(ctx *tntContext.Context, msg messaging.Message) error {
+		select {
+		case <-ctx.Ctx.Done():
+		case <-release:
+		}
+		return nil
+	}, nil, Timeout(10*time.Millisecond))
+
+	err := d.Dispatch(dto("1", ""))
+	assert.True(t, errors.Is(err, ErrTimeout))
+
+	d = dispatcher(func(ctx *tntContext.Context, msg messaging.Message) error {
+		return errors.New("failed")
+	}, nil, Timeout(time.Second))
+	assert.EqualError(t, d.Dispatch(dto("2", "")), "failed")
+}
+
+func TestDeduplicate(t *testing.T) {
+	c, err := memory.New()
+	assert.NoError(t, err)
+	defer c.Close()
+
+	calls := 0
+	fail := true
+	d := dispatcher(func(ctx *tntContext.Context, msg messaging.Message) error {
+		calls++
+		if fail {
+			return errors.New("failed")
+		}
+		return nil
+	}, nil, Deduplicate(c, &DeduplicateOption{TTL: time.Minute}))
+
+	// a failed message is released so it can be retried
+	assert.Error(t, d.Dispatch(dto("1", "")))
+	fail = false
+	assert.NoError(t, d.Dispatch(dto("1", "")))
+	assert.NoError(t, d.Dispatch(dto("1", "")))
+	assert.NoError(t, d.Dispatch(dto("2", "")))
+
+	assert.Equal(t, 3, calls)
+}
+
+func TestDeduplicateInProgress(t *testing.T) {
+	c, err := memory.New()
+	assert.NoError(t, err)
+	defer c.Close()
+
+	started, release := make(chan struct{}), make(chan error)
+	calls := 0
+	d := dispatcher(func(ctx *tntContext.Context, msg messaging.Message) error {
+		calls++
+		if calls == 1 {
+			close(started)
+			return <-release
+		}
+		return nil
+	}, nil, Deduplicate(c, &DeduplicateOption{TTL: time.Minute}))
+
+	first := make(chan error)
+	go func() { first <- d.Dispatch(dto("1", "")) }()
+	<-started
+
+	// the duplicate is not skipped while the first delivery may still fail
+	assert.True(t, errors.Is(d.Dispatch(dto("1", "")), ErrInProgress))
+
+	release <- errors.New("failed")
+	assert.Error(t, <-first)
+
+	// so the redelivery of the duplicate is handled
+	assert.NoError(t, d.Dispatch(dto("1", "")))
+	assert.Equal(t, 2, calls)
+}
+
+func TestMetrics(t *testing.T) {
+	registry := NewRegistry(&MetricsOption{Buckets: []float64{1}})
+	d := dispatcher(func(ctx *tntContext.Context, msg messaging.Message) error {
+		if msg.MsgID == "2" {
+			return errors.New("failed")
+		}
+		return nil
+	}, func(ctx *tntContext.Context, msg messaging.Message, err error) {}, Metrics(registry))
+
+	assert.NoError(t, d.Dispatch(dto("1", "")))
+	assert.Error(t, d.Dispatch(dto("2", "")))
+
+	failure := dto("2", "")
+	failure.Type = messaging.Error
+	assert.NoError(t, d.Dispatch(failure))
+
+	var b bytes.Buffer
+	assert.NoError(t, registry.WritePrometheus(&b))
+
+	out := b.String()
+	assert.Contains(t, out, `messaging_messages_total{topic="order",result="success"} 1`)
+	assert.Contains(t, out, `messaging_messages_total{topic="order",result="failure"} 1`)
+	assert.Contains(t, out, `messaging_message_duration_seconds_bucket{topic="order",le="1"} 2`)
+	assert.Equal(t, 1, strings.Count(out, "messaging_message_duration_seconds_count"))
+}
+
+type tracingAPM struct {
+	apm.APM
+	name   string
+	header http.Header
+}
+
+func (a *tracingAPM) StartTransactionFromHeaders(name string, header http.Header) apm.Transaction {
+	a.name, a.header = name, header
+	return apm.StartTransactionFromHeaders(a.APM, name, header)
+}
+
+func TestApm(t *testing.T) {
+	disabledAPM, _ := disabled.New()
+	tracer := &tracingAPM{APM: disabledAPM}
+
+	var txn apm.Transaction
+	d := dispatcher(func(ctx *tntContext.Context, msg messaging.Message) error {
+		txn = ctx.Transaction
+		return nil
+	}, nil, Apm(tracer))
+
+	message := dto("1", "")
+	message.Msg.MsgAttributes = map[string]string{"X-Datadog-Trace-Id": "42"}
+	assert.NoError(t, d.Dispatch(message))
+
+	assert.NotNil(t, txn)
+	assert.Equal(t, "messaging_consume:order", tracer.name)
+	assert.Equal(t, "42", tracer.header.Get("X-Datadog-Trace-Id"))
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/pkg/errors"
+
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+// ErrPanic is the cause of the error dispatched by Recover
+var ErrPanic = errors.New("handler panicked")
+
+// Recover turns a panic of a handler into an Error dispatch of the same message, which is then
+// considered done. A panic of the error handler is returned as an error.
+func Recover() messaging.MiddlewareFunc {
+	return func(next messaging.MiddlewareHandlerFunc) messaging.MiddlewareHandlerFunc {
+		return func(ctx *tntContext.Context, dto messaging.DispatchDTO) (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+
+				err = errors.Wrap(ErrPanic, fmt.Sprint(r))
+				if dto.Log != nil {
+					dto.Log.Errorf("panic on message %s from %s: %v\n%s", dto.Msg.MsgID, dto.Source, r, debug.Stack())
+				}
+
+				if dto.Type == messaging.Handle {
+					dto.Type, dto.Err = messaging.Error, err
+					err = run(next, ctx, dto)
+				}
+			}()
+
+			return next(ctx, dto)
+		}
+	}
+}
+
+// run calls next, returning a panic as an error
+func run(next messaging.MiddlewareHandlerFunc, ctx *tntContext.Context, dto messaging.DispatchDTO) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrap(ErrPanic, fmt.Sprint(r))
+		}
+	}()
+
+	return next(ctx, dto)
+}
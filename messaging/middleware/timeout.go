@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+// ErrTimeout is returned by Timeout when the handler did not return in time
+var ErrTimeout = errors.New("handler timed out")
+
+// Timeout cancels ctx.Ctx of the handler after timeout and fails the message with ErrTimeout. A handler that
+// ignores ctx keeps running in the background, so it must be safe to run it again on retry.
+func Timeout(timeout time.Duration) messaging.MiddlewareFunc {
+	return func(next messaging.MiddlewareHandlerFunc) messaging.MiddlewareHandlerFunc {
+		return func(ctx *tntContext.Context, dto messaging.DispatchDTO) error {
+			parent := ctx.Ctx
+			if parent == nil {
+				parent = context.Background()
+			}
+
+			c, cancel := context.WithTimeout(parent, timeout)
+			defer cancel()
+
+			handlerCtx := *ctx
+			handlerCtx.Ctx = c
+
+			result := make(chan error, 1)
+			go func() {
+				result <- run(next, &handlerCtx, dto)
+			}()
+
+			select {
+			case err := <-result:
+				return err
+			case <-c.Done():
+				if parent.Err() != nil {
+					return parent.Err()
+				}
+				return errors.Wrapf(ErrTimeout, "message %s not handled within %s", dto.Msg.MsgID, timeout)
+			}
+		}
+	}
+}
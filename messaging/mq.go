@@ -4,7 +4,14 @@ import (
 	tntContext "github.com/Dert12318/Utilities/context"
 )
 
-const PublishTime = "publish_time"
+const (
+	PublishTime = "publish_time"
+	// MessageType is the attribute holding the type a RoutingDispatcher routes on, see DispatchDTO.MsgType
+	MessageType = "message"
+	// PartitionKey is the attribute the partitioned queues such as kafka partition on instead of the MsgID,
+	// the messages sharing a partition key are consumed in order
+	PartitionKey = "partition_key"
+)
 
 type (
 	Message struct {
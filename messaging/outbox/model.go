@@ -0,0 +1,69 @@
+package outbox
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/models"
+)
+
+// Actor is the CreatedBy and UpdatedBy of the outbox rows
+const Actor = "outbox"
+
+type (
+	// Attributes are the messaging.Message attributes, stored as JSON
+	Attributes map[string]string
+
+	// Message is a row of the outbox table. A message is pending until either PublishedAt or FailedAt is set,
+	// only the oldest pending message of an AggregateKey is published so that a key is delivered in order.
+	Message struct {
+		ID            int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+		Topic         string     `gorm:"type:varchar(255);not null" json:"topic"`
+		AggregateKey  string     `gorm:"type:varchar(255);not null;index" json:"aggregate_key"`
+		MsgID         string     `gorm:"type:varchar(255);not null" json:"msg_id"`
+		MsgData       []byte     `json:"msg_data"`
+		MsgAttributes Attributes `gorm:"type:jsonb" json:"msg_attributes"`
+		Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+		LastError     string     `json:"last_error"`
+		AvailableAt   time.Time  `gorm:"not null;index" json:"available_at"`
+		PublishedAt   *time.Time `gorm:"index" json:"published_at"`
+		FailedAt      *time.Time `json:"failed_at"`
+		models.BaseModel
+	}
+)
+
+func (Message) TableName() string {
+	return DefaultTable
+}
+
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	value, err := json.Marshal(a)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode outbox attributes")
+	}
+	return string(value), nil
+}
+
+func (a *Attributes) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.Errorf("unexpected outbox attributes %T", value)
+	}
+
+	return errors.Wrap(json.Unmarshal(data, a), "failed to decode outbox attributes")
+}
@@ -0,0 +1,126 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/logs"
+)
+
+const (
+	DefaultTable           = "outbox_messages"
+	DefaultPollInterval    = time.Second
+	DefaultBatchSize       = 100
+	DefaultRetryBackoff    = time.Second
+	DefaultMaxRetryBackoff = 5 * time.Minute
+)
+
+type (
+	Option interface {
+		Apply(o *option)
+	}
+	option struct {
+		Table           string
+		PollInterval    time.Duration
+		BatchSize       int
+		RetryBackoff    time.Duration
+		MaxRetryBackoff time.Duration
+		MaxAttempts     int
+		Log             logs.Logger
+	}
+)
+
+func validate(option option) error {
+	if option.Table == "" {
+		return errors.New("invalid outbox table")
+	}
+	if option.PollInterval <= 0 {
+		return errors.New("invalid outbox poll interval")
+	}
+	if option.BatchSize < 1 {
+		return errors.New("invalid outbox batch size")
+	}
+	if option.RetryBackoff <= 0 || option.MaxRetryBackoff < option.RetryBackoff {
+		return errors.New("invalid outbox retry backoff")
+	}
+	if option.MaxAttempts < 0 {
+		return errors.New("invalid outbox max attempts")
+	}
+	return nil
+}
+
+type withTable string
+
+func WithTable(table string) Option {
+	return withTable(table)
+}
+
+func (w withTable) Apply(o *option) {
+	o.Table = string(w)
+}
+
+type withPollInterval time.Duration
+
+// WithPollInterval is how long the relay waits before polling again once the outbox is drained.
+func WithPollInterval(interval time.Duration) Option {
+	return withPollInterval(interval)
+}
+
+func (w withPollInterval) Apply(o *option) {
+	o.PollInterval = time.Duration(w)
+}
+
+type withBatchSize int
+
+// WithBatchSize is how many messages a poll locks and publishes in one transaction.
+func WithBatchSize(size int) Option {
+	return withBatchSize(size)
+}
+
+func (w withBatchSize) Apply(o *option) {
+	o.BatchSize = int(w)
+}
+
+type withRetryBackoff time.Duration
+
+// WithRetryBackoff is the delay before the first retry of a failed publish, it doubles on every attempt.
+func WithRetryBackoff(backoff time.Duration) Option {
+	return withRetryBackoff(backoff)
+}
+
+func (w withRetryBackoff) Apply(o *option) {
+	o.RetryBackoff = time.Duration(w)
+}
+
+type withMaxRetryBackoff time.Duration
+
+func WithMaxRetryBackoff(backoff time.Duration) Option {
+	return withMaxRetryBackoff(backoff)
+}
+
+func (w withMaxRetryBackoff) Apply(o *option) {
+	o.MaxRetryBackoff = time.Duration(w)
+}
+
+type withMaxAttempts int
+
+// WithMaxAttempts gives up on a message after attempts failed publishes, setting its FailedAt so that the next
+// messages of its key are published. Zero, the default, retries forever.
+func WithMaxAttempts(attempts int) Option {
+	return withMaxAttempts(attempts)
+}
+
+func (w withMaxAttempts) Apply(o *option) {
+	o.MaxAttempts = int(w)
+}
+
+type withLog struct{ logs.Logger }
+
+func WithLog(logger logs.Logger) Option {
+	return withLog{logger}
+}
+
+func (w withLog) Apply(o *option) {
+	o.Log = w.Logger
+}
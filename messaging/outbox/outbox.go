@@ -0,0 +1,268 @@
+package outbox
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Dert12318/Utilities/common/constant/header"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs/logrus"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+type (
+	// Outbox publishes messages only once the database transaction that enqueued them is committed: Enqueue
+	// writes the message in the transaction and the relay publishes the committed messages to the queue,
+	// retrying failed publishes. Messages are delivered at least once, the consumers must be idempotent.
+	Outbox interface {
+		// Enqueue stores msg to be published to topic inside tx, the message is only published once tx is committed,
+		// e.g. with PostgresDatabaseManager.CommitTransaction. Messages sharing a key are published in order,
+		// key defaults to the MsgID and is the messaging.PartitionKey of the published message. The request id
+		// of ctx is added to the attributes, the credentials such as the authorization are dropped.
+		Enqueue(ctx *tntContext.Context, tx *gorm.DB, topic, key string, msg messaging.Message) error
+		// Migrate creates or updates the outbox table
+		Migrate() error
+		// Start runs the relay in the background until Close, several relays can share the same table
+		Start()
+		// Close stops the relay, waiting for the batch being published
+		Close() error
+	}
+
+	outbox struct {
+		db     *gorm.DB
+		queue  messaging.Queue
+		option option
+
+		mu      sync.Mutex
+		started bool
+		stop    chan struct{}
+		wg      sync.WaitGroup
+	}
+)
+
+// New returns an Outbox stored in db and relayed to queue.
+func New(db *gorm.DB, queue messaging.Queue, options ...Option) (Outbox, error) {
+	o := option{
+		Table:           DefaultTable,
+		PollInterval:    DefaultPollInterval,
+		BatchSize:       DefaultBatchSize,
+		RetryBackoff:    DefaultRetryBackoff,
+		MaxRetryBackoff: DefaultMaxRetryBackoff,
+		Log:             logrus.DefaultLog(),
+	}
+
+	for _, opt := range options {
+		opt.Apply(&o)
+	}
+
+	if err := validate(o); err != nil {
+		return nil, err
+	}
+
+	if db == nil {
+		return nil, errors.New("invalid outbox database")
+	}
+	if queue == nil {
+		return nil, errors.New("invalid outbox queue")
+	}
+
+	return &outbox{
+		db:     db,
+		queue:  queue,
+		option: o,
+		stop:   make(chan struct{}),
+	}, nil
+}
+
+func (o *outbox) Enqueue(ctx *tntContext.Context, tx *gorm.DB, topic, key string, msg messaging.Message) error {
+	if msg.MsgID == "" {
+		msg.MsgID = uuid.New().String()
+	}
+	if key == "" {
+		key = msg.MsgID
+	}
+
+	attributes := make(Attributes, len(msg.MsgAttributes)+1)
+	if ctx != nil && ctx.MandatoryRequest().RequestID() != "" {
+		attributes[header.MessagingRequestID] = ctx.MandatoryRequest().RequestID()
+	}
+	// the attributes set by the caller win over the ones of ctx, the credentials are never stored:
+	// the table is readable by anyone with access to the database and they expire before the relay runs
+	for k, attr := range msg.MsgAttributes {
+		if !header.IsCredential(k) {
+			attributes[k] = attr
+		}
+	}
+
+	now := time.Now()
+	row := Message{
+		Topic:         topic,
+		AggregateKey:  key,
+		MsgID:         msg.MsgID,
+		MsgData:       msg.MsgData,
+		MsgAttributes: attributes,
+		AvailableAt:   now,
+	}
+	row.CreatedAt, row.CreatedBy = now, Actor
+	row.UpdatedAt, row.UpdatedBy = now, Actor
+
+	if err := tx.Table(o.option.Table).Create(&row).Error; err != nil {
+		return errors.Wrapf(err, "failed to enqueue message %s to topic %s", msg.MsgID, topic)
+	}
+
+	return nil
+}
+
+func (o *outbox) Migrate() error {
+	if err := o.db.Table(o.option.Table).AutoMigrate(&Message{}); err != nil {
+		return errors.Wrapf(err, "failed to migrate outbox table %s", o.option.Table)
+	}
+
+	return nil
+}
+
+func (o *outbox) Start() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.started {
+		o.option.Log.Info("outbox relay already started")
+		return
+	}
+
+	o.started = true
+	o.wg.Add(1)
+	go o.relay()
+}
+
+func (o *outbox) Close() error {
+	o.mu.Lock()
+	select {
+	case <-o.stop:
+	default:
+		close(o.stop)
+	}
+	o.mu.Unlock()
+
+	o.wg.Wait()
+	return nil
+}
+
+// relay polls until Close, polling again right away while the batches are full
+func (o *outbox) relay() {
+	defer o.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-timer.C:
+		}
+
+		n, err := o.poll()
+		if err != nil {
+			o.option.Log.Error(err)
+		}
+
+		if err == nil && n == o.option.BatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(o.option.PollInterval)
+		}
+	}
+}
+
+// poll locks and publishes a batch of the oldest pending message of every key. SKIP LOCKED lets several relays
+// poll concurrently, the next message of a key is not selected before its predecessor is published or failed.
+func (o *outbox) poll() (int, error) {
+	var rows []Message
+	err := o.db.Transaction(func(tx *gorm.DB) error {
+		heads := tx.Table(o.option.Table).
+			Select("MIN(id)").
+			Where("published_at IS NULL AND failed_at IS NULL").
+			Group("aggregate_key")
+
+		if err := tx.Table(o.option.Table).
+			Where("id IN (?) AND available_at <= ?", heads, time.Now()).
+			Order("id").
+			Limit(o.option.BatchSize).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Find(&rows).Error; err != nil {
+			return errors.Wrapf(err, "failed to poll outbox %s", o.option.Table)
+		}
+
+		for i := range rows {
+			if err := o.publish(tx, rows[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return len(rows), err
+}
+
+// publish sends row to the queue and records the outcome, a failed publish is retried after a backoff
+func (o *outbox) publish(tx *gorm.DB, row Message) error {
+	// the queue keeps the messages of the aggregate in order by partitioning on its key
+	attributes := make(map[string]string, len(row.MsgAttributes)+1)
+	for k, attr := range row.MsgAttributes {
+		attributes[k] = attr
+	}
+	attributes[messaging.PartitionKey] = row.AggregateKey
+
+	err := o.queue.Publish(row.Topic, messaging.Message{
+		MsgID:         row.MsgID,
+		MsgData:       row.MsgData,
+		MsgAttributes: attributes,
+	})
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"updated_at": now,
+		"updated_by": Actor,
+	}
+	if err == nil {
+		updates["published_at"] = now
+	} else {
+		attempts := row.Attempts + 1
+		updates["attempts"] = attempts
+		updates["last_error"] = err.Error()
+		updates["available_at"] = now.Add(o.backoff(attempts))
+
+		if o.option.MaxAttempts > 0 && attempts >= o.option.MaxAttempts {
+			updates["failed_at"] = now
+			o.option.Log.Error(errors.Wrapf(err, "giving up publishing outbox message %s to topic %s after %d attempts",
+				row.MsgID, row.Topic, attempts))
+		} else {
+			o.option.Log.Error(errors.Wrapf(err, "failed to publish outbox message %s to topic %s", row.MsgID, row.Topic))
+		}
+	}
+
+	if err := tx.Table(o.option.Table).Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+		return errors.Wrapf(err, "failed to update outbox message %s", row.MsgID)
+	}
+
+	return nil
+}
+
+// backoff doubles RetryBackoff on every attempt up to MaxRetryBackoff
+func (o *outbox) backoff(attempts int) time.Duration {
+	backoff := o.option.RetryBackoff
+	for i := 1; i < attempts && backoff < o.option.MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > o.option.MaxRetryBackoff {
+		return o.option.MaxRetryBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/Dert12318/Utilities/common/constant/header"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/messaging"
+	"github.com/Dert12318/Utilities/messaging/memory"
+)
+
+var columns = []string{"id", "topic", "aggregate_key", "msg_id", "msg_data", "msg_attributes", "attempts", "available_at"}
+
+func newOutbox(t *testing.T, options ...Option) (*outbox, sqlmock.Sqlmock, memory.Queue) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: logger.Discard})
+	assert.NoError(t, err)
+
+	queue, err := memory.New(memory.WithoutConsumer())
+	assert.NoError(t, err)
+
+	o, err := New(db, queue, options...)
+	assert.NoError(t, err)
+	return o.(*outbox), mock, queue
+}
+
+func expectPoll(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "outbox_messages" WHERE id IN ` +
+		`(SELECT MIN(id) FROM "outbox_messages" WHERE published_at IS NULL AND failed_at IS NULL GROUP BY "aggregate_key") ` +
+		`AND available_at <= $1 ORDER BY id LIMIT 100 FOR UPDATE SKIP LOCKED`)).
+		WillReturnRows(rows)
+}
+
+func TestEnqueue(t *testing.T) {
+	o, mock, _ := newOutbox(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "outbox_messages"`)).
+		WithArgs("orders", "order-1", "1", []byte(`{"id":1}`),
+			`{"message":"created","requestId":"req-1"}`, 0, "", sqlmock.AnyArg(), nil, nil,
+			sqlmock.AnyArg(), Actor, sqlmock.AnyArg(), Actor).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	ctx := tntContext.New()
+	ctx.SetMandatory(tntContext.NewMessagingSource(map[string]string{
+		header.MessagingRequestID:     "req-1",
+		header.MessagingAuthorization: "Bearer token",
+	}))
+
+	// the credentials of ctx and of the caller are not stored
+	tx := o.db.Begin()
+	assert.NoError(t, o.Enqueue(ctx, tx, "orders", "order-1", messaging.Message{
+		MsgID:         "1",
+		MsgData:       []byte(`{"id":1}`),
+		MsgAttributes: map[string]string{messaging.MessageType: "created", header.HttpAuthorization: "Bearer other"},
+	}))
+	assert.NoError(t, tx.Commit().Error)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPollPublishes(t *testing.T) {
+	o, mock, queue := newOutbox(t)
+
+	expectPoll(mock, sqlmock.NewRows(columns).
+		AddRow(1, "orders", "order-1", "1", []byte(`{"id":1}`), `{"message":"created"}`, 0, time.Now()).
+		AddRow(2, "orders", "order-2", "2", []byte(`{"id":2}`), nil, 0, time.Now()))
+	for _, id := range []int64{1, 2} {
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox_messages" SET "published_at"=$1,"updated_at"=$2,"updated_by"=$3 WHERE id = $4`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), Actor, id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	n, err := o.poll()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	published := queue.Published("orders")
+	if assert.Len(t, published, 2) {
+		assert.Equal(t, "1", published[0].MsgID)
+		assert.Equal(t, []byte(`{"id":1}`), published[0].MsgData)
+		assert.Equal(t, "created", published[0].MsgAttributes[messaging.MessageType])
+		assert.Equal(t, "order-1", published[0].MsgAttributes[messaging.PartitionKey])
+		assert.Equal(t, "2", published[1].MsgID)
+		assert.Equal(t, "order-2", published[1].MsgAttributes[messaging.PartitionKey])
+	}
+}
+
+func TestPollRetriesFailedPublish(t *testing.T) {
+	o, mock, queue := newOutbox(t)
+	queue.FailPublish("orders", errors.New("broker down"), 1)
+
+	expectPoll(mock, sqlmock.NewRows(columns).
+		AddRow(1, "orders", "order-1", "1", nil, nil, 2, time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox_messages" SET "attempts"=$1,"available_at"=$2,"last_error"=$3,"updated_at"=$4,"updated_by"=$5 WHERE id = $6`)).
+		WithArgs(3, sqlmock.AnyArg(), "failed to publish message 1 to topic orders: broker down", sqlmock.AnyArg(), Actor, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err := o.poll()
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Empty(t, queue.Published("orders"))
+}
+
+func TestPollGivesUpAfterMaxAttempts(t *testing.T) {
+	o, mock, queue := newOutbox(t, WithMaxAttempts(3))
+	queue.FailPublish("orders", errors.New("broker down"), 1)
+
+	expectPoll(mock, sqlmock.NewRows(columns).
+		AddRow(1, "orders", "order-1", "1", nil, nil, 2, time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox_messages" SET "attempts"=$1,"available_at"=$2,"failed_at"=$3,"last_error"=$4,"updated_at"=$5,"updated_by"=$6 WHERE id = $7`)).
+		WithArgs(3, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), Actor, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err := o.poll()
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPollRollsBackWhenUpdateFails(t *testing.T) {
+	o, mock, _ := newOutbox(t)
+
+	expectPoll(mock, sqlmock.NewRows(columns).
+		AddRow(1, "orders", "order-1", "1", nil, nil, 0, time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox_messages"`)).
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	_, err := o.poll()
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBackoff(t *testing.T) {
+	o, _, _ := newOutbox(t, WithRetryBackoff(time.Second), WithMaxRetryBackoff(10*time.Second))
+
+	assert.Equal(t, time.Second, o.backoff(1))
+	assert.Equal(t, 2*time.Second, o.backoff(2))
+	assert.Equal(t, 8*time.Second, o.backoff(4))
+	assert.Equal(t, 10*time.Second, o.backoff(5))
+	assert.Equal(t, 10*time.Second, o.backoff(100))
+}
+
+func TestAttributes(t *testing.T) {
+	value, err := Attributes{"a": "1"}.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":"1"}`, value)
+
+	var a Attributes
+	assert.NoError(t, a.Scan([]byte(`{"b":"2"}`)))
+	assert.Equal(t, Attributes{"b": "2"}, a)
+	assert.NoError(t, a.Scan(nil))
+	assert.Nil(t, a)
+	assert.Error(t, a.Scan(1))
+}
+
+func TestInvalidOption(t *testing.T) {
+	queue, _ := memory.New(memory.WithoutConsumer())
+
+	_, err := New(&gorm.DB{}, queue, WithBatchSize(0))
+	assert.Error(t, err)
+	_, err = New(&gorm.DB{}, queue, WithRetryBackoff(time.Minute), WithMaxRetryBackoff(time.Second))
+	assert.Error(t, err)
+	_, err = New(nil, queue)
+	assert.Error(t, err)
+}
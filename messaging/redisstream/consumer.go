@@ -0,0 +1,204 @@
+package redisstream
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/common/constant/header"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+type (
+	consumer struct {
+		stream     *stream
+		topic      string
+		dispatcher messaging.Dispatcher
+	}
+)
+
+// read consumes the entries delivered to the group that no consumer has read yet
+func (c *consumer) read() {
+	defer c.stream.wg.Done()
+
+	o := c.stream.option
+	for !c.stream.stopped() {
+		streams, err := c.stream.client.XReadGroup(&redis.XReadGroupArgs{
+			Group:    o.ConsumerGroup,
+			Consumer: o.ConsumerName,
+			Streams:  []string{c.topic, ">"},
+			Count:    o.BatchSize,
+			Block:    o.Block,
+		}).Result()
+
+		if err == redis.Nil {
+			continue
+		}
+
+		if err != nil {
+			if c.stream.stopped() {
+				return
+			}
+
+			o.Log.Error(errors.Wrapf(err, "failed to read stream %s", c.topic))
+			c.wait(DefaultReadBackoff)
+			continue
+		}
+
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				c.process(msg)
+			}
+		}
+	}
+}
+
+// reclaim takes over the entries left pending by consumers idle for longer than ClaimMinIdle
+func (c *consumer) reclaim() {
+	defer c.stream.wg.Done()
+
+	ticker := time.NewTicker(c.stream.option.ClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stream.stop:
+			return
+		case <-ticker.C:
+			if err := c.claim(); err != nil {
+				c.stream.option.Log.Error(err)
+			}
+		}
+	}
+}
+
+func (c *consumer) claim() error {
+	o := c.stream.option
+	start := "0-0"
+	for !c.stream.stopped() {
+		cmd := redis.NewCmd("xautoclaim", c.topic, o.ConsumerGroup, o.ConsumerName,
+			o.ClaimMinIdle.Milliseconds(), start, "count", o.BatchSize)
+		if err := c.stream.client.Process(cmd); err != nil {
+			return errors.Wrapf(err, "failed to reclaim pending entries of stream %s", c.topic)
+		}
+
+		next, messages, deleted, err := parseAutoClaim(cmd.Val())
+		if err != nil {
+			return errors.Wrapf(err, "failed to reclaim pending entries of stream %s", c.topic)
+		}
+
+		for _, msg := range messages {
+			c.process(msg)
+		}
+
+		// entries deleted from the stream while pending can never be processed
+		if len(deleted) > 0 {
+			c.ack(deleted...)
+		}
+
+		if next == "0-0" {
+			return nil
+		}
+		start = next
+	}
+
+	return nil
+}
+
+// process dispatches msg, retrying up to ConsumerRetryMax times before dispatching the error, like the kafka consumer
+func (c *consumer) process(entry redis.XMessage) {
+	o := c.stream.option
+	messageData := getMessage(entry.Values)
+	requestID := messageData.MsgAttributes[header.MessagingRequestID]
+	msgType := messageData.MsgAttributes[messaging.MessageType]
+
+	var err error
+	var message = messaging.DispatchDTO{
+		Type:      messaging.Handle,
+		Source:    fmt.Sprintf("Redis Stream - %s", c.topic),
+		Topic:     c.topic,
+		RequestID: requestID,
+		MsgType:   msgType,
+		Msg:       messageData,
+		Log:       o.Log,
+	}
+	for i := 0; i <= o.ConsumerRetryMax; i++ {
+		message.Err = nil
+		if err = c.dispatcher.Dispatch(message); err == nil {
+			c.ack(entry.ID)
+			return
+		}
+
+		o.Log.Error("error on dispatch message from redis stream: ", err.Error())
+		message.Err = err
+	}
+
+	errMessage := message
+	errMessage.Type = messaging.Error
+	errMessage.Err = err
+	_ = c.dispatcher.Dispatch(errMessage)
+	c.ack(entry.ID)
+}
+
+func (c *consumer) ack(ids ...string) {
+	if err := c.stream.client.XAck(c.topic, c.stream.option.ConsumerGroup, ids...).Err(); err != nil {
+		c.stream.option.Log.Error(errors.Wrapf(err, "failed to ack entries %v of stream %s", ids, c.topic))
+	}
+}
+
+func (c *consumer) wait(d time.Duration) {
+	select {
+	case <-c.stream.stop:
+	case <-time.After(d):
+	}
+}
+
+// parseAutoClaim parses the XAUTOCLAIM reply: the next start id, the claimed entries and, since Redis 7,
+// the ids of the pending entries that no longer exist. Redis 6.2 reports those as entries without fields.
+func parseAutoClaim(reply interface{}) (string, []redis.XMessage, []string, error) {
+	values, ok := reply.([]interface{})
+	if !ok || len(values) < 2 {
+		return "", nil, nil, errors.Errorf("unexpected XAUTOCLAIM reply %v", reply)
+	}
+
+	next, _ := values[0].(string)
+	entries, _ := values[1].([]interface{})
+
+	var (
+		messages = make([]redis.XMessage, 0, len(entries))
+		deleted  []string
+	)
+	for _, e := range entries {
+		entry, ok := e.([]interface{})
+		if !ok || len(entry) != 2 {
+			return "", nil, nil, errors.Errorf("unexpected XAUTOCLAIM entry %v", e)
+		}
+
+		id, _ := entry[0].(string)
+		fields, ok := entry[1].([]interface{})
+		if !ok {
+			deleted = append(deleted, id)
+			continue
+		}
+
+		values := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			field, _ := fields[i].(string)
+			values[field] = fields[i+1]
+		}
+		messages = append(messages, redis.XMessage{ID: id, Values: values})
+	}
+
+	if len(values) > 2 {
+		ids, _ := values[2].([]interface{})
+		for _, id := range ids {
+			if s, ok := id.(string); ok {
+				deleted = append(deleted, s)
+			}
+		}
+	}
+
+	return next, messages, deleted, nil
+}
@@ -0,0 +1,223 @@
+package redisstream
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/common/constant/header"
+	tntContext "github.com/Dert12318/Utilities/context"
+	"github.com/Dert12318/Utilities/logs/logrus"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+const (
+	EventPublish = "redisstream_publish"
+
+	// FieldMsgID and FieldMsgData hold messaging.Message.MsgID and MsgData, every attribute is stored
+	// in its own field prefixed with FieldAttributePrefix
+	FieldMsgID           = "msg_id"
+	FieldMsgData         = "msg_data"
+	FieldAttributePrefix = "attr:"
+)
+
+type (
+	stream struct {
+		option option
+		client redis.UniversalClient
+
+		mu        sync.Mutex
+		topics    map[string]messaging.Dispatcher
+		listening bool
+		stop      chan struct{}
+		wg        sync.WaitGroup
+	}
+)
+
+// New returns a messaging.Queue on Redis Streams. Every topic is a stream read by a consumer group,
+// entries that stay pending on a crashed consumer are reclaimed with XAUTOCLAIM, which needs Redis 6.2.
+func New(options ...Option) (messaging.Queue, error) {
+	o := option{
+		ConsumerRetryMax: DefaultConsumerRetryMax,
+		Block:            DefaultBlock,
+		BatchSize:        DefaultBatchSize,
+		ClaimMinIdle:     DefaultClaimMinIdle,
+		ClaimInterval:    DefaultClaimInterval,
+		Log:              logrus.DefaultLog(),
+	}
+
+	for _, opt := range options {
+		opt.Apply(&o)
+	}
+
+	if err := validate(o); err != nil {
+		return nil, err
+	}
+
+	if o.ConsumerName == "" {
+		hostname, _ := os.Hostname()
+		o.ConsumerName = fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8])
+	}
+
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:    o.Address,
+		Password: o.Password,
+		DB:       o.DB,
+		// a blocking read must not time out before the server answers
+		ReadTimeout: o.Block + 3*time.Second,
+	})
+
+	if err := client.Ping().Err(); err != nil {
+		_ = client.Close()
+		return nil, errors.Wrap(err, "Failed to connect to redis!")
+	}
+
+	return &stream{
+		option: o,
+		client: client,
+		topics: make(map[string]messaging.Dispatcher),
+		stop:   make(chan struct{}),
+	}, nil
+}
+
+func (s *stream) Subscribe(topic string, dispatcher messaging.Dispatcher) error {
+	if s.option.WithoutConsumer {
+		return errors.New("redis stream is initialize without consumer")
+	}
+
+	err := s.client.XGroupCreateMkStream(topic, s.option.ConsumerGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return errors.Wrapf(err, "failed to create consumer group %s of stream %s", s.option.ConsumerGroup, topic)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.topics[topic] = dispatcher
+	return nil
+}
+
+func (s *stream) Listen() {
+	if s.option.WithoutConsumer {
+		s.option.Log.Error(errors.New("redis stream is initialize without consumer"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listening {
+		s.option.Log.Info("already listening to redis stream")
+		return
+	}
+
+	for topic, dispatcher := range s.topics {
+		c := &consumer{stream: s, topic: topic, dispatcher: dispatcher}
+
+		s.wg.Add(2)
+		go c.read()
+		go c.reclaim()
+	}
+
+	s.option.Log.Info("Start Listening")
+	s.listening = true
+}
+
+func (s *stream) Publish(topic string, msg messaging.Message) error {
+	return s.PublishWithContext(tntContext.New(), topic, msg)
+}
+
+func (s *stream) PublishWithContext(ctx *tntContext.Context, topic string, msg messaging.Message) error {
+	if msg.MsgID == "" {
+		msg.MsgID = uuid.New().String()
+	}
+
+	values := map[string]interface{}{
+		FieldMsgID:   msg.MsgID,
+		FieldMsgData: msg.MsgData,
+		FieldAttributePrefix + messaging.PublishTime: time.Now().Format(time.RFC3339),
+	}
+	for key, attr := range msg.MsgAttributes {
+		values[FieldAttributePrefix+key] = attr
+	}
+
+	if err := s.client.XAdd(&redis.XAddArgs{
+		Stream:       topic,
+		MaxLenApprox: s.option.MaxLen,
+		Values:       values,
+	}).Err(); err != nil {
+		return errors.Wrapf(err, "failed to publish message %s to stream %s", msg.MsgID, topic)
+	}
+
+	if s.option.Apm != nil {
+		key := fmt.Sprintf("%s:%s", EventPublish, strings.ReplaceAll(topic, ".", "_"))
+		s.option.Apm.RecordCustomEvent(key, map[string]interface{}{
+			"topic":        topic,
+			"message_id":   msg.MsgID,
+			"message_attr": header.WithoutCredentials(msg.MsgAttributes),
+			"message_data": string(msg.MsgData),
+		})
+	}
+
+	return nil
+}
+
+func (s *stream) Ping(ctx *tntContext.Context) error {
+	if err := s.client.Ping().Err(); err != nil {
+		return errors.Wrap(err, "failed to ping redis")
+	}
+
+	return nil
+}
+
+// Close stops the consumers, waiting for the messages being processed, and closes the connection.
+func (s *stream) Close() error {
+	s.mu.Lock()
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	if err := s.client.Close(); err != nil {
+		return errors.Wrapf(err, "Failed to Close redis stream")
+	}
+
+	return nil
+}
+
+func (s *stream) stopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// getMessage maps the fields of an entry back to a messaging.Message
+func getMessage(values map[string]interface{}) messaging.Message {
+	msg := messaging.Message{MsgAttributes: make(map[string]string)}
+	for field, value := range values {
+		v := fmt.Sprint(value)
+		switch {
+		case field == FieldMsgID:
+			msg.MsgID = v
+		case field == FieldMsgData:
+			msg.MsgData = []byte(v)
+		case strings.HasPrefix(field, FieldAttributePrefix):
+			msg.MsgAttributes[strings.TrimPrefix(field, FieldAttributePrefix)] = v
+		}
+	}
+
+	return msg
+}
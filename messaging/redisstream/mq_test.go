@@ -0,0 +1,191 @@
+package redisstream
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Dert12318/Utilities/apm"
+	"github.com/Dert12318/Utilities/apm/disabled"
+	"github.com/Dert12318/Utilities/common/constant/header"
+	"github.com/Dert12318/Utilities/messaging"
+)
+
+type recorder struct {
+	mu   sync.Mutex
+	fail int
+	dtos []messaging.DispatchDTO
+}
+
+func (r *recorder) AddHandler(messaging.HandlerFunc, messaging.ErrorHandlerFunc, ...string) {}
+
+func (r *recorder) Use(...messaging.MiddlewareFunc) {}
+
+func (r *recorder) Dispatch(dto messaging.DispatchDTO) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.dtos = append(r.dtos, dto)
+	if dto.Type == messaging.Handle && r.fail > 0 {
+		r.fail--
+		return errors.New("handler failed")
+	}
+	return nil
+}
+
+func (r *recorder) received() []messaging.DispatchDTO {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]messaging.DispatchDTO(nil), r.dtos...)
+}
+
+func newStream(t *testing.T, options ...Option) (*miniredis.Miniredis, messaging.Queue) {
+	s := miniredis.RunT(t)
+	options = append([]Option{
+		WithAddress([]string{s.Addr()}),
+		WithConsumerGroup("group"),
+		WithBlock(50 * time.Millisecond),
+	}, options...)
+
+	q, err := New(options...)
+	assert.NoError(t, err)
+	return s, q
+}
+
+func pending(s *miniredis.Miniredis) int64 {
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	return client.XPending("orders", "group").Val().Count
+}
+
+func TestPublishAndConsume(t *testing.T) {
+	s, q := newStream(t)
+	defer q.Close()
+
+	r := &recorder{}
+	assert.NoError(t, q.Subscribe("orders", r))
+	q.Listen()
+
+	assert.NoError(t, q.Publish("orders", messaging.Message{
+		MsgID:         "1",
+		MsgData:       []byte(`{"id":1}`),
+		MsgAttributes: map[string]string{"message": "created"},
+	}))
+
+	assert.Eventually(t, func() bool { return len(r.received()) == 1 }, time.Second, 10*time.Millisecond)
+
+	dto := r.received()[0]
+	assert.Equal(t, messaging.Handle, dto.Type)
+	assert.Equal(t, "Redis Stream - orders", dto.Source)
+	assert.Equal(t, "created", dto.MsgType)
+	assert.Equal(t, "1", dto.Msg.MsgID)
+	assert.Equal(t, `{"id":1}`, string(dto.Msg.MsgData))
+	assert.NotEmpty(t, dto.Msg.MsgAttributes[messaging.PublishTime])
+
+	assert.Eventually(t, func() bool { return pending(s) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+// eventAPM records the custom events and fails the test when a transaction is started
+type eventAPM struct {
+	apm.APM
+	t      *testing.T
+	mu     sync.Mutex
+	events []map[string]interface{}
+}
+
+func (a *eventAPM) StartTransaction(name string) apm.Transaction {
+	a.t.Errorf("unexpected transaction %s", name)
+	return a.APM.StartTransaction(name)
+}
+
+func (a *eventAPM) RecordCustomEvent(eventType string, params map[string]interface{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, params)
+}
+
+func TestPublishRecordsEventWithoutCredentials(t *testing.T) {
+	disabledAPM, _ := disabled.New()
+	recorder := &eventAPM{APM: disabledAPM, t: t}
+	_, q := newStream(t, WithApm(recorder))
+	defer q.Close()
+
+	assert.NoError(t, q.Publish("orders", messaging.Message{
+		MsgID:         "1",
+		MsgAttributes: map[string]string{"message": "created", header.MessagingAuthorization: "Bearer secret"},
+	}))
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	assert.Len(t, recorder.events, 1)
+	assert.Equal(t, map[string]string{"message": "created"}, recorder.events[0]["message_attr"])
+}
+
+func TestRetryThenError(t *testing.T) {
+	_, q := newStream(t, WithConsumerRetryMax(1))
+	defer q.Close()
+
+	r := &recorder{fail: 2}
+	assert.NoError(t, q.Subscribe("orders", r))
+	q.Listen()
+
+	assert.NoError(t, q.Publish("orders", messaging.Message{MsgData: []byte("data")}))
+
+	assert.Eventually(t, func() bool { return len(r.received()) == 3 }, time.Second, 10*time.Millisecond)
+
+	dtos := r.received()
+	assert.Equal(t, messaging.Handle, dtos[0].Type)
+	assert.Equal(t, messaging.Handle, dtos[1].Type)
+	assert.Equal(t, messaging.Error, dtos[2].Type)
+	assert.EqualError(t, dtos[2].Err, "handler failed")
+}
+
+func TestReclaim(t *testing.T) {
+	s, q := newStream(t, WithClaimMinIdle(time.Millisecond), WithClaimInterval(20*time.Millisecond))
+	defer q.Close()
+
+	r := &recorder{}
+	assert.NoError(t, q.Subscribe("orders", r))
+	assert.NoError(t, q.Publish("orders", messaging.Message{MsgID: "crashed"}))
+
+	// a consumer reads the entry and dies before acknowledging it
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+	_, err := client.XReadGroup(&redis.XReadGroupArgs{
+		Group:    "group",
+		Consumer: "crashed",
+		Streams:  []string{"orders", ">"},
+		Count:    1,
+	}).Result()
+	assert.NoError(t, err)
+
+	q.Listen()
+
+	assert.Eventually(t, func() bool { return len(r.received()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "crashed", r.received()[0].Msg.MsgID)
+
+	assert.Eventually(t, func() bool { return pending(s) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestParseAutoClaim(t *testing.T) {
+	next, messages, deleted, err := parseAutoClaim([]interface{}{
+		"0-0",
+		[]interface{}{
+			[]interface{}{"1-0", []interface{}{FieldMsgID, "a"}},
+			[]interface{}{"2-0", nil},
+		},
+		[]interface{}{"3-0"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0-0", next)
+	assert.Equal(t, []redis.XMessage{{ID: "1-0", Values: map[string]interface{}{FieldMsgID: "a"}}}, messages)
+	assert.Equal(t, []string{"2-0", "3-0"}, deleted)
+}
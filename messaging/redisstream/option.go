@@ -0,0 +1,196 @@
+package redisstream
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Dert12318/Utilities/apm"
+	"github.com/Dert12318/Utilities/logs"
+)
+
+const (
+	DefaultConsumerRetryMax = 3
+	DefaultBlock            = 2 * time.Second
+	DefaultBatchSize        = 10
+	DefaultClaimMinIdle     = time.Minute
+	DefaultClaimInterval    = 30 * time.Second
+	DefaultReadBackoff      = time.Second
+)
+
+type (
+	Option interface {
+		Apply(o *option)
+	}
+	option struct {
+		Address          []string
+		Password         string
+		DB               int
+		ConsumerGroup    string
+		ConsumerName     string
+		ConsumerRetryMax int
+		Block            time.Duration
+		BatchSize        int64
+		ClaimMinIdle     time.Duration
+		ClaimInterval    time.Duration
+		MaxLen           int64
+		Log              logs.Logger
+		Apm              apm.APM
+		WithoutConsumer  bool
+	}
+)
+
+func validate(option option) error {
+	if len(option.Address) < 1 {
+		return errors.New("invalid redis address")
+	}
+	if !option.WithoutConsumer && option.ConsumerGroup == "" {
+		return errors.New("invalid redis stream consumer group")
+	}
+	return nil
+}
+
+type withAddress []string
+
+func WithAddress(address []string) Option {
+	return withAddress(address)
+}
+
+func (w withAddress) Apply(o *option) {
+	o.Address = w
+}
+
+type withPassword string
+
+func WithPassword(password string) Option {
+	return withPassword(password)
+}
+
+func (w withPassword) Apply(o *option) {
+	o.Password = string(w)
+}
+
+type withDB int
+
+func WithDB(db int) Option {
+	return withDB(db)
+}
+
+func (w withDB) Apply(o *option) {
+	o.DB = int(w)
+}
+
+type withConsumerGroup string
+
+func WithConsumerGroup(group string) Option {
+	return withConsumerGroup(group)
+}
+
+func (w withConsumerGroup) Apply(o *option) {
+	o.ConsumerGroup = string(w)
+}
+
+type withConsumerName string
+
+// WithConsumerName names this consumer inside the group, it must be unique per instance and defaults to
+// the hostname followed by a random suffix.
+func WithConsumerName(name string) Option {
+	return withConsumerName(name)
+}
+
+func (w withConsumerName) Apply(o *option) {
+	o.ConsumerName = string(w)
+}
+
+type withConsumerRetryMax int
+
+func WithConsumerRetryMax(maxRetry int) Option {
+	return withConsumerRetryMax(maxRetry)
+}
+
+func (w withConsumerRetryMax) Apply(o *option) {
+	o.ConsumerRetryMax = int(w)
+}
+
+type withBlock time.Duration
+
+// WithBlock is how long a read waits for new entries, it also bounds how long Close waits for the consumers.
+func WithBlock(block time.Duration) Option {
+	return withBlock(block)
+}
+
+func (w withBlock) Apply(o *option) {
+	o.Block = time.Duration(w)
+}
+
+type withBatchSize int64
+
+func WithBatchSize(size int64) Option {
+	return withBatchSize(size)
+}
+
+func (w withBatchSize) Apply(o *option) {
+	o.BatchSize = int64(w)
+}
+
+type withClaimMinIdle time.Duration
+
+// WithClaimMinIdle is how long an entry stays pending on a consumer before another one reclaims it.
+func WithClaimMinIdle(idle time.Duration) Option {
+	return withClaimMinIdle(idle)
+}
+
+func (w withClaimMinIdle) Apply(o *option) {
+	o.ClaimMinIdle = time.Duration(w)
+}
+
+type withClaimInterval time.Duration
+
+func WithClaimInterval(interval time.Duration) Option {
+	return withClaimInterval(interval)
+}
+
+func (w withClaimInterval) Apply(o *option) {
+	o.ClaimInterval = time.Duration(w)
+}
+
+type withMaxLen int64
+
+// WithMaxLen trims the streams to about maxLen entries on publish, zero keeps every entry.
+func WithMaxLen(maxLen int64) Option {
+	return withMaxLen(maxLen)
+}
+
+func (w withMaxLen) Apply(o *option) {
+	o.MaxLen = int64(w)
+}
+
+type withLog struct{ logs.Logger }
+
+func WithLog(logger logs.Logger) Option {
+	return withLog{logger}
+}
+
+func (w withLog) Apply(o *option) {
+	o.Log = w.Logger
+}
+
+type withApm struct{ apm.APM }
+
+func WithApm(apm apm.APM) Option {
+	return withApm{apm}
+}
+
+func (w withApm) Apply(o *option) {
+	o.Apm = w.APM
+}
+
+type withoutConsumer bool
+
+func WithoutConsumer() Option {
+	return withoutConsumer(true)
+}
+
+func (w withoutConsumer) Apply(o *option) {
+	o.WithoutConsumer = bool(w)
+}
@@ -0,0 +1,170 @@
+package messaging
+
+import (
+	"context"
+	"path"
+	"sync"
+
+	tntContext "github.com/Dert12318/Utilities/context"
+	constantError "github.com/Dert12318/Utilities/errors"
+)
+
+// AnyMessage registers the default handler, used when no other route matches the message type
+const AnyMessage = "*"
+
+type (
+	// RoutingDispatcher dispatches a message to the handlers registered for its DispatchDTO.MsgType.
+	// A message type is matched exactly first, then against the glob patterns such as "order.*"
+	// in the order they were registered and finally falls back to the AnyMessage handlers.
+	RoutingDispatcher interface {
+		Dispatcher
+		// AddJob registers job.Process and job.OnError, like AddHandler
+		AddJob(job Job, msgType ...string)
+		// UseRoute applies middlewareFunc to the handlers of msgType only, after the middlewares added with Use
+		UseRoute(msgType string, middlewareFunc ...MiddlewareFunc)
+	}
+
+	routingDispatcher struct {
+		mu          sync.RWMutex
+		routes      map[string]*route
+		patterns    []string
+		middlewares []MiddlewareFunc
+	}
+
+	route struct {
+		handler      HandlerFunc
+		errorHandler ErrorHandlerFunc
+		middlewares  []MiddlewareFunc
+	}
+)
+
+func NewRoutingDispatcher() RoutingDispatcher {
+	return &routingDispatcher{
+		routes:      make(map[string]*route),
+		middlewares: make([]MiddlewareFunc, 0),
+	}
+}
+
+// AddHandler registers handler and errorHandler for every msgType, without msgType they are the AnyMessage handlers
+func (d *routingDispatcher) AddHandler(handler HandlerFunc, errorHandler ErrorHandlerFunc, msgType ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(msgType) == 0 {
+		msgType = []string{AnyMessage}
+	}
+
+	for _, t := range msgType {
+		r := d.route(t)
+		r.handler = handler
+		r.errorHandler = errorHandler
+	}
+}
+
+func (d *routingDispatcher) AddJob(job Job, msgType ...string) {
+	d.AddHandler(job.Process, job.OnError, msgType...)
+}
+
+func (d *routingDispatcher) Use(middlewareFunc ...MiddlewareFunc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.middlewares = append(d.middlewares, middlewareFunc...)
+}
+
+func (d *routingDispatcher) UseRoute(msgType string, middlewareFunc ...MiddlewareFunc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	r := d.route(msgType)
+	r.middlewares = append(r.middlewares, middlewareFunc...)
+}
+
+func (d *routingDispatcher) Dispatch(dto DispatchDTO) error {
+	dto.Log.Debugf("RECEIVE[%v][%v][%v] %v", dto.Msg.MsgID, dto.RequestID, dto.MsgType, string(dto.Msg.MsgData))
+
+	d.mu.RLock()
+	dispatch := applyMiddleware(d.dispatch, d.middlewares...)
+	d.mu.RUnlock()
+
+	ctx := dto.Context
+	if ctx == nil {
+		ctx = tntContext.NewWithContext(context.Background())
+	}
+	return dispatch(ctx, dto)
+}
+
+func (d *routingDispatcher) dispatch(ctx *tntContext.Context, dto DispatchDTO) error {
+	d.mu.RLock()
+	var dispatch MiddlewareHandlerFunc
+	if r := d.match(dto.MsgType); r != nil {
+		// a copy, AddHandler may replace the handlers of the route once the lock is released
+		snapshot := *r
+		dispatch = applyMiddleware(snapshot.dispatch, snapshot.middlewares...)
+	}
+	d.mu.RUnlock()
+
+	if dispatch == nil {
+		return constantError.MissingHandler
+	}
+	return dispatch(ctx, dto)
+}
+
+// route returns the route of msgType, creating it when missing, d.mu must be locked
+func (d *routingDispatcher) route(msgType string) *route {
+	if r, ok := d.routes[msgType]; ok {
+		return r
+	}
+
+	r := &route{}
+	d.routes[msgType] = r
+	if msgType != AnyMessage && isPattern(msgType) {
+		d.patterns = append(d.patterns, msgType)
+	}
+	return r
+}
+
+// match finds the route of msgType, d.mu must be locked
+func (d *routingDispatcher) match(msgType string) *route {
+	if r, ok := d.routes[msgType]; ok && msgType != AnyMessage && r.registered() {
+		return r
+	}
+
+	for _, pattern := range d.patterns {
+		if ok, _ := path.Match(pattern, msgType); ok && d.routes[pattern].registered() {
+			return d.routes[pattern]
+		}
+	}
+
+	return d.routes[AnyMessage]
+}
+
+// registered is false for a route that only has middlewares
+func (r *route) registered() bool {
+	return r.handler != nil || r.errorHandler != nil
+}
+
+func (r *route) dispatch(ctx *tntContext.Context, dto DispatchDTO) error {
+	if dto.Type == Handle {
+		if r.handler == nil {
+			return constantError.MissingHandler
+		}
+		return r.handler(ctx, dto.Msg)
+	}
+
+	if r.errorHandler == nil {
+		return constantError.MissingHandler
+	}
+	r.errorHandler(ctx, dto.Msg, dto.Err)
+	return nil
+}
+
+func isPattern(msgType string) bool {
+	for _, c := range msgType {
+		switch c {
+		case '*', '?', '[', '\\':
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package messaging
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	tntContext "github.com/Dert12318/Utilities/context"
+	constantError "github.com/Dert12318/Utilities/errors"
+	"github.com/Dert12318/Utilities/logs/logrus"
+)
+
+type job struct {
+	processed []string
+	failed    []error
+}
+
+func (j *job) Process(ctx *tntContext.Context, msg Message) error {
+	j.processed = append(j.processed, msg.MsgID)
+	return nil
+}
+
+func (j *job) OnError(ctx *tntContext.Context, msg Message, err error) {
+	j.failed = append(j.failed, err)
+}
+
+func dto(msgType, id string) DispatchDTO {
+	return DispatchDTO{Type: Handle, MsgType: msgType, Msg: Message{MsgID: id}, Log: logrus.DefaultLog()}
+}
+
+func TestRoutingDispatcher(t *testing.T) {
+	var handled []string
+	handler := func(name string) HandlerFunc {
+		return func(ctx *tntContext.Context, msg Message) error {
+			handled = append(handled, name+":"+msg.MsgID)
+			return nil
+		}
+	}
+
+	d := NewRoutingDispatcher()
+	d.AddHandler(handler("created"), nil, "order.created")
+	d.AddHandler(handler("order"), nil, "order.*")
+	d.AddHandler(handler("user"), nil, "user.created", "user.updated")
+
+	assert.NoError(t, d.Dispatch(dto("order.created", "1")))
+	assert.NoError(t, d.Dispatch(dto("order.paid", "2")))
+	assert.NoError(t, d.Dispatch(dto("user.updated", "3")))
+	assert.Equal(t, constantError.MissingHandler, d.Dispatch(dto("payment.paid", "4")))
+
+	d.AddHandler(handler("default"), nil)
+	assert.NoError(t, d.Dispatch(dto("payment.paid", "5")))
+	assert.NoError(t, d.Dispatch(dto("", "6")))
+
+	assert.Equal(t, []string{"created:1", "order:2", "user:3", "default:5", "default:6"}, handled)
+}
+
+func TestRoutingDispatcherJob(t *testing.T) {
+	j := &job{}
+	d := NewRoutingDispatcher()
+	d.AddJob(j, "order.created")
+
+	assert.NoError(t, d.Dispatch(dto("order.created", "1")))
+
+	failure := dto("order.created", "2")
+	failure.Type, failure.Err = Error, errors.New("failed")
+	assert.NoError(t, d.Dispatch(failure))
+
+	assert.Equal(t, []string{"1"}, j.processed)
+	assert.Equal(t, []error{failure.Err}, j.failed)
+
+	failure.MsgType = "order.paid"
+	assert.Equal(t, constantError.MissingHandler, d.Dispatch(failure))
+}
+
+func TestRoutingDispatcherMiddleware(t *testing.T) {
+	var calls []string
+	middleware := func(name string) MiddlewareFunc {
+		return func(next MiddlewareHandlerFunc) MiddlewareHandlerFunc {
+			return func(ctx *tntContext.Context, dto DispatchDTO) error {
+				calls = append(calls, name)
+				return next(ctx, dto)
+			}
+		}
+	}
+
+	d := NewRoutingDispatcher()
+	d.Use(middleware("global"))
+	d.UseRoute("order.created", middleware("route"))
+	d.AddHandler(func(ctx *tntContext.Context, msg Message) error {
+		calls = append(calls, "handler")
+		return nil
+	}, nil, "order.created", "order.paid")
+
+	assert.NoError(t, d.Dispatch(dto("order.created", "1")))
+	assert.NoError(t, d.Dispatch(dto("order.paid", "2")))
+	assert.Equal(t, []string{"global", "route", "handler", "global", "handler"}, calls)
+
+	// a route with only middlewares falls back to the default handler
+	d.UseRoute("user.created", middleware("user"))
+	d.AddHandler(func(ctx *tntContext.Context, msg Message) error { return nil }, nil, AnyMessage)
+	assert.NoError(t, d.Dispatch(dto("user.created", "3")))
+}
+
+func TestRoutingDispatcherContext(t *testing.T) {
+	var got *tntContext.Context
+	d := NewRoutingDispatcher()
+	d.AddHandler(func(ctx *tntContext.Context, msg Message) error {
+		got = ctx
+		return nil
+	}, nil)
+
+	ctx := tntContext.New()
+	message := dto("order.created", "1")
+	message.Context = ctx
+	assert.NoError(t, d.Dispatch(message))
+	assert.Same(t, ctx, got)
+}
+
+func TestRoutingDispatcherConcurrentRegister(t *testing.T) {
+	d := NewRoutingDispatcher()
+	noop := func(ctx *tntContext.Context, msg Message) error { return nil }
+	d.AddHandler(noop, nil, "order.created")
+
+	// run with -race, the handler is replaced while messages are dispatched
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			d.AddHandler(noop, nil, "order.created")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, d.Dispatch(dto("order.created", "1")))
+	}
+	<-done
+}
@@ -23,7 +23,11 @@ func (d *singleEventDispatcher) AddHandler(handler HandlerFunc, errorHandler Err
 func (d *singleEventDispatcher) Dispatch(dto DispatchDTO) error {
 	dto.Log.Debugf("RECEIVE[%v][%v] %v", dto.Msg.MsgID, dto.RequestID, string(dto.Msg.MsgData))
 	dispatch := applyMiddleware(d.dispatch, d.middlewares...)
-	return dispatch(tntContext.NewWithContext(context.Background()), dto)
+	ctx := dto.Context
+	if ctx == nil {
+		ctx = tntContext.NewWithContext(context.Background())
+	}
+	return dispatch(ctx, dto)
 }
 
 func (d *singleEventDispatcher) Use(middlewareFunc ...MiddlewareFunc) {
@@ -9,494 +9,2003 @@ import (
 	reflect "reflect"
 	time "time"
 
+	messaging "github.com/Dert12318/Utilities/messaging"
 	redis "github.com/go-redis/redis"
 	gomock "github.com/golang/mock/gomock"
 	cache "github.com/Dert12318/Utilities/cache"
 )
 
-// MockPipe is a mock of Pipe interface.
-type MockPipe struct {
+// MockIterator is a mock of Iterator interface.
+type MockIterator struct {
 	ctrl     *gomock.Controller
-	recorder *MockPipeMockRecorder
+	recorder *MockIteratorMockRecorder
 }
 
-// MockPipeMockRecorder is the mock recorder for MockPipe.
-type MockPipeMockRecorder struct {
-	mock *MockPipe
+// MockIteratorMockRecorder is the mock recorder for MockIterator.
+type MockIteratorMockRecorder struct {
+	mock *MockIterator
 }
 
-// NewMockPipe creates a new mock instance.
-func NewMockPipe(ctrl *gomock.Controller) *MockPipe {
-	mock := &MockPipe{ctrl: ctrl}
-	mock.recorder = &MockPipeMockRecorder{mock}
+// NewMockIterator creates a new mock instance.
+func NewMockIterator(ctrl *gomock.Controller) *MockIterator {
+	mock := &MockIterator{ctrl: ctrl}
+	mock.recorder = &MockIteratorMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockPipe) EXPECT() *MockPipeMockRecorder {
+func (m *MockIterator) EXPECT() *MockIteratorMockRecorder {
 	return m.recorder
 }
 
-// Exec mocks base method.
-func (m *MockPipe) Exec() error {
+// Err mocks base method.
+func (m *MockIterator) Err() error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Exec")
+	ret := m.ctrl.Call(m, "Err")
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Exec indicates an expected call of Exec.
-func (mr *MockPipeMockRecorder) Exec() *gomock.Call {
+// Err indicates an expected call of Err.
+func (mr *MockIteratorMockRecorder) Err() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exec", reflect.TypeOf((*MockPipe)(nil).Exec))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockIterator)(nil).Err))
 }
 
-// Get mocks base method.
-func (m *MockPipe) Get(key string, object interface{}) error {
+// Key mocks base method.
+func (m *MockIterator) Key() string {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", key, object)
+	ret := m.ctrl.Call(m, "Key")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Key indicates an expected call of Key.
+func (mr *MockIteratorMockRecorder) Key() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Key", reflect.TypeOf((*MockIterator)(nil).Key))
+}
+
+// Next mocks base method.
+func (m *MockIterator) Next() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Next")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Next indicates an expected call of Next.
+func (mr *MockIteratorMockRecorder) Next() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Next", reflect.TypeOf((*MockIterator)(nil).Next))
+}
+
+// MockPipeResult is a mock of PipeResult interface.
+type MockPipeResult struct {
+	ctrl     *gomock.Controller
+	recorder *MockPipeResultMockRecorder
+}
+
+// MockPipeResultMockRecorder is the mock recorder for MockPipeResult.
+type MockPipeResultMockRecorder struct {
+	mock *MockPipeResult
+}
+
+// NewMockPipeResult creates a new mock instance.
+func NewMockPipeResult(ctrl *gomock.Controller) *MockPipeResult {
+	mock := &MockPipeResult{ctrl: ctrl}
+	mock.recorder = &MockPipeResultMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPipeResult) EXPECT() *MockPipeResultMockRecorder {
+	return m.recorder
+}
+
+// Err mocks base method.
+func (m *MockPipeResult) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Get indicates an expected call of Get.
-func (mr *MockPipeMockRecorder) Get(key, object interface{}) *gomock.Call {
+// Err indicates an expected call of Err.
+func (mr *MockPipeResultMockRecorder) Err() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockPipe)(nil).Get), key, object)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockPipeResult)(nil).Err))
 }
 
-// Set mocks base method.
-func (m *MockPipe) Set(key string, value interface{}) error {
+// MockStatusResult is a mock of StatusResult interface.
+type MockStatusResult struct {
+	ctrl     *gomock.Controller
+	recorder *MockStatusResultMockRecorder
+}
+
+// MockStatusResultMockRecorder is the mock recorder for MockStatusResult.
+type MockStatusResultMockRecorder struct {
+	mock *MockStatusResult
+}
+
+// NewMockStatusResult creates a new mock instance.
+func NewMockStatusResult(ctrl *gomock.Controller) *MockStatusResult {
+	mock := &MockStatusResult{ctrl: ctrl}
+	mock.recorder = &MockStatusResultMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStatusResult) EXPECT() *MockStatusResultMockRecorder {
+	return m.recorder
+}
+
+// Err mocks base method.
+func (m *MockStatusResult) Err() error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Set", key, value)
+	ret := m.ctrl.Call(m, "Err")
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Set indicates an expected call of Set.
-func (mr *MockPipeMockRecorder) Set(key, value interface{}) *gomock.Call {
+// Err indicates an expected call of Err.
+func (mr *MockStatusResultMockRecorder) Err() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockPipe)(nil).Set), key, value)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockStatusResult)(nil).Err))
 }
 
-// SetWithExpiration mocks base method.
-func (m *MockPipe) SetWithExpiration(key string, value interface{}, expired time.Duration) error {
+// MockStringResult is a mock of StringResult interface.
+type MockStringResult struct {
+	ctrl     *gomock.Controller
+	recorder *MockStringResultMockRecorder
+}
+
+// MockStringResultMockRecorder is the mock recorder for MockStringResult.
+type MockStringResultMockRecorder struct {
+	mock *MockStringResult
+}
+
+// NewMockStringResult creates a new mock instance.
+func NewMockStringResult(ctrl *gomock.Controller) *MockStringResult {
+	mock := &MockStringResult{ctrl: ctrl}
+	mock.recorder = &MockStringResultMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStringResult) EXPECT() *MockStringResultMockRecorder {
+	return m.recorder
+}
+
+// Err mocks base method.
+func (m *MockStringResult) Err() error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetWithExpiration", key, value, expired)
+	ret := m.ctrl.Call(m, "Err")
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SetWithExpiration indicates an expected call of SetWithExpiration.
-func (mr *MockPipeMockRecorder) SetWithExpiration(key, value, expired interface{}) *gomock.Call {
+// Err indicates an expected call of Err.
+func (mr *MockStringResultMockRecorder) Err() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithExpiration", reflect.TypeOf((*MockPipe)(nil).SetWithExpiration), key, value, expired)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockStringResult)(nil).Err))
 }
 
-// MockPubSub is a mock of PubSub interface.
-type MockPubSub struct {
+// Scan mocks base method.
+func (m *MockStringResult) Scan(object interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Scan", object)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Scan indicates an expected call of Scan.
+func (mr *MockStringResultMockRecorder) Scan(object interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*MockStringResult)(nil).Scan), object)
+}
+
+// Val mocks base method.
+func (m *MockStringResult) Val() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Val")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Val indicates an expected call of Val.
+func (mr *MockStringResultMockRecorder) Val() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Val", reflect.TypeOf((*MockStringResult)(nil).Val))
+}
+
+// MockIntResult is a mock of IntResult interface.
+type MockIntResult struct {
 	ctrl     *gomock.Controller
-	recorder *MockPubSubMockRecorder
+	recorder *MockIntResultMockRecorder
 }
 
-// MockPubSubMockRecorder is the mock recorder for MockPubSub.
-type MockPubSubMockRecorder struct {
-	mock *MockPubSub
+// MockIntResultMockRecorder is the mock recorder for MockIntResult.
+type MockIntResultMockRecorder struct {
+	mock *MockIntResult
 }
 
-// NewMockPubSub creates a new mock instance.
-func NewMockPubSub(ctrl *gomock.Controller) *MockPubSub {
-	mock := &MockPubSub{ctrl: ctrl}
-	mock.recorder = &MockPubSubMockRecorder{mock}
+// NewMockIntResult creates a new mock instance.
+func NewMockIntResult(ctrl *gomock.Controller) *MockIntResult {
+	mock := &MockIntResult{ctrl: ctrl}
+	mock.recorder = &MockIntResultMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockPubSub) EXPECT() *MockPubSubMockRecorder {
+func (m *MockIntResult) EXPECT() *MockIntResultMockRecorder {
 	return m.recorder
 }
 
-// Channel mocks base method.
-func (m *MockPubSub) Channel() <-chan *redis.Message {
+// Err mocks base method.
+func (m *MockIntResult) Err() error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Channel")
-	ret0, _ := ret[0].(<-chan *redis.Message)
+	ret := m.ctrl.Call(m, "Err")
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Channel indicates an expected call of Channel.
-func (mr *MockPubSubMockRecorder) Channel() *gomock.Call {
+// Err indicates an expected call of Err.
+func (mr *MockIntResultMockRecorder) Err() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Channel", reflect.TypeOf((*MockPubSub)(nil).Channel))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockIntResult)(nil).Err))
 }
 
-// Close mocks base method.
-func (m *MockPubSub) Close() error {
+// Val mocks base method.
+func (m *MockIntResult) Val() int64 {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Close")
+	ret := m.ctrl.Call(m, "Val")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// Val indicates an expected call of Val.
+func (mr *MockIntResultMockRecorder) Val() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Val", reflect.TypeOf((*MockIntResult)(nil).Val))
+}
+
+// MockBoolResult is a mock of BoolResult interface.
+type MockBoolResult struct {
+	ctrl     *gomock.Controller
+	recorder *MockBoolResultMockRecorder
+}
+
+// MockBoolResultMockRecorder is the mock recorder for MockBoolResult.
+type MockBoolResultMockRecorder struct {
+	mock *MockBoolResult
+}
+
+// NewMockBoolResult creates a new mock instance.
+func NewMockBoolResult(ctrl *gomock.Controller) *MockBoolResult {
+	mock := &MockBoolResult{ctrl: ctrl}
+	mock.recorder = &MockBoolResultMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBoolResult) EXPECT() *MockBoolResultMockRecorder {
+	return m.recorder
+}
+
+// Err mocks base method.
+func (m *MockBoolResult) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Close indicates an expected call of Close.
-func (mr *MockPubSubMockRecorder) Close() *gomock.Call {
+// Err indicates an expected call of Err.
+func (mr *MockBoolResultMockRecorder) Err() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockPubSub)(nil).Close))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockBoolResult)(nil).Err))
 }
 
-// Publish mocks base method.
-func (m *MockPubSub) Publish(message string) error {
+// Val mocks base method.
+func (m *MockBoolResult) Val() bool {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Publish", message)
+	ret := m.ctrl.Call(m, "Val")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Val indicates an expected call of Val.
+func (mr *MockBoolResultMockRecorder) Val() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Val", reflect.TypeOf((*MockBoolResult)(nil).Val))
+}
+
+// MockSliceResult is a mock of SliceResult interface.
+type MockSliceResult struct {
+	ctrl     *gomock.Controller
+	recorder *MockSliceResultMockRecorder
+}
+
+// MockSliceResultMockRecorder is the mock recorder for MockSliceResult.
+type MockSliceResultMockRecorder struct {
+	mock *MockSliceResult
+}
+
+// NewMockSliceResult creates a new mock instance.
+func NewMockSliceResult(ctrl *gomock.Controller) *MockSliceResult {
+	mock := &MockSliceResult{ctrl: ctrl}
+	mock.recorder = &MockSliceResultMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSliceResult) EXPECT() *MockSliceResultMockRecorder {
+	return m.recorder
+}
+
+// Err mocks base method.
+func (m *MockSliceResult) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Publish indicates an expected call of Publish.
-func (mr *MockPubSubMockRecorder) Publish(message interface{}) *gomock.Call {
+// Err indicates an expected call of Err.
+func (mr *MockSliceResultMockRecorder) Err() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockPubSub)(nil).Publish), message)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockSliceResult)(nil).Err))
 }
 
-// Receive mocks base method.
-func (m *MockPubSub) Receive() error {
+// Val mocks base method.
+func (m *MockSliceResult) Val() []interface{} {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Receive")
+	ret := m.ctrl.Call(m, "Val")
+	ret0, _ := ret[0].([]interface{})
+	return ret0
+}
+
+// Val indicates an expected call of Val.
+func (mr *MockSliceResultMockRecorder) Val() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Val", reflect.TypeOf((*MockSliceResult)(nil).Val))
+}
+
+// MockStringSliceResult is a mock of StringSliceResult interface.
+type MockStringSliceResult struct {
+	ctrl     *gomock.Controller
+	recorder *MockStringSliceResultMockRecorder
+}
+
+// MockStringSliceResultMockRecorder is the mock recorder for MockStringSliceResult.
+type MockStringSliceResultMockRecorder struct {
+	mock *MockStringSliceResult
+}
+
+// NewMockStringSliceResult creates a new mock instance.
+func NewMockStringSliceResult(ctrl *gomock.Controller) *MockStringSliceResult {
+	mock := &MockStringSliceResult{ctrl: ctrl}
+	mock.recorder = &MockStringSliceResultMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStringSliceResult) EXPECT() *MockStringSliceResultMockRecorder {
+	return m.recorder
+}
+
+// Err mocks base method.
+func (m *MockStringSliceResult) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Err indicates an expected call of Err.
+func (mr *MockStringSliceResultMockRecorder) Err() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockStringSliceResult)(nil).Err))
+}
+
+// Val mocks base method.
+func (m *MockStringSliceResult) Val() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Val")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// Val indicates an expected call of Val.
+func (mr *MockStringSliceResultMockRecorder) Val() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Val", reflect.TypeOf((*MockStringSliceResult)(nil).Val))
+}
+
+// MockStringMapResult is a mock of StringMapResult interface.
+type MockStringMapResult struct {
+	ctrl     *gomock.Controller
+	recorder *MockStringMapResultMockRecorder
+}
+
+// MockStringMapResultMockRecorder is the mock recorder for MockStringMapResult.
+type MockStringMapResultMockRecorder struct {
+	mock *MockStringMapResult
+}
+
+// NewMockStringMapResult creates a new mock instance.
+func NewMockStringMapResult(ctrl *gomock.Controller) *MockStringMapResult {
+	mock := &MockStringMapResult{ctrl: ctrl}
+	mock.recorder = &MockStringMapResultMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStringMapResult) EXPECT() *MockStringMapResultMockRecorder {
+	return m.recorder
+}
+
+// Err mocks base method.
+func (m *MockStringMapResult) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Receive indicates an expected call of Receive.
-func (mr *MockPubSubMockRecorder) Receive() *gomock.Call {
+// Err indicates an expected call of Err.
+func (mr *MockStringMapResultMockRecorder) Err() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockStringMapResult)(nil).Err))
+}
+
+// Val mocks base method.
+func (m *MockStringMapResult) Val() map[string]string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Val")
+	ret0, _ := ret[0].(map[string]string)
+	return ret0
+}
+
+// Val indicates an expected call of Val.
+func (mr *MockStringMapResultMockRecorder) Val() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Val", reflect.TypeOf((*MockStringMapResult)(nil).Val))
+}
+
+// MockPipe is a mock of Pipe interface.
+type MockPipe struct {
+	ctrl     *gomock.Controller
+	recorder *MockPipeMockRecorder
+}
+
+// MockPipeMockRecorder is the mock recorder for MockPipe.
+type MockPipeMockRecorder struct {
+	mock *MockPipe
+}
+
+// NewMockPipe creates a new mock instance.
+func NewMockPipe(ctrl *gomock.Controller) *MockPipe {
+	mock := &MockPipe{ctrl: ctrl}
+	mock.recorder = &MockPipeMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPipe) EXPECT() *MockPipeMockRecorder {
+	return m.recorder
+}
+
+// Del mocks base method.
+func (m *MockPipe) Del(keys ...string) cache.IntResult {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range keys {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Del", varargs...)
+	ret0, _ := ret[0].(cache.IntResult)
+	return ret0
+}
+
+// Del indicates an expected call of Del.
+func (mr *MockPipeMockRecorder) Del(keys ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Del", reflect.TypeOf((*MockPipe)(nil).Del), keys...)
+}
+
+// Discard mocks base method.
+func (m *MockPipe) Discard() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Discard")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Discard indicates an expected call of Discard.
+func (mr *MockPipeMockRecorder) Discard() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Discard", reflect.TypeOf((*MockPipe)(nil).Discard))
+}
+
+// Exec mocks base method.
+func (m *MockPipe) Exec() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Exec")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Exec indicates an expected call of Exec.
+func (mr *MockPipeMockRecorder) Exec() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exec", reflect.TypeOf((*MockPipe)(nil).Exec))
+}
+
+// Expire mocks base method.
+func (m *MockPipe) Expire(key string, expiration time.Duration) cache.BoolResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Expire", key, expiration)
+	ret0, _ := ret[0].(cache.BoolResult)
+	return ret0
+}
+
+// Expire indicates an expected call of Expire.
+func (mr *MockPipeMockRecorder) Expire(key, expiration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expire", reflect.TypeOf((*MockPipe)(nil).Expire), key, expiration)
+}
+
+// Get mocks base method.
+func (m *MockPipe) Get(key string) cache.StringResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", key)
+	ret0, _ := ret[0].(cache.StringResult)
+	return ret0
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockPipeMockRecorder) Get(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockPipe)(nil).Get), key)
+}
+
+// HDel mocks base method.
+func (m *MockPipe) HDel(key string, fields ...string) cache.IntResult {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{key}
+	for _, a := range fields {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "HDel", varargs...)
+	ret0, _ := ret[0].(cache.IntResult)
+	return ret0
+}
+
+// HDel indicates an expected call of HDel.
+func (mr *MockPipeMockRecorder) HDel(key interface{}, fields ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{key}, fields...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HDel", reflect.TypeOf((*MockPipe)(nil).HDel), varargs...)
+}
+
+// HGet mocks base method.
+func (m *MockPipe) HGet(key, field string) cache.StringResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HGet", key, field)
+	ret0, _ := ret[0].(cache.StringResult)
+	return ret0
+}
+
+// HGet indicates an expected call of HGet.
+func (mr *MockPipeMockRecorder) HGet(key, field interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HGet", reflect.TypeOf((*MockPipe)(nil).HGet), key, field)
+}
+
+// HGetAll mocks base method.
+func (m *MockPipe) HGetAll(key string) cache.StringMapResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HGetAll", key)
+	ret0, _ := ret[0].(cache.StringMapResult)
+	return ret0
+}
+
+// HGetAll indicates an expected call of HGetAll.
+func (mr *MockPipeMockRecorder) HGetAll(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HGetAll", reflect.TypeOf((*MockPipe)(nil).HGetAll), key)
+}
+
+// HMGet mocks base method.
+func (m *MockPipe) HMGet(key string, fields ...string) cache.SliceResult {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{key}
+	for _, a := range fields {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "HMGet", varargs...)
+	ret0, _ := ret[0].(cache.SliceResult)
+	return ret0
+}
+
+// HMGet indicates an expected call of HMGet.
+func (mr *MockPipeMockRecorder) HMGet(key interface{}, fields ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{key}, fields...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HMGet", reflect.TypeOf((*MockPipe)(nil).HMGet), varargs...)
+}
+
+// HMSet mocks base method.
+func (m *MockPipe) HMSet(key string, value map[string]interface{}) cache.StatusResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HMSet", key, value)
+	ret0, _ := ret[0].(cache.StatusResult)
+	return ret0
+}
+
+// HMSet indicates an expected call of HMSet.
+func (mr *MockPipeMockRecorder) HMSet(key, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HMSet", reflect.TypeOf((*MockPipe)(nil).HMSet), key, value)
+}
+
+// HSet mocks base method.
+func (m *MockPipe) HSet(key, field string, value interface{}) cache.BoolResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HSet", key, field, value)
+	ret0, _ := ret[0].(cache.BoolResult)
+	return ret0
+}
+
+// HSet indicates an expected call of HSet.
+func (mr *MockPipeMockRecorder) HSet(key, field, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HSet", reflect.TypeOf((*MockPipe)(nil).HSet), key, field, value)
+}
+
+// Incr mocks base method.
+func (m *MockPipe) Incr(key string) cache.IntResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Incr", key)
+	ret0, _ := ret[0].(cache.IntResult)
+	return ret0
+}
+
+// Incr indicates an expected call of Incr.
+func (mr *MockPipeMockRecorder) Incr(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Incr", reflect.TypeOf((*MockPipe)(nil).Incr), key)
+}
+
+// IncrBy mocks base method.
+func (m *MockPipe) IncrBy(key string, value int64) cache.IntResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrBy", key, value)
+	ret0, _ := ret[0].(cache.IntResult)
+	return ret0
+}
+
+// IncrBy indicates an expected call of IncrBy.
+func (mr *MockPipeMockRecorder) IncrBy(key, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrBy", reflect.TypeOf((*MockPipe)(nil).IncrBy), key, value)
+}
+
+// SAdd mocks base method.
+func (m *MockPipe) SAdd(key string, members ...interface{}) cache.IntResult {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{key}
+	for _, a := range members {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SAdd", varargs...)
+	ret0, _ := ret[0].(cache.IntResult)
+	return ret0
+}
+
+// SAdd indicates an expected call of SAdd.
+func (mr *MockPipeMockRecorder) SAdd(key interface{}, members ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{key}, members...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SAdd", reflect.TypeOf((*MockPipe)(nil).SAdd), varargs...)
+}
+
+// SIsMember mocks base method.
+func (m *MockPipe) SIsMember(key string, member interface{}) cache.BoolResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SIsMember", key, member)
+	ret0, _ := ret[0].(cache.BoolResult)
+	return ret0
+}
+
+// SIsMember indicates an expected call of SIsMember.
+func (mr *MockPipeMockRecorder) SIsMember(key, member interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SIsMember", reflect.TypeOf((*MockPipe)(nil).SIsMember), key, member)
+}
+
+// SMembers mocks base method.
+func (m *MockPipe) SMembers(key string) cache.StringSliceResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SMembers", key)
+	ret0, _ := ret[0].(cache.StringSliceResult)
+	return ret0
+}
+
+// SMembers indicates an expected call of SMembers.
+func (mr *MockPipeMockRecorder) SMembers(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SMembers", reflect.TypeOf((*MockPipe)(nil).SMembers), key)
+}
+
+// SRem mocks base method.
+func (m *MockPipe) SRem(key string, members ...interface{}) cache.IntResult {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{key}
+	for _, a := range members {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SRem", varargs...)
+	ret0, _ := ret[0].(cache.IntResult)
+	return ret0
+}
+
+// SRem indicates an expected call of SRem.
+func (mr *MockPipeMockRecorder) SRem(key interface{}, members ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{key}, members...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SRem", reflect.TypeOf((*MockPipe)(nil).SRem), varargs...)
+}
+
+// Set mocks base method.
+func (m *MockPipe) Set(key string, value interface{}) cache.StatusResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", key, value)
+	ret0, _ := ret[0].(cache.StatusResult)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockPipeMockRecorder) Set(key, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockPipe)(nil).Set), key, value)
+}
+
+// SetWithExpiration mocks base method.
+func (m *MockPipe) SetWithExpiration(key string, value interface{}, expired time.Duration) cache.StatusResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWithExpiration", key, value, expired)
+	ret0, _ := ret[0].(cache.StatusResult)
+	return ret0
+}
+
+// SetWithExpiration indicates an expected call of SetWithExpiration.
+func (mr *MockPipeMockRecorder) SetWithExpiration(key, value, expired interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithExpiration", reflect.TypeOf((*MockPipe)(nil).SetWithExpiration), key, value, expired)
+}
+
+// MockTx is a mock of Tx interface.
+type MockTx struct {
+	ctrl     *gomock.Controller
+	recorder *MockTxMockRecorder
+}
+
+// MockTxMockRecorder is the mock recorder for MockTx.
+type MockTxMockRecorder struct {
+	mock *MockTx
+}
+
+// NewMockTx creates a new mock instance.
+func NewMockTx(ctrl *gomock.Controller) *MockTx {
+	mock := &MockTx{ctrl: ctrl}
+	mock.recorder = &MockTxMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTx) EXPECT() *MockTxMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockTx) Get(key string, object interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", key, object)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockTxMockRecorder) Get(key, object interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockTx)(nil).Get), key, object)
+}
+
+// HGet mocks base method.
+func (m *MockTx) HGet(key, field string, object interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HGet", key, field, object)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HGet indicates an expected call of HGet.
+func (mr *MockTxMockRecorder) HGet(key, field, object interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HGet", reflect.TypeOf((*MockTx)(nil).HGet), key, field, object)
+}
+
+// Pipelined mocks base method.
+func (m *MockTx) Pipelined(fn func(cache.Pipe) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pipelined", fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Pipelined indicates an expected call of Pipelined.
+func (mr *MockTxMockRecorder) Pipelined(fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pipelined", reflect.TypeOf((*MockTx)(nil).Pipelined), fn)
+}
+
+// MockPubSub is a mock of PubSub interface.
+type MockPubSub struct {
+	ctrl     *gomock.Controller
+	recorder *MockPubSubMockRecorder
+}
+
+// MockPubSubMockRecorder is the mock recorder for MockPubSub.
+type MockPubSubMockRecorder struct {
+	mock *MockPubSub
+}
+
+// NewMockPubSub creates a new mock instance.
+func NewMockPubSub(ctrl *gomock.Controller) *MockPubSub {
+	mock := &MockPubSub{ctrl: ctrl}
+	mock.recorder = &MockPubSubMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPubSub) EXPECT() *MockPubSubMockRecorder {
+	return m.recorder
+}
+
+// Channel mocks base method.
+func (m *MockPubSub) Channel() <-chan *redis.Message {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Channel")
+	ret0, _ := ret[0].(<-chan *redis.Message)
+	return ret0
+}
+
+// Channel indicates an expected call of Channel.
+func (mr *MockPubSubMockRecorder) Channel() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Channel", reflect.TypeOf((*MockPubSub)(nil).Channel))
+}
+
+// Close mocks base method.
+func (m *MockPubSub) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockPubSubMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockPubSub)(nil).Close))
+}
+
+// Publish mocks base method.
+func (m *MockPubSub) Publish(message string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Publish", message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockPubSubMockRecorder) Publish(message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockPubSub)(nil).Publish), message)
+}
+
+// Receive mocks base method.
+func (m *MockPubSub) Receive() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Receive")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Receive indicates an expected call of Receive.
+func (mr *MockPubSubMockRecorder) Receive() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Receive", reflect.TypeOf((*MockPubSub)(nil).Receive))
+}
+
+// MockSubscriber is a mock of Subscriber interface.
+type MockSubscriber struct {
+	ctrl     *gomock.Controller
+	recorder *MockSubscriberMockRecorder
+}
+
+// MockSubscriberMockRecorder is the mock recorder for MockSubscriber.
+type MockSubscriberMockRecorder struct {
+	mock *MockSubscriber
+}
+
+// NewMockSubscriber creates a new mock instance.
+func NewMockSubscriber(ctrl *gomock.Controller) *MockSubscriber {
+	mock := &MockSubscriber{ctrl: ctrl}
+	mock.recorder = &MockSubscriberMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSubscriber) EXPECT() *MockSubscriberMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockSubscriber) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockSubscriberMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockSubscriber)(nil).Close))
+}
+
+// PSubscribe mocks base method.
+func (m *MockSubscriber) PSubscribe(pattern string, dispatcher messaging.Dispatcher) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PSubscribe", pattern, dispatcher)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PSubscribe indicates an expected call of PSubscribe.
+func (mr *MockSubscriberMockRecorder) PSubscribe(pattern, dispatcher interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PSubscribe", reflect.TypeOf((*MockSubscriber)(nil).PSubscribe), pattern, dispatcher)
+}
+
+// PUnsubscribe mocks base method.
+func (m *MockSubscriber) PUnsubscribe(patterns ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range patterns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PUnsubscribe", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PUnsubscribe indicates an expected call of PUnsubscribe.
+func (mr *MockSubscriberMockRecorder) PUnsubscribe(patterns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PUnsubscribe", reflect.TypeOf((*MockSubscriber)(nil).PUnsubscribe), patterns...)
+}
+
+// Subscribe mocks base method.
+func (m *MockSubscriber) Subscribe(channel string, dispatcher messaging.Dispatcher) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", channel, dispatcher)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockSubscriberMockRecorder) Subscribe(channel, dispatcher interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockSubscriber)(nil).Subscribe), channel, dispatcher)
+}
+
+// Unsubscribe mocks base method.
+func (m *MockSubscriber) Unsubscribe(channels ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range channels {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Unsubscribe", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unsubscribe indicates an expected call of Unsubscribe.
+func (mr *MockSubscriberMockRecorder) Unsubscribe(channels ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unsubscribe", reflect.TypeOf((*MockSubscriber)(nil).Unsubscribe), channels...)
+}
+
+// MockCache is a mock of Cache interface.
+type MockCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockCacheMockRecorder
+}
+
+// MockCacheMockRecorder is the mock recorder for MockCache.
+type MockCacheMockRecorder struct {
+	mock *MockCache
+}
+
+// NewMockCache creates a new mock instance.
+func NewMockCache(ctrl *gomock.Controller) *MockCache {
+	mock := &MockCache{ctrl: ctrl}
+	mock.recorder = &MockCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCache) EXPECT() *MockCacheMockRecorder {
+	return m.recorder
+}
+
+// Client mocks base method.
+func (m *MockCache) Client() cache.Cache {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Client")
+	ret0, _ := ret[0].(cache.Cache)
+	return ret0
+}
+
+// Client indicates an expected call of Client.
+func (mr *MockCacheMockRecorder) Client() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Client", reflect.TypeOf((*MockCache)(nil).Client))
+}
+
+// Close mocks base method.
+func (m *MockCache) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockCacheMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockCache)(nil).Close))
+}
+
+// DeleteByPattern mocks base method.
+func (m *MockCache) DeleteByPattern(ctx *context.Context, option cache.ScanOption) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByPattern", ctx, option)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteByPattern indicates an expected call of DeleteByPattern.
+func (mr *MockCacheMockRecorder) DeleteByPattern(ctx, option interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByPattern", reflect.TypeOf((*MockCache)(nil).DeleteByPattern), ctx, option)
+}
+
+// Expire mocks base method.
+func (m *MockCache) Expire(ctx *context.Context, key string, ttl time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Expire", ctx, key, ttl)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Expire indicates an expected call of Expire.
+func (mr *MockCacheMockRecorder) Expire(ctx, key, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expire", reflect.TypeOf((*MockCache)(nil).Expire), ctx, key, ttl)
+}
+
+// FlushAll mocks base method.
+func (m *MockCache) FlushAll(ctx *context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlushAll", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FlushAll indicates an expected call of FlushAll.
+func (mr *MockCacheMockRecorder) FlushAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushAll", reflect.TypeOf((*MockCache)(nil).FlushAll), ctx)
+}
+
+// FlushDatabase mocks base method.
+func (m *MockCache) FlushDatabase(ctx *context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlushDatabase", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FlushDatabase indicates an expected call of FlushDatabase.
+func (mr *MockCacheMockRecorder) FlushDatabase(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushDatabase", reflect.TypeOf((*MockCache)(nil).FlushDatabase), ctx)
+}
+
+// Get mocks base method.
+func (m *MockCache) Get(ctx *context.Context, key string, data interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, key, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockCacheMockRecorder) Get(ctx, key, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCache)(nil).Get), ctx, key, data)
+}
+
+// HGet mocks base method.
+func (m *MockCache) HGet(ctx *context.Context, key, field string, response interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HGet", ctx, key, field, response)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HGet indicates an expected call of HGet.
+func (mr *MockCacheMockRecorder) HGet(ctx, key, field, response interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HGet", reflect.TypeOf((*MockCache)(nil).HGet), ctx, key, field, response)
+}
+
+// HGetAll mocks base method.
+func (m *MockCache) HGetAll(ctx *context.Context, key string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HGetAll", ctx, key)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HGetAll indicates an expected call of HGetAll.
+func (mr *MockCacheMockRecorder) HGetAll(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HGetAll", reflect.TypeOf((*MockCache)(nil).HGetAll), ctx, key)
+}
+
+// HMGet mocks base method.
+func (m *MockCache) HMGet(ctx *context.Context, key string, fields ...string) ([]interface{}, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, key}
+	for _, a := range fields {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "HMGet", varargs...)
+	ret0, _ := ret[0].([]interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HMGet indicates an expected call of HMGet.
+func (mr *MockCacheMockRecorder) HMGet(ctx, key interface{}, fields ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, key}, fields...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HMGet", reflect.TypeOf((*MockCache)(nil).HMGet), varargs...)
+}
+
+// HMSet mocks base method.
+func (m *MockCache) HMSet(ctx *context.Context, key string, value map[string]interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HMSet", ctx, key, value)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HMSet indicates an expected call of HMSet.
+func (mr *MockCacheMockRecorder) HMSet(ctx, key, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HMSet", reflect.TypeOf((*MockCache)(nil).HMSet), ctx, key, value)
+}
+
+// HMSetWithExpiration mocks base method.
+func (m *MockCache) HMSetWithExpiration(ctx *context.Context, key string, value map[string]interface{}, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HMSetWithExpiration", ctx, key, value, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HMSetWithExpiration indicates an expected call of HMSetWithExpiration.
+func (mr *MockCacheMockRecorder) HMSetWithExpiration(ctx, key, value, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HMSetWithExpiration", reflect.TypeOf((*MockCache)(nil).HMSetWithExpiration), ctx, key, value, ttl)
+}
+
+// HSet mocks base method.
+func (m *MockCache) HSet(ctx *context.Context, key, field string, value interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HSet", ctx, key, field, value)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HSet indicates an expected call of HSet.
+func (mr *MockCacheMockRecorder) HSet(ctx, key, field, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HSet", reflect.TypeOf((*MockCache)(nil).HSet), ctx, key, field, value)
+}
+
+// HSetWithExpiration mocks base method.
+func (m *MockCache) HSetWithExpiration(ctx *context.Context, key, field string, value interface{}, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HSetWithExpiration", ctx, key, field, value, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HSetWithExpiration indicates an expected call of HSetWithExpiration.
+func (mr *MockCacheMockRecorder) HSetWithExpiration(ctx, key, field, value, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HSetWithExpiration", reflect.TypeOf((*MockCache)(nil).HSetWithExpiration), ctx, key, field, value, ttl)
+}
+
+// Incr mocks base method.
+func (m *MockCache) Incr(ctx *context.Context, key string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Incr", ctx, key)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Incr indicates an expected call of Incr.
+func (mr *MockCacheMockRecorder) Incr(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Incr", reflect.TypeOf((*MockCache)(nil).Incr), ctx, key)
+}
+
+// IncrBy mocks base method.
+func (m *MockCache) IncrBy(ctx *context.Context, key string, value int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrBy", ctx, key, value)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrBy indicates an expected call of IncrBy.
+func (mr *MockCacheMockRecorder) IncrBy(ctx, key, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrBy", reflect.TypeOf((*MockCache)(nil).IncrBy), ctx, key, value)
+}
+
+// Keys mocks base method.
+func (m *MockCache) Keys(ctx *context.Context, pattern string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Keys", ctx, pattern)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Keys indicates an expected call of Keys.
+func (mr *MockCacheMockRecorder) Keys(ctx, pattern interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keys", reflect.TypeOf((*MockCache)(nil).Keys), ctx, pattern)
+}
+
+// LLen mocks base method.
+func (m *MockCache) LLen(ctx *context.Context, key string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LLen", ctx, key)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LLen indicates an expected call of LLen.
+func (mr *MockCacheMockRecorder) LLen(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LLen", reflect.TypeOf((*MockCache)(nil).LLen), ctx, key)
+}
+
+// LPop mocks base method.
+func (m *MockCache) LPop(ctx *context.Context, key string, object interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LPop", ctx, key, object)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LPop indicates an expected call of LPop.
+func (mr *MockCacheMockRecorder) LPop(ctx, key, object interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LPop", reflect.TypeOf((*MockCache)(nil).LPop), ctx, key, object)
+}
+
+// LPush mocks base method.
+func (m *MockCache) LPush(ctx *context.Context, key string, values ...interface{}) (int64, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, key}
+	for _, a := range values {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "LPush", varargs...)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LPush indicates an expected call of LPush.
+func (mr *MockCacheMockRecorder) LPush(ctx, key interface{}, values ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, key}, values...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LPush", reflect.TypeOf((*MockCache)(nil).LPush), varargs...)
+}
+
+// LRange mocks base method.
+func (m *MockCache) LRange(ctx *context.Context, key string, start, stop int64) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LRange", ctx, key, start, stop)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LRange indicates an expected call of LRange.
+func (mr *MockCacheMockRecorder) LRange(ctx, key, start, stop interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LRange", reflect.TypeOf((*MockCache)(nil).LRange), ctx, key, start, stop)
+}
+
+// MGet mocks base method.
+func (m *MockCache) MGet(ctx *context.Context, key []string) ([]interface{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MGet", ctx, key)
+	ret0, _ := ret[0].([]interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MGet indicates an expected call of MGet.
+func (mr *MockCacheMockRecorder) MGet(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MGet", reflect.TypeOf((*MockCache)(nil).MGet), ctx, key)
+}
+
+// Persist mocks base method.
+func (m *MockCache) Persist(ctx *context.Context, key string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Persist", ctx, key)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Persist indicates an expected call of Persist.
+func (mr *MockCacheMockRecorder) Persist(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Persist", reflect.TypeOf((*MockCache)(nil).Persist), ctx, key)
+}
+
+// Ping mocks base method.
+func (m *MockCache) Ping(ctx *context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockCacheMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockCache)(nil).Ping), ctx)
+}
+
+// Pipeline mocks base method.
+func (m *MockCache) Pipeline() cache.Pipe {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pipeline")
+	ret0, _ := ret[0].(cache.Pipe)
+	return ret0
+}
+
+// Pipeline indicates an expected call of Pipeline.
+func (mr *MockCacheMockRecorder) Pipeline() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pipeline", reflect.TypeOf((*MockCache)(nil).Pipeline))
+}
+
+// RPop mocks base method.
+func (m *MockCache) RPop(ctx *context.Context, key string, object interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RPop", ctx, key, object)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RPop indicates an expected call of RPop.
+func (mr *MockCacheMockRecorder) RPop(ctx, key, object interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RPop", reflect.TypeOf((*MockCache)(nil).RPop), ctx, key, object)
+}
+
+// RPush mocks base method.
+func (m *MockCache) RPush(ctx *context.Context, key string, values ...interface{}) (int64, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, key}
+	for _, a := range values {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RPush", varargs...)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RPush indicates an expected call of RPush.
+func (mr *MockCacheMockRecorder) RPush(ctx, key interface{}, values ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, key}, values...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RPush", reflect.TypeOf((*MockCache)(nil).RPush), varargs...)
+}
+
+// Remove mocks base method.
+func (m *MockCache) Remove(ctx *context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Remove", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Remove indicates an expected call of Remove.
+func (mr *MockCacheMockRecorder) Remove(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockCache)(nil).Remove), ctx, key)
+}
+
+// RemoveByPattern mocks base method.
+func (m *MockCache) RemoveByPattern(ctx *context.Context, pattern string, countPerLoop int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveByPattern", ctx, pattern, countPerLoop)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveByPattern indicates an expected call of RemoveByPattern.
+func (mr *MockCacheMockRecorder) RemoveByPattern(ctx, pattern, countPerLoop interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveByPattern", reflect.TypeOf((*MockCache)(nil).RemoveByPattern), ctx, pattern, countPerLoop)
+}
+
+// SAdd mocks base method.
+func (m *MockCache) SAdd(ctx context.Context, key string, values ...interface{}) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, key}
+	for _, a := range values {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SAdd", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SAdd indicates an expected call of SAdd.
+func (mr *MockCacheMockRecorder) SAdd(ctx, key interface{}, values ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, key}, values...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SAdd", reflect.TypeOf((*MockCache)(nil).SAdd), varargs...)
+}
+
+// SIsMember mocks base method.
+func (m *MockCache) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SIsMember", ctx, key, member)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SIsMember indicates an expected call of SIsMember.
+func (mr *MockCacheMockRecorder) SIsMember(ctx, key, member interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SIsMember", reflect.TypeOf((*MockCache)(nil).SIsMember), ctx, key, member)
+}
+
+// SMembers mocks base method.
+func (m *MockCache) SMembers(ctx context.Context, key string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SMembers", ctx, key)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SMembers indicates an expected call of SMembers.
+func (mr *MockCacheMockRecorder) SMembers(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SMembers", reflect.TypeOf((*MockCache)(nil).SMembers), ctx, key)
+}
+
+// Scan mocks base method.
+func (m *MockCache) Scan(ctx *context.Context, option cache.ScanOption) cache.Iterator {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Scan", ctx, option)
+	ret0, _ := ret[0].(cache.Iterator)
+	return ret0
+}
+
+// Scan indicates an expected call of Scan.
+func (mr *MockCacheMockRecorder) Scan(ctx, option interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*MockCache)(nil).Scan), ctx, option)
+}
+
+// Set mocks base method.
+func (m *MockCache) Set(ctx *context.Context, key string, value interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", ctx, key, value)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockCacheMockRecorder) Set(ctx, key, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockCache)(nil).Set), ctx, key, value)
+}
+
+// SetNX mocks base method.
+func (m *MockCache) SetNX(ctx *context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetNX", ctx, key, value, ttl)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetNX indicates an expected call of SetNX.
+func (mr *MockCacheMockRecorder) SetNX(ctx, key, value, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNX", reflect.TypeOf((*MockCache)(nil).SetNX), ctx, key, value, ttl)
+}
+
+// SetWithExpiration mocks base method.
+func (m *MockCache) SetWithExpiration(ctx *context.Context, key string, value interface{}, duration time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWithExpiration", ctx, key, value, duration)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetWithExpiration indicates an expected call of SetWithExpiration.
+func (mr *MockCacheMockRecorder) SetWithExpiration(ctx, key, value, duration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithExpiration", reflect.TypeOf((*MockCache)(nil).SetWithExpiration), ctx, key, value, duration)
+}
+
+// Subscribe mocks base method.
+func (m *MockCache) Subscribe(channel string) (cache.PubSub, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", channel)
+	ret0, _ := ret[0].(cache.PubSub)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockCacheMockRecorder) Subscribe(channel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockCache)(nil).Subscribe), channel)
+}
+
+// TTL mocks base method.
+func (m *MockCache) TTL(ctx *context.Context, key string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TTL", ctx, key)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TTL indicates an expected call of TTL.
+func (mr *MockCacheMockRecorder) TTL(ctx, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Receive", reflect.TypeOf((*MockPubSub)(nil).Receive))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TTL", reflect.TypeOf((*MockCache)(nil).TTL), ctx, key)
 }
 
-// MockCache is a mock of Cache interface.
-type MockCache struct {
-	ctrl     *gomock.Controller
-	recorder *MockCacheMockRecorder
+// TxPipeline mocks base method.
+func (m *MockCache) TxPipeline() cache.Pipe {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TxPipeline")
+	ret0, _ := ret[0].(cache.Pipe)
+	return ret0
 }
 
-// MockCacheMockRecorder is the mock recorder for MockCache.
-type MockCacheMockRecorder struct {
-	mock *MockCache
+// TxPipeline indicates an expected call of TxPipeline.
+func (mr *MockCacheMockRecorder) TxPipeline() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TxPipeline", reflect.TypeOf((*MockCache)(nil).TxPipeline))
 }
 
-// NewMockCache creates a new mock instance.
-func NewMockCache(ctrl *gomock.Controller) *MockCache {
-	mock := &MockCache{ctrl: ctrl}
-	mock.recorder = &MockCacheMockRecorder{mock}
-	return mock
+// Watch mocks base method.
+func (m *MockCache) Watch(ctx *context.Context, fn func(cache.Tx) error, keys ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, fn}
+	for _, a := range keys {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Watch", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockCache) EXPECT() *MockCacheMockRecorder {
-	return m.recorder
+// Watch indicates an expected call of Watch.
+func (mr *MockCacheMockRecorder) Watch(ctx, fn interface{}, keys ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, fn}, keys...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockCache)(nil).Watch), varargs...)
 }
 
-// Client mocks base method.
-func (m *MockCache) Client() cache.Cache {
+// ZAdd mocks base method.
+func (m *MockCache) ZAdd(ctx *context.Context, key string, members ...cache.Z) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Client")
-	ret0, _ := ret[0].(cache.Cache)
-	return ret0
+	varargs := []interface{}{ctx, key}
+	for _, a := range members {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ZAdd", varargs...)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// Client indicates an expected call of Client.
-func (mr *MockCacheMockRecorder) Client() *gomock.Call {
+// ZAdd indicates an expected call of ZAdd.
+func (mr *MockCacheMockRecorder) ZAdd(ctx, key interface{}, members ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Client", reflect.TypeOf((*MockCache)(nil).Client))
+	varargs := append([]interface{}{ctx, key}, members...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZAdd", reflect.TypeOf((*MockCache)(nil).ZAdd), varargs...)
 }
 
-// Close mocks base method.
-func (m *MockCache) Close() error {
+// ZCard mocks base method.
+func (m *MockCache) ZCard(ctx *context.Context, key string) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Close")
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ZCard", ctx, key)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// Close indicates an expected call of Close.
-func (mr *MockCacheMockRecorder) Close() *gomock.Call {
+// ZCard indicates an expected call of ZCard.
+func (mr *MockCacheMockRecorder) ZCard(ctx, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockCache)(nil).Close))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZCard", reflect.TypeOf((*MockCache)(nil).ZCard), ctx, key)
 }
 
-// FlushAll mocks base method.
-func (m *MockCache) FlushAll(ctx *context.Context) error {
+// ZIncrBy mocks base method.
+func (m *MockCache) ZIncrBy(ctx *context.Context, key string, increment float64, member interface{}) (float64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FlushAll", ctx)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ZIncrBy", ctx, key, increment, member)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// FlushAll indicates an expected call of FlushAll.
-func (mr *MockCacheMockRecorder) FlushAll(ctx interface{}) *gomock.Call {
+// ZIncrBy indicates an expected call of ZIncrBy.
+func (mr *MockCacheMockRecorder) ZIncrBy(ctx, key, increment, member interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushAll", reflect.TypeOf((*MockCache)(nil).FlushAll), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZIncrBy", reflect.TypeOf((*MockCache)(nil).ZIncrBy), ctx, key, increment, member)
 }
 
-// FlushDatabase mocks base method.
-func (m *MockCache) FlushDatabase(ctx *context.Context) error {
+// ZRange mocks base method.
+func (m *MockCache) ZRange(ctx *context.Context, key string, start, stop int64) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FlushDatabase", ctx)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ZRange", ctx, key, start, stop)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// FlushDatabase indicates an expected call of FlushDatabase.
-func (mr *MockCacheMockRecorder) FlushDatabase(ctx interface{}) *gomock.Call {
+// ZRange indicates an expected call of ZRange.
+func (mr *MockCacheMockRecorder) ZRange(ctx, key, start, stop interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushDatabase", reflect.TypeOf((*MockCache)(nil).FlushDatabase), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRange", reflect.TypeOf((*MockCache)(nil).ZRange), ctx, key, start, stop)
 }
 
-// Get mocks base method.
-func (m *MockCache) Get(ctx *context.Context, key string, data interface{}) error {
+// ZRangeByScore mocks base method.
+func (m *MockCache) ZRangeByScore(ctx *context.Context, key string, opt cache.ZRangeBy) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", ctx, key, data)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ZRangeByScore", ctx, key, opt)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// Get indicates an expected call of Get.
-func (mr *MockCacheMockRecorder) Get(ctx, key, data interface{}) *gomock.Call {
+// ZRangeByScore indicates an expected call of ZRangeByScore.
+func (mr *MockCacheMockRecorder) ZRangeByScore(ctx, key, opt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCache)(nil).Get), ctx, key, data)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRangeByScore", reflect.TypeOf((*MockCache)(nil).ZRangeByScore), ctx, key, opt)
 }
 
-// HGet mocks base method.
-func (m *MockCache) HGet(ctx *context.Context, key, field string, response interface{}) error {
+// ZRangeWithScores mocks base method.
+func (m *MockCache) ZRangeWithScores(ctx *context.Context, key string, start, stop int64) ([]cache.Z, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "HGet", ctx, key, field, response)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ZRangeWithScores", ctx, key, start, stop)
+	ret0, _ := ret[0].([]cache.Z)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// HGet indicates an expected call of HGet.
-func (mr *MockCacheMockRecorder) HGet(ctx, key, field, response interface{}) *gomock.Call {
+// ZRangeWithScores indicates an expected call of ZRangeWithScores.
+func (mr *MockCacheMockRecorder) ZRangeWithScores(ctx, key, start, stop interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HGet", reflect.TypeOf((*MockCache)(nil).HGet), ctx, key, field, response)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRangeWithScores", reflect.TypeOf((*MockCache)(nil).ZRangeWithScores), ctx, key, start, stop)
 }
 
-// HGetAll mocks base method.
-func (m *MockCache) HGetAll(ctx *context.Context, key string) (map[string]string, error) {
+// ZRank mocks base method.
+func (m *MockCache) ZRank(ctx *context.Context, key string, member interface{}) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "HGetAll", ctx, key)
-	ret0, _ := ret[0].(map[string]string)
+	ret := m.ctrl.Call(m, "ZRank", ctx, key, member)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// HGetAll indicates an expected call of HGetAll.
-func (mr *MockCacheMockRecorder) HGetAll(ctx, key interface{}) *gomock.Call {
+// ZRank indicates an expected call of ZRank.
+func (mr *MockCacheMockRecorder) ZRank(ctx, key, member interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HGetAll", reflect.TypeOf((*MockCache)(nil).HGetAll), ctx, key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRank", reflect.TypeOf((*MockCache)(nil).ZRank), ctx, key, member)
 }
 
-// HMGet mocks base method.
-func (m *MockCache) HMGet(ctx *context.Context, key string, fields ...string) ([]interface{}, error) {
+// ZRem mocks base method.
+func (m *MockCache) ZRem(ctx *context.Context, key string, members ...interface{}) (int64, error) {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{ctx, key}
-	for _, a := range fields {
+	for _, a := range members {
 		varargs = append(varargs, a)
 	}
-	ret := m.ctrl.Call(m, "HMGet", varargs...)
-	ret0, _ := ret[0].([]interface{})
+	ret := m.ctrl.Call(m, "ZRem", varargs...)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// HMGet indicates an expected call of HMGet.
-func (mr *MockCacheMockRecorder) HMGet(ctx, key interface{}, fields ...interface{}) *gomock.Call {
+// ZRem indicates an expected call of ZRem.
+func (mr *MockCacheMockRecorder) ZRem(ctx, key interface{}, members ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]interface{}{ctx, key}, fields...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HMGet", reflect.TypeOf((*MockCache)(nil).HMGet), varargs...)
+	varargs := append([]interface{}{ctx, key}, members...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRem", reflect.TypeOf((*MockCache)(nil).ZRem), varargs...)
 }
 
-// HMSet mocks base method.
-func (m *MockCache) HMSet(ctx *context.Context, key string, value map[string]interface{}) error {
+// ZRemRangeByScore mocks base method.
+func (m *MockCache) ZRemRangeByScore(ctx *context.Context, key, min, max string) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "HMSet", ctx, key, value)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ZRemRangeByScore", ctx, key, min, max)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// HMSet indicates an expected call of HMSet.
-func (mr *MockCacheMockRecorder) HMSet(ctx, key, value interface{}) *gomock.Call {
+// ZRemRangeByScore indicates an expected call of ZRemRangeByScore.
+func (mr *MockCacheMockRecorder) ZRemRangeByScore(ctx, key, min, max interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HMSet", reflect.TypeOf((*MockCache)(nil).HMSet), ctx, key, value)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRemRangeByScore", reflect.TypeOf((*MockCache)(nil).ZRemRangeByScore), ctx, key, min, max)
 }
 
-// HMSetWithExpiration mocks base method.
-func (m *MockCache) HMSetWithExpiration(ctx *context.Context, key string, value map[string]interface{}, ttl time.Duration) error {
+// ZRevRange mocks base method.
+func (m *MockCache) ZRevRange(ctx *context.Context, key string, start, stop int64) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "HMSetWithExpiration", ctx, key, value, ttl)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ZRevRange", ctx, key, start, stop)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// HMSetWithExpiration indicates an expected call of HMSetWithExpiration.
-func (mr *MockCacheMockRecorder) HMSetWithExpiration(ctx, key, value, ttl interface{}) *gomock.Call {
+// ZRevRange indicates an expected call of ZRevRange.
+func (mr *MockCacheMockRecorder) ZRevRange(ctx, key, start, stop interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HMSetWithExpiration", reflect.TypeOf((*MockCache)(nil).HMSetWithExpiration), ctx, key, value, ttl)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRevRange", reflect.TypeOf((*MockCache)(nil).ZRevRange), ctx, key, start, stop)
 }
 
-// HSet mocks base method.
-func (m *MockCache) HSet(ctx *context.Context, key, field string, value interface{}) error {
+// ZRevRangeWithScores mocks base method.
+func (m *MockCache) ZRevRangeWithScores(ctx *context.Context, key string, start, stop int64) ([]cache.Z, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "HSet", ctx, key, field, value)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ZRevRangeWithScores", ctx, key, start, stop)
+	ret0, _ := ret[0].([]cache.Z)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// HSet indicates an expected call of HSet.
-func (mr *MockCacheMockRecorder) HSet(ctx, key, field, value interface{}) *gomock.Call {
+// ZRevRangeWithScores indicates an expected call of ZRevRangeWithScores.
+func (mr *MockCacheMockRecorder) ZRevRangeWithScores(ctx, key, start, stop interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HSet", reflect.TypeOf((*MockCache)(nil).HSet), ctx, key, field, value)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRevRangeWithScores", reflect.TypeOf((*MockCache)(nil).ZRevRangeWithScores), ctx, key, start, stop)
 }
 
-// HSetWithExpiration mocks base method.
-func (m *MockCache) HSetWithExpiration(ctx *context.Context, key, field string, value interface{}, ttl time.Duration) error {
+// ZRevRank mocks base method.
+func (m *MockCache) ZRevRank(ctx *context.Context, key string, member interface{}) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "HSetWithExpiration", ctx, key, field, value, ttl)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ZRevRank", ctx, key, member)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// HSetWithExpiration indicates an expected call of HSetWithExpiration.
-func (mr *MockCacheMockRecorder) HSetWithExpiration(ctx, key, field, value, ttl interface{}) *gomock.Call {
+// ZRevRank indicates an expected call of ZRevRank.
+func (mr *MockCacheMockRecorder) ZRevRank(ctx, key, member interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HSetWithExpiration", reflect.TypeOf((*MockCache)(nil).HSetWithExpiration), ctx, key, field, value, ttl)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRevRank", reflect.TypeOf((*MockCache)(nil).ZRevRank), ctx, key, member)
 }
 
-// Keys mocks base method.
-func (m *MockCache) Keys(ctx *context.Context, pattern string) ([]string, error) {
+// ZScore mocks base method.
+func (m *MockCache) ZScore(ctx *context.Context, key string, member interface{}) (float64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Keys", ctx, pattern)
-	ret0, _ := ret[0].([]string)
+	ret := m.ctrl.Call(m, "ZScore", ctx, key, member)
+	ret0, _ := ret[0].(float64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Keys indicates an expected call of Keys.
-func (mr *MockCacheMockRecorder) Keys(ctx, pattern interface{}) *gomock.Call {
+// ZScore indicates an expected call of ZScore.
+func (mr *MockCacheMockRecorder) ZScore(ctx, key, member interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keys", reflect.TypeOf((*MockCache)(nil).Keys), ctx, pattern)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZScore", reflect.TypeOf((*MockCache)(nil).ZScore), ctx, key, member)
 }
 
-// MGet mocks base method.
-func (m *MockCache) MGet(ctx *context.Context, key []string) ([]interface{}, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MGet", ctx, key)
-	ret0, _ := ret[0].([]interface{})
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+// MockLock is a mock of Lock interface.
+type MockLock struct {
+	ctrl     *gomock.Controller
+	recorder *MockLockMockRecorder
 }
 
-// MGet indicates an expected call of MGet.
-func (mr *MockCacheMockRecorder) MGet(ctx, key interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MGet", reflect.TypeOf((*MockCache)(nil).MGet), ctx, key)
+// MockLockMockRecorder is the mock recorder for MockLock.
+type MockLockMockRecorder struct {
+	mock *MockLock
 }
 
-// Ping mocks base method.
-func (m *MockCache) Ping(ctx *context.Context) error {
+// NewMockLock creates a new mock instance.
+func NewMockLock(ctrl *gomock.Controller) *MockLock {
+	mock := &MockLock{ctrl: ctrl}
+	mock.recorder = &MockLockMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLock) EXPECT() *MockLockMockRecorder {
+	return m.recorder
+}
+
+// Done mocks base method.
+func (m *MockLock) Done() <-chan struct{} {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Ping", ctx)
-	ret0, _ := ret[0].(error)
+	ret := m.ctrl.Call(m, "Done")
+	ret0, _ := ret[0].(<-chan struct{})
 	return ret0
 }
 
-// Ping indicates an expected call of Ping.
-func (mr *MockCacheMockRecorder) Ping(ctx interface{}) *gomock.Call {
+// Done indicates an expected call of Done.
+func (mr *MockLockMockRecorder) Done() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockCache)(nil).Ping), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Done", reflect.TypeOf((*MockLock)(nil).Done))
 }
 
-// Pipeline mocks base method.
-func (m *MockCache) Pipeline() cache.Pipe {
+// Key mocks base method.
+func (m *MockLock) Key() string {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Pipeline")
-	ret0, _ := ret[0].(cache.Pipe)
+	ret := m.ctrl.Call(m, "Key")
+	ret0, _ := ret[0].(string)
 	return ret0
 }
 
-// Pipeline indicates an expected call of Pipeline.
-func (mr *MockCacheMockRecorder) Pipeline() *gomock.Call {
+// Key indicates an expected call of Key.
+func (mr *MockLockMockRecorder) Key() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pipeline", reflect.TypeOf((*MockCache)(nil).Pipeline))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Key", reflect.TypeOf((*MockLock)(nil).Key))
 }
 
-// Remove mocks base method.
-func (m *MockCache) Remove(ctx *context.Context, key string) error {
+// Refresh mocks base method.
+func (m *MockLock) Refresh(ctx *context.Context, ttl time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Remove", ctx, key)
+	ret := m.ctrl.Call(m, "Refresh", ctx, ttl)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Remove indicates an expected call of Remove.
-func (mr *MockCacheMockRecorder) Remove(ctx, key interface{}) *gomock.Call {
+// Refresh indicates an expected call of Refresh.
+func (mr *MockLockMockRecorder) Refresh(ctx, ttl interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockCache)(nil).Remove), ctx, key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockLock)(nil).Refresh), ctx, ttl)
 }
 
-// RemoveByPattern mocks base method.
-func (m *MockCache) RemoveByPattern(ctx *context.Context, pattern string, countPerLoop int64) error {
+// Release mocks base method.
+func (m *MockLock) Release(ctx *context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RemoveByPattern", ctx, pattern, countPerLoop)
+	ret := m.ctrl.Call(m, "Release", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// RemoveByPattern indicates an expected call of RemoveByPattern.
-func (mr *MockCacheMockRecorder) RemoveByPattern(ctx, pattern, countPerLoop interface{}) *gomock.Call {
+// Release indicates an expected call of Release.
+func (mr *MockLockMockRecorder) Release(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveByPattern", reflect.TypeOf((*MockCache)(nil).RemoveByPattern), ctx, pattern, countPerLoop)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockLock)(nil).Release), ctx)
 }
 
-// Set mocks base method.
-func (m *MockCache) Set(ctx *context.Context, key string, value interface{}) error {
+// Token mocks base method.
+func (m *MockLock) Token() int64 {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Set", ctx, key, value)
-	ret0, _ := ret[0].(error)
+	ret := m.ctrl.Call(m, "Token")
+	ret0, _ := ret[0].(int64)
 	return ret0
 }
 
-// Set indicates an expected call of Set.
-func (mr *MockCacheMockRecorder) Set(ctx, key, value interface{}) *gomock.Call {
+// Token indicates an expected call of Token.
+func (mr *MockLockMockRecorder) Token() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockCache)(nil).Set), ctx, key, value)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Token", reflect.TypeOf((*MockLock)(nil).Token))
 }
 
-// SetWithExpiration mocks base method.
-func (m *MockCache) SetWithExpiration(ctx *context.Context, key string, value interface{}, duration time.Duration) error {
+// MockLocker is a mock of Locker interface.
+type MockLocker struct {
+	ctrl     *gomock.Controller
+	recorder *MockLockerMockRecorder
+}
+
+// MockLockerMockRecorder is the mock recorder for MockLocker.
+type MockLockerMockRecorder struct {
+	mock *MockLocker
+}
+
+// NewMockLocker creates a new mock instance.
+func NewMockLocker(ctrl *gomock.Controller) *MockLocker {
+	mock := &MockLocker{ctrl: ctrl}
+	mock.recorder = &MockLockerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLocker) EXPECT() *MockLockerMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockLocker) Close() error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetWithExpiration", ctx, key, value, duration)
+	ret := m.ctrl.Call(m, "Close")
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SetWithExpiration indicates an expected call of SetWithExpiration.
-func (mr *MockCacheMockRecorder) SetWithExpiration(ctx, key, value, duration interface{}) *gomock.Call {
+// Close indicates an expected call of Close.
+func (mr *MockLockerMockRecorder) Close() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithExpiration", reflect.TypeOf((*MockCache)(nil).SetWithExpiration), ctx, key, value, duration)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockLocker)(nil).Close))
 }
 
-// Subscribe mocks base method.
-func (m *MockCache) Subscribe(channel string) (cache.PubSub, error) {
+// Obtain mocks base method.
+func (m *MockLocker) Obtain(ctx *context.Context, key string, option cache.LockOption) (cache.Lock, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Subscribe", channel)
-	ret0, _ := ret[0].(cache.PubSub)
+	ret := m.ctrl.Call(m, "Obtain", ctx, key, option)
+	ret0, _ := ret[0].(cache.Lock)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Subscribe indicates an expected call of Subscribe.
-func (mr *MockCacheMockRecorder) Subscribe(channel interface{}) *gomock.Call {
+// Obtain indicates an expected call of Obtain.
+func (mr *MockLockerMockRecorder) Obtain(ctx, key, option interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockCache)(nil).Subscribe), channel)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Obtain", reflect.TypeOf((*MockLocker)(nil).Obtain), ctx, key, option)
 }
 
 // MockPool is a mock of Pool interface.
@@ -522,6 +2031,20 @@ func (m *MockPool) EXPECT() *MockPoolMockRecorder {
 	return m.recorder
 }
 
+// Borrow mocks base method.
+func (m *MockPool) Borrow(ctx *context.Context, name string, callback func(cache.Cache) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Borrow", ctx, name, callback)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Borrow indicates an expected call of Borrow.
+func (mr *MockPoolMockRecorder) Borrow(ctx, name, callback interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Borrow", reflect.TypeOf((*MockPool)(nil).Borrow), ctx, name, callback)
+}
+
 // Client mocks base method.
 func (m *MockPool) Client() cache.Cache {
 	m.ctrl.T.Helper()
@@ -550,6 +2073,21 @@ func (mr *MockPoolMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockPool)(nil).Close))
 }
 
+// Get mocks base method.
+func (m *MockPool) Get(ctx *context.Context, name string) (cache.Cache, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, name)
+	ret0, _ := ret[0].(cache.Cache)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockPoolMockRecorder) Get(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockPool)(nil).Get), ctx, name)
+}
+
 // Use mocks base method.
 func (m *MockPool) Use(callback cache.PoolCallback) {
 	m.ctrl.T.Helper()
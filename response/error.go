@@ -11,11 +11,12 @@ import (
 )
 
 var (
-	ErrBadRequest     = NewError(http.StatusText(http.StatusBadRequest), http.StatusBadRequest, nil)
-	ErrUnauthorized   = NewError(http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized, nil)
-	ErrNotFound       = NewError(http.StatusText(http.StatusNotFound), http.StatusNotFound, nil)
-	ErrInternalServer = NewError(http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, nil)
-	ErrForbidden      = NewError(http.StatusText(http.StatusForbidden), http.StatusForbidden, nil)
+	ErrBadRequest      = NewError(http.StatusText(http.StatusBadRequest), http.StatusBadRequest, nil)
+	ErrUnauthorized    = NewError(http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized, nil)
+	ErrNotFound        = NewError(http.StatusText(http.StatusNotFound), http.StatusNotFound, nil)
+	ErrInternalServer  = NewError(http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError, nil)
+	ErrForbidden       = NewError(http.StatusText(http.StatusForbidden), http.StatusForbidden, nil)
+	ErrTooManyRequests = NewError(http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests, nil)
 )
 
 type HttpError struct {